@@ -0,0 +1,81 @@
+// Command duplicates reports spots that are probably the same place added
+// twice: spots within 100 m of each other with a similar title or a near
+// identical photo. Photos uploaded before hashing existed are hashed first.
+//
+//	go run ./cmd/duplicates
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"chillspot-backend/internal/db"
+	"chillspot-backend/internal/handlers"
+	"chillspot-backend/internal/imaging"
+	"chillspot-backend/internal/models"
+	"chillspot-backend/internal/storage"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: No .env file found - using default environment variables")
+	}
+
+	database := db.ConnectDB()
+
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatal("Failed to set up storage:", err)
+	}
+
+	if err := backfillPhotoHashes(database, store); err != nil {
+		log.Fatal("Failed to hash photos:", err)
+	}
+
+	pairs, err := handlers.FindDuplicatePairs(database)
+	if err != nil {
+		log.Fatal("Failed to find duplicates:", err)
+	}
+
+	for _, pair := range pairs {
+		duplicate := pair.Duplicate
+		fmt.Printf("%s %q\n  %s %q\n  %.0f m apart, title similarity %.2f",
+			pair.Spot.ID, pair.Spot.Title, duplicate.Spot.ID, duplicate.Spot.Title,
+			duplicate.Distance, duplicate.TitleSimilarity)
+		if duplicate.PhotoDistance != nil {
+			fmt.Printf(", photo distance %d", *duplicate.PhotoDistance)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%d probable duplicate(s)\n", len(pairs))
+}
+
+// backfillPhotoHashes computes the perceptual hash of every photo that has none
+func backfillPhotoHashes(database *gorm.DB, store storage.Storage) error {
+	var photos []models.SpotPhoto
+	return database.Where("hash IS NULL").FindInBatches(&photos, 100, func(tx *gorm.DB, batch int) error {
+		for _, photo := range photos {
+			object, err := store.Get(context.Background(), storage.ImagesPrefix+photo.Filename)
+			if err != nil {
+				log.Printf("Skipping photo %s: %v", photo.ID, err)
+				continue
+			}
+			hash, err := imaging.Hash(object)
+			object.Close()
+			if err != nil {
+				log.Printf("Skipping photo %s: %v", photo.ID, err)
+				continue
+			}
+
+			photo.SetHash(hash)
+			if err := tx.Model(&models.SpotPhoto{}).Where("id = ?", photo.ID).UpdateColumn("hash", photo.Hash).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
@@ -0,0 +1,49 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Mean length of a lunation in days
+const synodicMonth = 29.530588853
+
+// A new moon to count lunations from (2000-01-06 18:14 UTC)
+var referenceNewMoon = time.Date(2000, 1, 6, 18, 14, 0, 0, time.UTC)
+
+type MoonPhase struct {
+	Phase        float64 `json:"phase"`        // 0 new, 0.25 first quarter, 0.5 full, 0.75 last quarter
+	Age          float64 `json:"age"`          // days since new moon
+	Illumination float64 `json:"illumination"` // lit fraction of the disc
+	Name         string  `json:"name"`
+}
+
+var moonPhaseNames = []string{
+	"new_moon",
+	"waxing_crescent",
+	"first_quarter",
+	"waxing_gibbous",
+	"full_moon",
+	"waning_gibbous",
+	"last_quarter",
+	"waning_crescent",
+}
+
+// Moon returns the moon's phase at t, using the mean lunation (within about
+// a day of the true phase)
+func Moon(t time.Time) MoonPhase {
+	days := t.Sub(referenceNewMoon).Hours() / 24
+	age := math.Mod(days, synodicMonth)
+	if age < 0 {
+		age += synodicMonth
+	}
+	phase := age / synodicMonth
+
+	return MoonPhase{
+		Phase:        phase,
+		Age:          age,
+		Illumination: (1 - math.Cos(2*math.Pi*phase)) / 2,
+		// Each name covers an eighth of the cycle centred on its phase
+		Name: moonPhaseNames[int(math.Floor(phase*8+0.5))%8],
+	}
+}
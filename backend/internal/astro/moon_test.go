@@ -0,0 +1,52 @@
+package astro
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMoon(t *testing.T) {
+	lunation := time.Duration(synodicMonth * float64(24*time.Hour))
+
+	tests := []struct {
+		name             string
+		at               time.Time
+		wantName         string
+		wantPhase        float64
+		wantIllumination float64
+		tolerance        float64
+	}{
+		{"reference new moon", referenceNewMoon, "new_moon", 0, 0, 1e-9},
+		{"first quarter", referenceNewMoon.Add(lunation / 4), "first_quarter", 0.25, 0.5, 1e-9},
+		{"full moon", referenceNewMoon.Add(lunation / 2), "full_moon", 0.5, 1, 1e-9},
+		{"last quarter", referenceNewMoon.Add(lunation * 3 / 4), "last_quarter", 0.75, 0.5, 1e-9},
+		{"a hundred lunations on", referenceNewMoon.Add(100 * lunation), "new_moon", 0, 0, 1e-6},
+		// Times before the reference wrap into the previous lunation
+		{"before the reference", referenceNewMoon.Add(-lunation / 4), "last_quarter", 0.75, 0.5, 1e-9},
+		{"waxing crescent", referenceNewMoon.Add(lunation / 8), "waxing_crescent", 0.125, 0.146, 1e-3},
+		{"waning gibbous", referenceNewMoon.Add(lunation * 5 / 8), "waning_gibbous", 0.625, 0.854, 1e-3},
+		// The mean lunation stays within a day of real phases: full moon of
+		// 2025-01-13 22:27 UTC and new moon of 2025-06-25 10:31 UTC
+		{"observed full moon", time.Date(2025, 1, 13, 22, 27, 0, 0, time.UTC), "full_moon", 0.5, 1, 0.04},
+		{"observed new moon", time.Date(2025, 6, 25, 10, 31, 0, 0, time.UTC), "new_moon", 0, 0, 0.04},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moon := Moon(tt.at)
+			if moon.Name != tt.wantName {
+				t.Errorf("name = %s, want %s", moon.Name, tt.wantName)
+			}
+			// Phase 1 is phase 0 again
+			if d := math.Abs(moon.Phase - tt.wantPhase); math.Min(d, 1-d) > tt.tolerance {
+				t.Errorf("phase = %v, want %v", moon.Phase, tt.wantPhase)
+			}
+			if math.Abs(moon.Illumination-tt.wantIllumination) > tt.tolerance {
+				t.Errorf("illumination = %v, want %v", moon.Illumination, tt.wantIllumination)
+			}
+			if moon.Age < 0 || moon.Age >= synodicMonth || math.Abs(moon.Age-moon.Phase*synodicMonth) > 1e-9 {
+				t.Errorf("age = %v for phase %v", moon.Age, moon.Phase)
+			}
+		})
+	}
+}
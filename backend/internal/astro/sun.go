@@ -0,0 +1,243 @@
+// Package astro computes sun and moon positions locally, using the NOAA
+// solar equations (accurate to about a minute for event times).
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Sun elevations in degrees that define the usual events. Sunrise and sunset
+// account for refraction and the radius of the sun's disc.
+const (
+	ElevationSunrise      = -0.833
+	ElevationGoldenHour   = 6
+	ElevationBlueHour     = -4
+	ElevationCivil        = -6
+	ElevationNautical     = -12
+	ElevationAstronomical = -18
+)
+
+// Interval is a stretch of the day; either end is nil when the sun never
+// reaches the elevation that bounds it (e.g. summer near the poles)
+type Interval struct {
+	Start *time.Time `json:"start"`
+	End   *time.Time `json:"end"`
+}
+
+// Twilight is when a twilight begins in the morning and ends in the evening
+type Twilight struct {
+	Dawn *time.Time `json:"dawn"`
+	Dusk *time.Time `json:"dusk"`
+}
+
+type DailyInterval struct {
+	Morning Interval `json:"morning"`
+	Evening Interval `json:"evening"`
+}
+
+// SunDay holds the sun events of one local day at a place. Times are UTC.
+type SunDay struct {
+	Date                 string        `json:"date"`
+	SolarNoon            time.Time     `json:"solar_noon"`
+	Sunrise              *time.Time    `json:"sunrise"`
+	Sunset               *time.Time    `json:"sunset"`
+	DayLength            float64       `json:"day_length"` // seconds
+	PolarDay             bool          `json:"polar_day"`
+	PolarNight           bool          `json:"polar_night"`
+	CivilTwilight        Twilight      `json:"civil_twilight"`
+	NauticalTwilight     Twilight      `json:"nautical_twilight"`
+	AstronomicalTwilight Twilight      `json:"astronomical_twilight"`
+	GoldenHour           DailyInterval `json:"golden_hour"`
+	BlueHour             DailyInterval `json:"blue_hour"`
+	Moon                 MoonPhase     `json:"moon"`
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+// julianCentury is the time in Julian centuries since J2000.0
+func julianCentury(t time.Time) float64 {
+	julianDay := float64(t.UnixNano())/float64(24*time.Hour) + 2440587.5
+	return (julianDay - 2451545) / 36525
+}
+
+// sunPosition returns the sun's declination in degrees and the equation of
+// time in minutes at t.
+func sunPosition(t time.Time) (declination, equationOfTime float64) {
+	T := julianCentury(t)
+
+	meanLongitude := math.Mod(280.46646+T*(36000.76983+T*0.0003032), 360)
+	meanAnomaly := 357.52911 + T*(35999.05029-0.0001537*T)
+	eccentricity := 0.016708634 - T*(0.000042037+0.0000001267*T)
+
+	m := radians(meanAnomaly)
+	center := math.Sin(m)*(1.914602-T*(0.004817+0.000014*T)) +
+		math.Sin(2*m)*(0.019993-0.000101*T) +
+		math.Sin(3*m)*0.000289
+
+	omega := radians(125.04 - 1934.136*T)
+	apparentLongitude := meanLongitude + center - 0.00569 - 0.00478*math.Sin(omega)
+
+	meanObliquity := 23 + (26+(21.448-T*(46.815+T*(0.00059-T*0.001813)))/60)/60
+	obliquity := radians(meanObliquity + 0.00256*math.Cos(omega))
+
+	declination = degrees(math.Asin(math.Sin(obliquity) * math.Sin(radians(apparentLongitude))))
+
+	y := math.Pow(math.Tan(obliquity/2), 2)
+	l0 := radians(meanLongitude)
+	equationOfTime = 4 * degrees(y*math.Sin(2*l0)-
+		2*eccentricity*math.Sin(m)+
+		4*eccentricity*y*math.Sin(m)*math.Cos(2*l0)-
+		0.5*y*y*math.Sin(4*l0)-
+		1.25*eccentricity*eccentricity*math.Sin(2*m))
+	return declination, equationOfTime
+}
+
+// SunElevation is the angle in degrees of the sun's centre above the horizon
+// at t, seen from (lat, lng). Refraction is not included.
+func SunElevation(t time.Time, lat, lng float64) float64 {
+	declination, equationOfTime := sunPosition(t)
+
+	utc := t.UTC()
+	minutes := float64(utc.Hour()*60+utc.Minute()) + float64(utc.Second())/60
+	trueSolarTime := math.Mod(minutes+equationOfTime+4*lng, 1440)
+	hourAngle := radians(trueSolarTime/4 - 180)
+
+	cosZenith := math.Sin(radians(lat))*math.Sin(radians(declination)) +
+		math.Cos(radians(lat))*math.Cos(radians(declination))*math.Cos(hourAngle)
+	return 90 - degrees(math.Acos(math.Max(-1, math.Min(1, cosZenith))))
+}
+
+// IsDark reports whether the sun is below the horizon at t
+func IsDark(t time.Time, lat, lng float64) bool {
+	return SunElevation(t, lat, lng) < ElevationSunrise
+}
+
+// horizonDip is how far below the horizon an observer altitude meters up can
+// see, in degrees; it makes the sun rise earlier and set later
+func horizonDip(altitude float64) float64 {
+	if altitude <= 0 {
+		return 0
+	}
+	return 2.076 * math.Sqrt(altitude) / 60
+}
+
+// solarNoon is when the sun culminates on the solar day of date, given as
+// midnight UTC of the calendar date
+func solarNoon(date time.Time, lng float64) time.Time {
+	noon := date.Add(time.Duration((720 - 4*lng) * float64(time.Minute)))
+	for i := 0; i < 2; i++ {
+		_, equationOfTime := sunPosition(noon)
+		noon = date.Add(time.Duration((720 - 4*lng - equationOfTime) * float64(time.Minute)))
+	}
+	return noon.Round(time.Second)
+}
+
+// crossing returns when the sun's centre passes elevation on the solar day of
+// date, in the morning when rising is set, or nil if it never does
+func crossing(date time.Time, lat, lng, elevation float64, rising bool) *time.Time {
+	t := solarNoon(date, lng)
+	for i := 0; i < 3; i++ {
+		declination, equationOfTime := sunPosition(t)
+		cosHourAngle := (math.Sin(radians(elevation)) - math.Sin(radians(lat))*math.Sin(radians(declination))) /
+			(math.Cos(radians(lat)) * math.Cos(radians(declination)))
+		if cosHourAngle < -1 || cosHourAngle > 1 {
+			return nil
+		}
+
+		offset := 4 * degrees(math.Acos(cosHourAngle))
+		if rising {
+			offset = -offset
+		}
+		t = date.Add(time.Duration((720 - 4*lng - equationOfTime + offset) * float64(time.Minute)))
+	}
+	t = t.Round(time.Second)
+	return &t
+}
+
+// LocalDate is the calendar date it currently is at longitude lng, going by
+// solar time since the real time zone is unknown
+func LocalDate(t time.Time, lng float64) time.Time {
+	local := t.UTC().Add(time.Duration(lng / 15 * float64(time.Hour)))
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// SunEvents computes every sun event on date (only its calendar day is used)
+// at (lat, lng) for an observer altitude meters above sea level.
+func SunEvents(date time.Time, lat, lng, altitude float64) SunDay {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	at := func(elevation float64, rising bool) *time.Time {
+		return crossing(date, lat, lng, elevation, rising)
+	}
+	horizon := ElevationSunrise - horizonDip(altitude)
+	noon := solarNoon(date, lng)
+
+	day := SunDay{
+		Date:      date.Format("2006-01-02"),
+		SolarNoon: noon,
+		Sunrise:   at(horizon, true),
+		Sunset:    at(horizon, false),
+		CivilTwilight: Twilight{
+			Dawn: at(ElevationCivil, true),
+			Dusk: at(ElevationCivil, false),
+		},
+		NauticalTwilight: Twilight{
+			Dawn: at(ElevationNautical, true),
+			Dusk: at(ElevationNautical, false),
+		},
+		AstronomicalTwilight: Twilight{
+			Dawn: at(ElevationAstronomical, true),
+			Dusk: at(ElevationAstronomical, false),
+		},
+		GoldenHour: DailyInterval{
+			Morning: Interval{Start: at(horizon, true), End: at(ElevationGoldenHour, true)},
+			Evening: Interval{Start: at(ElevationGoldenHour, false), End: at(horizon, false)},
+		},
+		BlueHour: DailyInterval{
+			Morning: Interval{Start: at(ElevationCivil, true), End: at(ElevationBlueHour, true)},
+			Evening: Interval{Start: at(ElevationBlueHour, false), End: at(ElevationCivil, false)},
+		},
+		Moon: Moon(noon),
+	}
+
+	if day.Sunrise != nil && day.Sunset != nil {
+		day.DayLength = day.Sunset.Sub(*day.Sunrise).Seconds()
+	} else if SunElevation(noon, lat, lng) > horizon {
+		day.PolarDay = true
+		day.DayLength = (24 * time.Hour).Seconds()
+	} else {
+		day.PolarNight = true
+	}
+	return day
+}
+
+// Round rounds every event time and the day length to multiples of d. The
+// events are only accurate to about a minute, and finer times give away the
+// exact place they were computed for.
+func (day SunDay) Round(d time.Duration) SunDay {
+	round := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+		rounded := t.Round(d)
+		return &rounded
+	}
+	twilight := func(t Twilight) Twilight { return Twilight{Dawn: round(t.Dawn), Dusk: round(t.Dusk)} }
+	interval := func(i Interval) Interval { return Interval{Start: round(i.Start), End: round(i.End)} }
+	daily := func(i DailyInterval) DailyInterval {
+		return DailyInterval{Morning: interval(i.Morning), Evening: interval(i.Evening)}
+	}
+
+	day.SolarNoon = day.SolarNoon.Round(d)
+	day.Sunrise = round(day.Sunrise)
+	day.Sunset = round(day.Sunset)
+	day.DayLength = (time.Duration(day.DayLength * float64(time.Second))).Round(d).Seconds()
+	day.CivilTwilight = twilight(day.CivilTwilight)
+	day.NauticalTwilight = twilight(day.NauticalTwilight)
+	day.AstronomicalTwilight = twilight(day.AstronomicalTwilight)
+	day.GoldenHour = daily(day.GoldenHour)
+	day.BlueHour = daily(day.BlueHour)
+	return day
+}
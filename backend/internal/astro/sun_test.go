@@ -0,0 +1,210 @@
+package astro
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Published event times are rounded to the minute and use a slightly
+// different model, so agreement within two minutes is all that's expected.
+const eventTolerance = 2 * time.Minute
+
+func utc(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestSunEvents(t *testing.T) {
+	tests := []struct {
+		name                 string
+		date                 string
+		lat, lng             float64
+		noon                 string
+		sunrise, sunset      string // empty when the sun doesn't rise or set
+		polarDay, polarNight bool
+	}{
+		{"London at midsummer", "2025-06-21", 51.5074, -0.1278, "2025-06-21 12:02", "2025-06-21 03:43", "2025-06-21 20:21", false, false},
+		{"equator at the equinox", "2025-03-20", 0, 0, "2025-03-20 12:07", "2025-03-20 06:04", "2025-03-20 18:11", false, false},
+		// East of Greenwich the solar day starts on the previous UTC date
+		{"Sydney at midsummer", "2025-12-21", -33.8688, 151.2093, "2025-12-21 01:53", "2025-12-20 18:41", "2025-12-21 09:05", false, false},
+		{"Tromsø in June", "2025-06-21", 69.6496, 18.9560, "2025-06-21 10:46", "", "", true, false},
+		{"Tromsø in December", "2025-12-21", 69.6496, 18.9560, "2025-12-21 10:42", "", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := SunEvents(utc(tt.date+" 15:00"), tt.lat, tt.lng, 0)
+			if day.Date != tt.date {
+				t.Errorf("date = %s, want %s", day.Date, tt.date)
+			}
+			near(t, "solar noon", &day.SolarNoon, tt.noon)
+			near(t, "sunrise", day.Sunrise, tt.sunrise)
+			near(t, "sunset", day.Sunset, tt.sunset)
+			if day.PolarDay != tt.polarDay || day.PolarNight != tt.polarNight {
+				t.Errorf("polar day %v, night %v; want %v, %v", day.PolarDay, day.PolarNight, tt.polarDay, tt.polarNight)
+			}
+
+			switch {
+			case tt.polarDay && day.DayLength != 86400:
+				t.Errorf("day length = %v on a polar day", day.DayLength)
+			case tt.polarNight && day.DayLength != 0:
+				t.Errorf("day length = %v on a polar night", day.DayLength)
+			case tt.sunrise != "":
+				if want := day.Sunset.Sub(*day.Sunrise).Seconds(); day.DayLength != want {
+					t.Errorf("day length = %v, want %v", day.DayLength, want)
+				}
+			}
+		})
+	}
+}
+
+// near checks an event time against "2006-01-02 15:04", or its absence for ""
+func near(t *testing.T, event string, got *time.Time, want string) {
+	t.Helper()
+	switch {
+	case want == "" && got != nil:
+		t.Errorf("%s = %s, want none", event, got)
+	case want == "":
+	case got == nil:
+		t.Errorf("no %s, want %s", event, want)
+	case got.Sub(utc(want)).Abs() > eventTolerance:
+		t.Errorf("%s = %s, want %s", event, got.Format("2006-01-02 15:04:05"), want)
+	}
+}
+
+func TestSunEventsOrder(t *testing.T) {
+	for _, place := range []struct {
+		name     string
+		lat, lng float64
+	}{{"Skopje", 41.9981, 21.4254}, {"Quito", -0.1807, -78.4678}, {"Honolulu", 21.3069, -157.8583}} {
+		for _, date := range []string{"2025-01-15", "2025-04-15", "2025-07-15", "2025-10-15"} {
+			day := SunEvents(utc(date+" 00:00"), place.lat, place.lng, 0)
+			events := []struct {
+				name string
+				at   *time.Time
+			}{
+				{"astronomical dawn", day.AstronomicalTwilight.Dawn},
+				{"nautical dawn", day.NauticalTwilight.Dawn},
+				{"civil dawn", day.CivilTwilight.Dawn},
+				{"blue hour end", day.BlueHour.Morning.End},
+				{"sunrise", day.Sunrise},
+				{"golden hour end", day.GoldenHour.Morning.End},
+				{"solar noon", &day.SolarNoon},
+				{"golden hour start", day.GoldenHour.Evening.Start},
+				{"sunset", day.Sunset},
+				{"blue hour start", day.BlueHour.Evening.Start},
+				{"civil dusk", day.CivilTwilight.Dusk},
+				{"nautical dusk", day.NauticalTwilight.Dusk},
+				{"astronomical dusk", day.AstronomicalTwilight.Dusk},
+			}
+			for i, event := range events {
+				if event.at == nil {
+					t.Fatalf("%s %s: no %s", place.name, date, event.name)
+				}
+				if i > 0 && !event.at.After(*events[i-1].at) {
+					t.Errorf("%s %s: %s at %s, not after %s at %s", place.name, date,
+						event.name, event.at, events[i-1].name, events[i-1].at)
+				}
+			}
+
+			// The intervals share their ends with the events
+			if *day.GoldenHour.Morning.Start != *day.Sunrise || *day.GoldenHour.Evening.End != *day.Sunset ||
+				*day.BlueHour.Morning.Start != *day.CivilTwilight.Dawn || *day.BlueHour.Evening.End != *day.CivilTwilight.Dusk {
+				t.Errorf("%s %s: golden or blue hour doesn't meet sunrise, sunset or civil twilight", place.name, date)
+			}
+		}
+	}
+}
+
+func TestSunEventsAltitude(t *testing.T) {
+	date := utc("2025-06-21 00:00")
+	sea := SunEvents(date, 46.5, 8, 0)
+	peak := SunEvents(date, 46.5, 8, 3000)
+
+	// Seen from higher up the sun rises earlier and sets later
+	if !peak.Sunrise.Before(*sea.Sunrise) || !peak.Sunset.After(*sea.Sunset) {
+		t.Errorf("at 3000 m: %s-%s, at sea level: %s-%s", peak.Sunrise, peak.Sunset, sea.Sunrise, sea.Sunset)
+	}
+	// Twilight is bounded by the true horizon, wherever the observer stands
+	if *peak.CivilTwilight.Dawn != *sea.CivilTwilight.Dawn {
+		t.Errorf("civil dawn moved with altitude: %s vs %s", peak.CivilTwilight.Dawn, sea.CivilTwilight.Dawn)
+	}
+	if horizonDip(-10) != 0 {
+		t.Error("dip below sea level")
+	}
+}
+
+func TestSunElevation(t *testing.T) {
+	tests := []struct {
+		name      string
+		at        time.Time
+		lat, lng  float64
+		want      float64
+		tolerance float64
+		dark      bool
+	}{
+		{"overhead at the equinox", utc("2025-03-20 12:07"), 0, 0, 89.5, 0.5, false},
+		{"underfoot at the equinox", utc("2025-03-21 00:07"), 0, 0, -89.5, 0.5, true},
+		// At midsummer noon the sun stands 90 - latitude + 23.44 degrees high
+		{"London at midsummer noon", utc("2025-06-21 12:02"), 51.5074, -0.1278, 61.9, 0.2, false},
+		{"London at midsummer midnight", utc("2025-06-21 00:02"), 51.5074, -0.1278, -15.0, 0.2, true},
+		{"Tromsø at winter noon", utc("2025-12-21 10:42"), 69.6496, 18.956, -3.1, 0.2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SunElevation(tt.at, tt.lat, tt.lng); math.Abs(got-tt.want) > tt.tolerance {
+				t.Errorf("SunElevation = %.2f, want %.2f", got, tt.want)
+			}
+			if got := IsDark(tt.at, tt.lat, tt.lng); got != tt.dark {
+				t.Errorf("IsDark = %v, want %v", got, tt.dark)
+			}
+		})
+	}
+}
+
+func TestLocalDate(t *testing.T) {
+	tests := []struct {
+		at   string
+		lng  float64
+		want string
+	}{
+		{"2025-06-01 12:00", 0, "2025-06-01"},
+		{"2025-06-01 23:30", 30, "2025-06-02"},
+		{"2025-06-01 01:00", -30, "2025-05-31"},
+		{"2025-12-31 13:00", 179, "2026-01-01"},
+	}
+	for _, tt := range tests {
+		if got := LocalDate(utc(tt.at), tt.lng).Format("2006-01-02"); got != tt.want {
+			t.Errorf("LocalDate(%s, %v) = %s, want %s", tt.at, tt.lng, got, tt.want)
+		}
+	}
+}
+
+func TestSunDayRound(t *testing.T) {
+	// No astronomical twilight: the sun stays above -18 degrees all night
+	day := SunEvents(utc("2025-06-21 00:00"), 51.5074, -0.1278, 0).Round(time.Minute)
+
+	times := []*time.Time{&day.SolarNoon, day.Sunrise, day.Sunset,
+		day.CivilTwilight.Dawn, day.NauticalTwilight.Dawn, day.NauticalTwilight.Dusk,
+		day.GoldenHour.Morning.End, day.GoldenHour.Evening.Start, day.BlueHour.Morning.End, day.BlueHour.Evening.Start}
+	for _, at := range times {
+		if at == nil {
+			t.Fatal("missing event")
+		}
+		if at.Second() != 0 || at.Nanosecond() != 0 {
+			t.Errorf("%s not rounded to the minute", at)
+		}
+	}
+	if math.Mod(day.DayLength, 60) != 0 {
+		t.Errorf("day length %v not rounded to the minute", day.DayLength)
+	}
+
+	// Rounding leaves missing events missing
+	polar := SunEvents(utc("2025-06-21 00:00"), 69.6496, 18.956, 0).Round(time.Minute)
+	if polar.Sunrise != nil || polar.Sunset != nil || polar.DayLength != 86400 {
+		t.Errorf("polar day rounded to sunrise %v, sunset %v, length %v", polar.Sunrise, polar.Sunset, polar.DayLength)
+	}
+}
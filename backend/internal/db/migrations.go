@@ -0,0 +1,287 @@
+package db
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"chillspot-backend/internal/geo"
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RunMigrations applies the schema changes AutoMigrate can't express
+func RunMigrations(db *gorm.DB) error {
+	// Prefix (LIKE 'abc%') lookups on geohash need the pattern operator class
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_spots_geohash ON spots (geohash varchar_pattern_ops)").Error; err != nil {
+		return err
+	}
+
+	if err := backfillGeohashes(db); err != nil {
+		return err
+	}
+
+	if err := migrateSpotSearch(db); err != nil {
+		return err
+	}
+
+	if err := backfillSpotPhotos(db); err != nil {
+		return err
+	}
+
+	if err := normalizeRecommendedWeather(db); err != nil {
+		return err
+	}
+
+	if err := migrateTaxonomy(db); err != nil {
+		return err
+	}
+
+	if err := seedDiscoveryBadge(db); err != nil {
+		return err
+	}
+
+	return runOnce(db, "reconcile_visits", reconcileVisits)
+}
+
+// appliedMigration marks a one-time migration as done
+type appliedMigration struct {
+	Name      string `gorm:"type:varchar(100);primaryKey"`
+	AppliedAt time.Time
+}
+
+// runOnce applies a migration that must never run twice, such as one that
+// rewrites rows by a rule only true of the data at the time. Servers
+// starting together wait on the marker, so only one of them applies it.
+func runOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	if err := db.AutoMigrate(&appliedMigration{}); err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		marked := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&appliedMigration{Name: name, AppliedAt: time.Now()})
+		if marked.Error != nil || marked.RowsAffected == 0 {
+			return marked.Error
+		}
+		log.Printf("Applying migration %s", name)
+		return migrate(tx)
+	})
+}
+
+// reconcileVisits brings the visits recorded before the visit service in
+// line with its rules. Their days are UTC, as nobody had a timezone then;
+// visits awaiting an anti-cheat review are left alone. Legacy unverified
+// visits stay in the journal but, as for new visits, count for nothing.
+//   - Same-day repeats of a verified visit are merged into the earliest,
+//     keeping notes.
+//   - Each user's earliest verified visit to a spot becomes its first visit.
+//   - Spots' visit_count is recounted from verified visits not rejected in a
+//     review, the ones the visit service counts.
+func reconcileVisits(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+			WITH legacy AS (
+				SELECT id, notes, visited_at,
+					FIRST_VALUE(id) OVER (
+						PARTITION BY user_id, spot_id, (visited_at AT TIME ZONE 'UTC')::date
+						ORDER BY visited_at, id) AS keep_id
+				FROM visited_spots
+				WHERE verified AND flag_status IN ('none', 'cleared')
+			), merged AS (
+				SELECT keep_id, STRING_AGG(notes, E'\n' ORDER BY visited_at, id) AS notes
+				FROM legacy
+				WHERE notes <> ''
+				GROUP BY keep_id
+			)
+			UPDATE visited_spots SET notes = merged.notes
+			FROM merged
+			WHERE visited_spots.id = merged.keep_id AND visited_spots.notes <> merged.notes
+				AND EXISTS (SELECT 1 FROM legacy WHERE legacy.keep_id = merged.keep_id AND legacy.id <> legacy.keep_id)`).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`
+			DELETE FROM visited_spots WHERE id IN (
+				SELECT id FROM (
+					SELECT id, ROW_NUMBER() OVER (
+						PARTITION BY user_id, spot_id, (visited_at AT TIME ZONE 'UTC')::date
+						ORDER BY visited_at, id) AS n
+					FROM visited_spots
+					WHERE verified AND flag_status IN ('none', 'cleared')
+				) repeats
+				WHERE n > 1)`).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`
+			UPDATE visited_spots SET first_visit = TRUE
+			WHERE id IN (
+				SELECT DISTINCT ON (user_id, spot_id) id
+				FROM visited_spots
+				WHERE verified
+				ORDER BY user_id, spot_id, visited_at, id)
+			AND NOT EXISTS (
+				SELECT 1 FROM visited_spots first
+				WHERE first.user_id = visited_spots.user_id AND first.spot_id = visited_spots.spot_id
+					AND first.first_visit)`).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			WITH counts AS (
+				SELECT spots.id, COUNT(visited_spots.id) AS visits
+				FROM spots
+				LEFT JOIN visited_spots ON visited_spots.spot_id = spots.id
+					AND visited_spots.verified AND visited_spots.flag_status <> 'confirmed'
+				GROUP BY spots.id
+			)
+			UPDATE spots SET visit_count = counts.visits
+			FROM counts
+			WHERE spots.id = counts.id AND spots.visit_count <> counts.visits`).Error
+	})
+}
+
+// seedDiscoveryBadge adds a badge for revealing a first geocache spot unless
+// a discoveries badge was defined already
+func seedDiscoveryBadge(db *gorm.DB) error {
+	var defined int64
+	if err := db.Model(&models.BadgeDefinition{}).Where("type = ?", models.BadgeDiscoveries).Count(&defined).Error; err != nil {
+		return err
+	}
+	if defined > 0 {
+		return nil
+	}
+	return db.Create(&models.BadgeDefinition{
+		Name:      "Geocacher",
+		ImagePath: "badges/explorer_badge.png",
+		Type:      models.BadgeDiscoveries,
+		Threshold: 1,
+	}).Error
+}
+
+// migrateTaxonomy seeds the default categories while there are none yet, so
+// admin edits are kept, and indexes tag names for autocomplete.
+func migrateTaxonomy(db *gorm.DB) error {
+	var seeded int64
+	if err := db.Model(&models.Category{}).Count(&seeded).Error; err != nil {
+		return err
+	}
+	if seeded == 0 {
+		categories := append([]models.Category(nil), models.DefaultCategories...)
+		if err := db.Create(&categories).Error; err != nil {
+			return err
+		}
+	}
+
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_tags_name_pattern ON tags (name varchar_pattern_ops)").Error
+}
+
+// normalizeRecommendedWeather turns the free-text recommended_weather of
+// spots and revisions written before conditions were validated into the
+// recommended_weathers list. Values that match no condition are dropped.
+func normalizeRecommendedWeather(db *gorm.DB) error {
+	for _, table := range []string{"spots", "spot_revisions"} {
+		var rows []struct {
+			ID                 uuid.UUID
+			RecommendedWeather string
+		}
+		if err := db.Table(table).
+			Select("id", "recommended_weather").
+			Where("recommended_weathers IS NULL").
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			conditions := models.WeatherConditions{}
+			for _, part := range strings.FieldsFunc(row.RecommendedWeather, func(r rune) bool {
+				return r == ',' || r == '/' || r == ';' || r == '|'
+			}) {
+				if strings.TrimSpace(part) == "" {
+					continue
+				}
+				condition, ok := models.ParseWeatherCondition(part)
+				if !ok {
+					log.Printf("Dropping unknown weather %q of %s %s", part, table, row.ID)
+					continue
+				}
+				conditions = append(conditions, condition)
+			}
+			conditions = conditions.Normalize()
+
+			if err := db.Table(table).Where("id = ?", row.ID).UpdateColumns(map[string]any{
+				"recommended_weather":  conditions.Primary(),
+				"recommended_weathers": conditions,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backfillSpotPhotos adds the day and night images of spots created before
+// galleries existed as their first photos, the day image as the cover.
+func backfillSpotPhotos(db *gorm.DB) error {
+	statements := []string{
+		`INSERT INTO spot_photos (id, spot_id, user_id, filename, time_of_day, position, is_cover, created_at)
+			SELECT gen_random_uuid(), s.id, s.user_id, s.day_image, 'day', 0, TRUE, s.created_at
+			FROM spots s
+			WHERE s.day_image IS NOT NULL AND s.day_image <> ''
+				AND NOT EXISTS (SELECT 1 FROM spot_photos p WHERE p.spot_id = s.id)`,
+		`INSERT INTO spot_photos (id, spot_id, user_id, filename, time_of_day, position, is_cover, created_at)
+			SELECT gen_random_uuid(), s.id, s.user_id, s.night_image, 'night', 1,
+				NOT EXISTS (SELECT 1 FROM spot_photos p WHERE p.spot_id = s.id AND p.is_cover), s.created_at
+			FROM spots s
+			WHERE s.night_image IS NOT NULL AND s.night_image <> ''
+				AND NOT EXISTS (SELECT 1 FROM spot_photos p WHERE p.spot_id = s.id AND p.filename = s.night_image)`,
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateSpotSearch adds the full-text search column and indexes. The
+// tsvector is a generated column, so Postgres keeps it in sync on every write.
+func migrateSpotSearch(db *gorm.DB) error {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		`ALTER TABLE spots ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+			) STORED`,
+		"CREATE INDEX IF NOT EXISTS idx_spots_search_vector ON spots USING GIN (search_vector)",
+		"CREATE INDEX IF NOT EXISTS idx_spots_title_trgm ON spots USING GIN (title gin_trgm_ops)",
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillGeohashes computes the geohash of spots created before the column existed
+func backfillGeohashes(db *gorm.DB) error {
+	var spots []models.Spot
+	return db.Select("id", "latitude", "longitude").
+		Where("geohash IS NULL OR geohash = ''").
+		FindInBatches(&spots, 500, func(tx *gorm.DB, batch int) error {
+			for _, spot := range spots {
+				hash := geo.Encode(spot.Latitude, spot.Longitude, geo.MaxPrecision)
+				if err := tx.Model(&models.Spot{}).Where("id = ?", spot.ID).UpdateColumn("geohash", hash).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
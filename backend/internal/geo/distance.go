@@ -0,0 +1,36 @@
+package geo
+
+import "math"
+
+// EarthRadius is the mean radius of the Earth in meters
+const EarthRadius = 6371000
+
+// Distance returns the great-circle distance in meters between two points
+// (haversine formula)
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	lat1Rad := lat1 * math.Pi / 180
+	lat2Rad := lat2 * math.Pi / 180
+	deltaLat := (lat2 - lat1) * math.Pi / 180
+	deltaLng := (lng2 - lng1) * math.Pi / 180
+
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(lat1Rad)*math.Cos(lat2Rad)*
+			math.Sin(deltaLng/2)*math.Sin(deltaLng/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	return EarthRadius * c
+}
+
+// Offset moves a point by the given number of meters north and east. It is
+// accurate for the short distances used by hint circles, away from the poles.
+func Offset(lat, lng, north, east float64) (float64, float64) {
+	metersPerDegree := EarthRadius * math.Pi / 180
+	lat2 := lat + north/metersPerDegree
+	lng2 := lng + east/(metersPerDegree*math.Cos(lat*math.Pi/180))
+	if lng2 > 180 {
+		lng2 -= 360
+	} else if lng2 < -180 {
+		lng2 += 360
+	}
+	return math.Max(-90, math.Min(90, lat2)), lng2
+}
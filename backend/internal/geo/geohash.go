@@ -0,0 +1,132 @@
+package geo
+
+import (
+	"math"
+	"strings"
+)
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// MaxPrecision is the geohash length stored on every spot (~19cm x 37cm cell)
+const MaxPrecision = 9
+
+// Approximate cell height in meters for each geohash length
+var cellHeights = []float64{0, 5009400, 624100, 156000, 19500, 4890, 610, 153, 19.1, 4.77}
+
+// Approximate cell width in meters at the equator for each geohash length
+var cellWidths = []float64{0, 5009400, 1252300, 156500, 39100, 4890, 1220, 153, 38.2, 4.77}
+
+// Encode returns the geohash of the given point with the given length
+func Encode(lat, lng float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+
+	var sb strings.Builder
+	bit, ch := 0, 0
+	even := true
+	for sb.Len() < precision {
+		if even {
+			mid := (lngRange[0] + lngRange[1]) / 2
+			if lng >= mid {
+				ch |= 1 << (4 - bit)
+				lngRange[0] = mid
+			} else {
+				lngRange[1] = mid
+			}
+		} else {
+			mid := (latRange[0] + latRange[1]) / 2
+			if lat >= mid {
+				ch |= 1 << (4 - bit)
+				latRange[0] = mid
+			} else {
+				latRange[1] = mid
+			}
+		}
+		even = !even
+
+		if bit < 4 {
+			bit++
+		} else {
+			sb.WriteByte(base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String()
+}
+
+// CellSize returns the size in degrees of a geohash cell of the given length.
+// The cells of one length tile the globe in a regular grid from (-90, -180).
+func CellSize(precision int) (latDeg, lngDeg float64) {
+	lngBits := (5*precision + 1) / 2
+	latBits := 5*precision - lngBits
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lngBits))
+}
+
+// PrecisionForRadius picks the longest geohash whose cell is at least as large
+// as the radius, so the cell around a point plus its 8 neighbours always
+// contain the whole search circle. Cells are measured at the circle's
+// poleward edge, where they are narrowest.
+func PrecisionForRadius(lat, radius float64) int {
+	edge := math.Min(90, math.Abs(lat)+radius/(EarthRadius*math.Pi/180))
+	scale := math.Cos(edge * math.Pi / 180)
+	for p := MaxPrecision; p > 1; p-- {
+		if cellHeights[p] >= radius && cellWidths[p]*scale >= radius {
+			return p
+		}
+	}
+	return 1
+}
+
+// CoveringCells returns the geohash of the point's cell and its 8 neighbours
+func CoveringCells(lat, lng float64, precision int) []string {
+	dLat, dLng := CellSize(precision)
+
+	seen := make(map[string]bool)
+	var cells []string
+	for _, i := range []float64{-1, 0, 1} {
+		for _, j := range []float64{-1, 0, 1} {
+			nLat := math.Max(-90, math.Min(90, lat+i*dLat))
+			nLng := lng + j*dLng
+			if nLng > 180 {
+				nLng -= 360
+			} else if nLng < -180 {
+				nLng += 360
+			}
+
+			hash := Encode(nLat, nLng, precision)
+			if !seen[hash] {
+				seen[hash] = true
+				cells = append(cells, hash)
+			}
+		}
+	}
+	return cells
+}
+
+// Center decodes a geohash into the centre of its cell
+func Center(hash string) (lat, lng float64) {
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+
+	even := true
+	for i := 0; i < len(hash); i++ {
+		ch := strings.IndexByte(base32, hash[i])
+		if ch < 0 {
+			break
+		}
+		for bit := 4; bit >= 0; bit-- {
+			r := &latRange
+			if even {
+				r = &lngRange
+			}
+			mid := (r[0] + r[1]) / 2
+			if ch&(1<<bit) != 0 {
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+	}
+	return (latRange[0] + latRange[1]) / 2, (lngRange[0] + lngRange[1]) / 2
+}
@@ -0,0 +1,126 @@
+package geo
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name      string
+		lat, lng  float64
+		precision int
+		want      string
+	}{
+		{"reference point", 57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{"short hash", 42.6, -5.6, 5, "ezs42"},
+		{"origin", 0, 0, 1, "s"},
+		{"south-west corner", -90, -180, 4, "0000"},
+		{"north-east corner", 90, 180, 4, "zzzz"},
+		{"empty", 10, 10, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Encode(tt.lat, tt.lng, tt.precision); got != tt.want {
+				t.Errorf("Encode(%v, %v, %d) = %q, want %q", tt.lat, tt.lng, tt.precision, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCenter(t *testing.T) {
+	tests := []struct {
+		hash             string
+		wantLat, wantLng float64
+		tolerance        float64
+	}{
+		{"s", 22.5, 22.5, 0},
+		{"ezs42", 42.605, -5.603, 0.001},
+		{"u4pruydqqvj", 57.64911, 10.40744, 0.00001},
+		// Decoding stops at the first character that isn't base32
+		{"s!zz", 22.5, 22.5, 0},
+		{"", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			lat, lng := Center(tt.hash)
+			if math.Abs(lat-tt.wantLat) > tt.tolerance || math.Abs(lng-tt.wantLng) > tt.tolerance {
+				t.Errorf("Center(%q) = (%v, %v), want (%v, %v)", tt.hash, lat, lng, tt.wantLat, tt.wantLng)
+			}
+		})
+	}
+}
+
+func TestCenterRoundTrip(t *testing.T) {
+	for _, hash := range []string{"0", "zzzz", "u4pruydqq", "r3gx2f9tt", "9q8yy"} {
+		lat, lng := Center(hash)
+		if got := Encode(lat, lng, len(hash)); got != hash {
+			t.Errorf("Encode(Center(%q)) = %q", hash, got)
+		}
+	}
+}
+
+func TestPrecisionForRadius(t *testing.T) {
+	tests := []struct {
+		name        string
+		lat, radius float64
+		want        int
+	}{
+		{"tiny radius", 0, 1, MaxPrecision},
+		{"100 m at the equator", 0, 100, 7},
+		{"1 km at the equator", 0, 1000, 5},
+		// Cells narrow away from the equator, so a coarser one is needed
+		{"100 m at 60 degrees", 60, 100, 6},
+		// Cells 13.37 km wide at 70 degrees are only 13.29 km wide at the
+		// circle's northern edge
+		{"13.3 km at 70 degrees", 70, 13300, 3},
+		{"13.3 km at -70 degrees", -70, 13300, 3},
+		{"13.2 km at 70 degrees", 70, 13200, 4},
+		{"reaching the pole", 89.9, 20000, 1},
+		{"wider than any cell", 0, 10000000, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrecisionForRadius(tt.lat, tt.radius); got != tt.want {
+				t.Errorf("PrecisionForRadius(%v, %v) = %d, want %d", tt.lat, tt.radius, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoveringCells(t *testing.T) {
+	tests := []struct {
+		name      string
+		lat, lng  float64
+		precision int
+		want      int // distinct cells
+	}{
+		{"mid-latitude", 41.9981, 21.4254, 6, 9},
+		{"across the antimeridian", 0, 179.999, 5, 9},
+		{"at the north pole", 90, 0, 4, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cells := CoveringCells(tt.lat, tt.lng, tt.precision)
+			if len(cells) != tt.want {
+				t.Errorf("got %d cells %v, want %d", len(cells), cells, tt.want)
+			}
+
+			// Any point less than a cell away is in one of them
+			dLat, dLng := CellSize(tt.precision)
+			for _, north := range []float64{-0.99, 0, 0.99} {
+				for _, east := range []float64{-0.99, 0, 0.99} {
+					lat := math.Max(-90, math.Min(90, tt.lat+north*dLat))
+					lng := tt.lng + east*dLng
+					if lng > 180 {
+						lng -= 360
+					}
+					if hash := Encode(lat, lng, tt.precision); !slices.Contains(cells, hash) {
+						t.Errorf("(%v, %v) in %s, not covered by %v", lat, lng, hash, cells)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	// Default of ANTI_CHEAT_MAX_SPEED, about 250 km/h
+	defaultMaxTravelSpeed = 70.0 // m/s
+	// Closer than this to the spot's stored coordinates is too good for GPS
+	perfectFixDistance = 0.5 // meters
+	rapidCheckInWindow = time.Minute
+	// Earlier check-ins at other spots within rapidCheckInWindow that flag a visit
+	rapidCheckInLimit = 3
+	// Earlier check-ins that must share the visit's accuracy to flag it
+	constantAccuracyRun = 5
+)
+
+var errAlreadyReviewed = errors.New("visit was already reviewed")
+
+// detectCheating runs the anti-cheat checks on a verified visit against the
+// user's earlier check-ins and returns a flag for each one it fails
+func detectCheating(db *gorm.DB, visit *models.VisitedSpot) ([]models.VisitFlag, error) {
+	var history []models.VisitedSpot
+	if err := db.Where("user_id = ? AND id <> ? AND reported_at IS NOT NULL AND reported_at <= ?",
+		visit.UserID, visit.ID, *visit.ReportedAt).
+		Order("reported_at DESC").
+		Limit(constantAccuracyRun).
+		Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return cheatFlags(visit, history), nil
+}
+
+// cheatFlags are the checks of detectCheating on the user's latest check-ins
+// before the visit, newest first
+func cheatFlags(visit *models.VisitedSpot, history []models.VisitedSpot) []models.VisitFlag {
+	var flags []models.VisitFlag
+	flag := func(reason models.FlagReason, detail string, args ...any) {
+		flags = append(flags, models.VisitFlag{Reason: reason, Detail: fmt.Sprintf(detail, args...)})
+	}
+
+	if len(history) > 0 && history[0].Latitude != nil && history[0].Longitude != nil {
+		previous := history[0]
+		distance := calculateDistance(*previous.Latitude, *previous.Longitude, *visit.Latitude, *visit.Longitude)
+		elapsed := visit.ReportedAt.Sub(*previous.ReportedAt).Seconds()
+		// Distances within the check-in radius are GPS noise
+		if distance > envFloat("VISIT_RADIUS", defaultVisitRadius) {
+			maxSpeed := envFloat("ANTI_CHEAT_MAX_SPEED", defaultMaxTravelSpeed)
+			switch {
+			case elapsed <= 0:
+				flag(models.FlagImpossibleSpeed, "%.0f m from the previous check-in at the same time", distance)
+			case distance/elapsed > maxSpeed:
+				flag(models.FlagImpossibleSpeed, "%.0f km/h since the previous check-in", distance/elapsed*3.6)
+			}
+		}
+	}
+
+	switch {
+	case *visit.Accuracy == 0:
+		flag(models.FlagPerfectCoordinates, "accuracy of 0 m")
+	case *visit.Distance < perfectFixDistance:
+		flag(models.FlagPerfectCoordinates, "%.2f m from the spot's coordinates", *visit.Distance)
+	}
+
+	rapid := 0
+	for _, earlier := range history {
+		if earlier.SpotID != visit.SpotID && visit.ReportedAt.Sub(*earlier.ReportedAt) <= rapidCheckInWindow {
+			rapid++
+		}
+	}
+	if rapid >= rapidCheckInLimit {
+		flag(models.FlagRapidCheckIns, "%d check-ins at other spots within %s", rapid, rapidCheckInWindow)
+	}
+
+	if len(history) == constantAccuracyRun {
+		constant := true
+		for _, earlier := range history {
+			if earlier.Accuracy == nil || *earlier.Accuracy != *visit.Accuracy {
+				constant = false
+				break
+			}
+		}
+		if constant {
+			flag(models.FlagConstantAccuracy, "accuracy of %g m on the last %d check-ins", *visit.Accuracy, constantAccuracyRun+1)
+		}
+	}
+	return flags
+}
+
+// screenVisit checks a freshly verified visit and, if it looks faked, sends
+// it to the review queue holding xp in escrow. It reports whether the visit
+// was flagged.
+func screenVisit(db *gorm.DB, visit *models.VisitedSpot, xp int) (bool, error) {
+	if !visit.Verified {
+		return false, nil
+	}
+	flags, err := detectCheating(db, visit)
+	if err != nil || len(flags) == 0 {
+		return false, err
+	}
+	visit.Flags = flags
+	visit.FlagStatus = models.FlagPending
+	visit.EscrowXP = xp
+	return true, nil
+}
+
+// GetFlaggedVisitsHandler is the anti-cheat review queue, latest first.
+// ?status= picks pending (the default), confirmed or cleared visits and
+// ?user_id= a single user's.
+func GetFlaggedVisitsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		page, err := parsePageRequest(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		status := models.FlagStatus(query.Get("status"))
+		switch status {
+		case "":
+			status = models.FlagPending
+		case models.FlagPending, models.FlagConfirmed, models.FlagCleared:
+		default:
+			http.Error(w, "Invalid status", http.StatusBadRequest)
+			return
+		}
+
+		visits := db.Preload("Flags").Preload("User").Preload("Spot").Where("flag_status = ?", status)
+		if userID := query.Get("user_id"); userID != "" {
+			userUUID, err := uuid.Parse(userID)
+			if err != nil {
+				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+			visits = visits.Where("user_id = ?", userUUID)
+		}
+
+		tx, err := applyKeyset[time.Time](visits, page, "visited_at", "id", true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var flagged []models.VisitedSpot
+		if err := tx.Find(&flagged).Error; err != nil {
+			http.Error(w, "Failed to fetch flagged visits", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newPage(flagged, page, "visited_at", func(v models.VisitedSpot) (any, uuid.UUID) {
+			return v.VisitedAt, v.ID
+		}))
+	}
+}
+
+// ReviewFlaggedVisitHandler lets an admin confirm a flagged visit as faked,
+// which forfeits its escrowed XP and verification, or clear it, which pays
+// the XP out and awards any badges the visit earned
+func ReviewFlaggedVisitHandler(db *gorm.DB, confirm bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		visitUUID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid visit ID", http.StatusBadRequest)
+			return
+		}
+
+		var visit models.VisitedSpot
+		if err := db.Preload("Flags").Where("id = ?", visitUUID).First(&visit).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(w, "Visit not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+			}
+			return
+		}
+		if visit.FlagStatus != models.FlagPending {
+			http.Error(w, "Visit is not awaiting review", http.StatusConflict)
+			return
+		}
+
+		now := time.Now()
+		escrow := visit.EscrowXP
+		updates := map[string]any{"escrow_xp": 0, "reviewed_at": now}
+		if confirm {
+			updates["flag_status"] = models.FlagConfirmed
+			updates["verified"] = false
+			updates["verification"] = models.VisitRejected
+		} else {
+			updates["flag_status"] = models.FlagCleared
+			updates["xp_awarded"] = escrow
+		}
+
+		var newBadges []models.Badge
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// The status condition keeps concurrent reviews from paying out twice
+			result := tx.Model(&models.VisitedSpot{}).
+				Where("id = ? AND flag_status = ?", visit.ID, models.FlagPending).
+				Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errAlreadyReviewed
+			}
+			if confirm {
+				// A faked visit no longer counts toward the spot
+				return tx.Model(&models.Spot{}).
+					Where("id = ? AND visit_count > 0", visit.SpotID).
+					Update("visit_count", gorm.Expr("visit_count - 1")).Error
+			}
+			if escrow > 0 {
+				if err := tx.Model(&models.User{}).
+					Where("id = ?", visit.UserID).
+					Update("xp", gorm.Expr("xp + ?", escrow)).Error; err != nil {
+					return err
+				}
+			}
+			// The badges were held back along with the XP
+			var err error
+			newBadges, err = awardBadges(tx, visit.UserID)
+			return err
+		})
+		if errors.Is(err, errAlreadyReviewed) {
+			http.Error(w, "Visit is not awaiting review", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to review visit", http.StatusInternalServerError)
+			return
+		}
+
+		visit.EscrowXP = 0
+		visit.ReviewedAt = &now
+		visit.FlagStatus = models.FlagCleared
+		message, released := "Flags cleared, XP released", escrow
+		if confirm {
+			visit.FlagStatus = models.FlagConfirmed
+			visit.Verified = false
+			visit.Verification = models.VisitRejected
+			message, released = "Visit rejected, XP forfeited", 0
+		} else {
+			visit.XPAwarded = escrow
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":     message,
+			"xp_released": released,
+			"new_badges":  badgeResponses(newBadges),
+			"visit":       visit,
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"chillspot-backend/internal/geo"
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestCheatFlags(t *testing.T) {
+	home, other := uuid.New(), uuid.New()
+	now := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	// checkIn is a check-in at a spot, north meters from the visit's position,
+	// ago before it, 10 m from the spot's coordinates
+	checkIn := func(spot uuid.UUID, north, accuracy float64, ago time.Duration) models.VisitedSpot {
+		lat, lng := geo.Offset(41.9981, 21.4254, north, 0)
+		at := now.Add(-ago)
+		distance := 10.0
+		return models.VisitedSpot{SpotID: spot, Latitude: &lat, Longitude: &lng, Accuracy: &accuracy, ReportedAt: &at, Distance: &distance}
+	}
+	visit := checkIn(home, 0, 8, 0)
+	with := func(edit func(*models.VisitedSpot)) models.VisitedSpot {
+		v := checkIn(home, 0, 8, 0)
+		edit(&v)
+		return v
+	}
+	repeat := func(n int, c func(i int) models.VisitedSpot) []models.VisitedSpot {
+		history := make([]models.VisitedSpot, n)
+		for i := range history {
+			history[i] = c(i)
+		}
+		return history
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		visit   models.VisitedSpot
+		history []models.VisitedSpot
+		want    []models.FlagReason
+	}{
+		{"first check-in", nil, visit, nil, nil},
+		{"plausible trip", nil, visit, []models.VisitedSpot{checkIn(other, 10000, 8, 10*time.Minute)}, nil},
+		{"too fast", nil, visit, []models.VisitedSpot{checkIn(other, 10000, 9, time.Minute)},
+			[]models.FlagReason{models.FlagImpossibleSpeed}},
+		{"two places at once", nil, visit, []models.VisitedSpot{checkIn(other, 1000, 9, 0)},
+			[]models.FlagReason{models.FlagImpossibleSpeed}},
+		// Jumps within the check-in radius are GPS noise
+		{"jitter", nil, visit, []models.VisitedSpot{checkIn(other, 90, 9, 0)}, nil},
+		{"previous without a position", nil, visit, []models.VisitedSpot{with(func(v *models.VisitedSpot) {
+			v.Latitude, v.Longitude = nil, nil
+		})}, nil},
+		{"faster limit", map[string]string{"ANTI_CHEAT_MAX_SPEED": "200"}, visit,
+			[]models.VisitedSpot{checkIn(other, 10000, 9, time.Minute)}, nil},
+		{"zero accuracy", nil, with(func(v *models.VisitedSpot) { *v.Accuracy = 0 }), nil,
+			[]models.FlagReason{models.FlagPerfectCoordinates}},
+		{"exactly on the spot", nil, with(func(v *models.VisitedSpot) { *v.Distance = 0.2 }), nil,
+			[]models.FlagReason{models.FlagPerfectCoordinates}},
+		{"rapid check-ins", nil, visit, repeat(3, func(i int) models.VisitedSpot {
+			return checkIn(uuid.New(), 0, float64(i), time.Duration(i+1)*10*time.Second)
+		}), []models.FlagReason{models.FlagRapidCheckIns}},
+		{"rapid at the same spot", nil, visit, repeat(3, func(i int) models.VisitedSpot {
+			return checkIn(home, 0, float64(i), time.Duration(i+1)*10*time.Second)
+		}), nil},
+		{"two quick check-ins", nil, visit, repeat(2, func(i int) models.VisitedSpot {
+			return checkIn(uuid.New(), 0, float64(i), time.Duration(i+1)*10*time.Second)
+		}), nil},
+		{"spread out check-ins", nil, visit, repeat(3, func(i int) models.VisitedSpot {
+			return checkIn(uuid.New(), 0, float64(i), time.Duration(i+1)*time.Hour)
+		}), nil},
+		{"constant accuracy", nil, visit, repeat(constantAccuracyRun, func(i int) models.VisitedSpot {
+			return checkIn(other, 0, 8, time.Duration(i+1)*time.Hour)
+		}), []models.FlagReason{models.FlagConstantAccuracy}},
+		{"too few to tell", nil, visit, repeat(constantAccuracyRun-1, func(i int) models.VisitedSpot {
+			return checkIn(other, 0, 8, time.Duration(i+1)*time.Hour)
+		}), nil},
+		{"varying accuracy", nil, visit, repeat(constantAccuracyRun, func(i int) models.VisitedSpot {
+			return checkIn(other, 0, float64(8+i%2), time.Duration(i+1)*time.Hour)
+		}), nil},
+		{"everything at once", nil, with(func(v *models.VisitedSpot) { *v.Accuracy = 0 }), repeat(constantAccuracyRun, func(i int) models.VisitedSpot {
+			return checkIn(uuid.New(), 5000, 0, time.Duration(i+1)*5*time.Second)
+		}), []models.FlagReason{models.FlagImpossibleSpeed, models.FlagPerfectCoordinates, models.FlagRapidCheckIns, models.FlagConstantAccuracy}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"VISIT_RADIUS", "ANTI_CHEAT_MAX_SPEED"} {
+				t.Setenv(name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			var got []models.FlagReason
+			for _, flag := range cheatFlags(&tt.visit, tt.history) {
+				if flag.Detail == "" {
+					t.Errorf("%s flag without detail", flag.Reason)
+				}
+				got = append(got, flag.Reason)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("flags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BadgeResponse struct {
@@ -24,6 +25,112 @@ type BadgeCheckResponse struct {
 	AllBadges []BadgeResponse `json:"allBadges"`
 }
 
+func badgeResponses(badges []models.Badge) []BadgeResponse {
+	responses := []BadgeResponse{}
+	for _, b := range badges {
+		responses = append(responses, BadgeResponse{
+			ID:        b.ID,
+			UserID:    b.UserID,
+			Name:      b.Name,
+			ImagePath: b.ImagePath,
+			CreatedAt: b.CreatedAt,
+		})
+	}
+	return responses
+}
+
+// visitedSpotCount is what visit badges count. Repeat visits don't count:
+// the badge is for distinct spots.
+func visitedSpotCount(db *gorm.DB, userUUID uuid.UUID) (int64, error) {
+	var count int64
+	err := db.Model(&models.VisitedSpot{}).
+		Where("user_id = ? AND verified AND flag_status <> ?", userUUID, models.FlagPending).
+		Distinct("spot_id").
+		Count(&count).Error
+	return count, err
+}
+
+// revokeVisitBadges takes back the visit badges the user no longer reaches
+// the threshold of, once visits are gone, returning them
+func revokeVisitBadges(db *gorm.DB, userUUID uuid.UUID) ([]models.Badge, error) {
+	visits, err := visitedSpotCount(db, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked []models.Badge
+	err = db.Clauses(clause.Returning{}).
+		Where("user_id = ? AND badge_def_id IN (?)", userUUID,
+			db.Model(&models.BadgeDefinition{}).Select("id").Where("type = ? AND threshold > ?", models.BadgeVisits, visits)).
+		Delete(&revoked).Error
+	return revoked, err
+}
+
+// awardBadges gives the user every badge whose threshold they reached and
+// doesn't have yet, returning the new ones
+func awardBadges(db *gorm.DB, userUUID uuid.UUID) ([]models.Badge, error) {
+	// Get user counts
+	var counts struct {
+		Reviews     int64
+		Visits      int64
+		Spots       int64
+		Friends     int64
+		Likes       int64
+		Discoveries int64
+	}
+
+	db.Model(&models.Review{}).Where("user_id = ?", userUUID).Count(&counts.Reviews)
+	counts.Visits, _ = visitedSpotCount(db, userUUID)
+	db.Model(&models.Spot{}).Where("user_id = ?", userUUID).Count(&counts.Spots)
+	db.Table("user_friends").Where("user_id = ?", userUUID).Count(&counts.Friends)
+	db.Model(&models.Like{}).Where("user_id = ?", userUUID).Count(&counts.Likes)
+	db.Model(&models.SpotDiscovery{}).Where("user_id = ?", userUUID).Count(&counts.Discoveries)
+
+	// Get all badge definitions
+	var definitions []models.BadgeDefinition
+	if err := db.Find(&definitions).Error; err != nil {
+		return nil, err
+	}
+
+	// Award new badges
+	var newBadges []models.Badge
+	for _, def := range definitions {
+		var count int64
+		switch def.Type {
+		case models.BadgeReviews:
+			count = counts.Reviews
+		case models.BadgeVisits:
+			count = counts.Visits
+		case models.BadgeSpots:
+			count = counts.Spots
+		case models.BadgeFriends:
+			count = counts.Friends
+		case models.BadgeLikes:
+			count = counts.Likes
+		case models.BadgeDiscoveries:
+			count = counts.Discoveries
+		}
+
+		if count >= int64(def.Threshold) {
+			// Check if user already has this badge
+			var existing models.Badge
+			if db.Where("badge_def_id = ? AND user_id = ?", def.ID, userUUID).First(&existing).Error != nil {
+				// Award badge if not exists
+				badge := models.Badge{
+					UserID:     userUUID,
+					Name:       def.Name,
+					ImagePath:  def.ImagePath,
+					BadgeDefID: def.ID,
+				}
+				if err := db.Create(&badge).Error; err == nil {
+					newBadges = append(newBadges, badge)
+				}
+			}
+		}
+	}
+	return newBadges, nil
+}
+
 func CheckBadgesHandler(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := r.Context().Value("user_id").(string)
@@ -38,58 +145,10 @@ func CheckBadgesHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		// Get user counts
-		var counts struct {
-			Reviews int64
-			Visits  int64
-			Spots   int64
-			Friends int64
-			Likes   int64
-		}
-
-		db.Model(&models.Review{}).Where("user_id = ?", userUUID).Count(&counts.Reviews)
-		db.Model(&models.VisitedSpot{}).Where("user_id = ?", userUUID).Count(&counts.Visits)
-		db.Model(&models.Spot{}).Where("user_id = ?", userUUID).Count(&counts.Spots)
-		db.Table("user_friends").Where("user_id = ?", userUUID).Count(&counts.Friends)
-		db.Model(&models.Like{}).Where("user_id = ?", userUUID).Count(&counts.Likes)
-
-		// Get all badge definitions
-		var definitions []models.BadgeDefinition
-		db.Find(&definitions)
-
-		// Award new badges
-		var newBadges []models.Badge
-		for _, def := range definitions {
-			var count int64
-			switch def.Type {
-			case models.BadgeReviews:
-				count = counts.Reviews
-			case models.BadgeVisits:
-				count = counts.Visits
-			case models.BadgeSpots:
-				count = counts.Spots
-			case models.BadgeFriends:
-				count = counts.Friends
-			case models.BadgeLikes:
-				count = counts.Likes
-			}
-
-			if count >= int64(def.Threshold) {
-				// Check if user already has this badge
-				var existing models.Badge
-				if db.Where("badge_def_id = ? AND user_id = ?", def.ID, userUUID).First(&existing).Error != nil {
-					// Award badge if not exists
-					badge := models.Badge{
-						UserID:     userUUID,
-						Name:       def.Name,
-						ImagePath:  def.ImagePath,
-						BadgeDefID: def.ID,
-					}
-					if err := db.Create(&badge).Error; err == nil {
-						newBadges = append(newBadges, badge)
-					}
-				}
-			}
+		newBadges, err := awardBadges(db, userUUID)
+		if err != nil {
+			http.Error(w, "Failed to check badges", http.StatusInternalServerError)
+			return
 		}
 
 		// Get all user badges
@@ -143,15 +202,34 @@ func GetUserBadgesHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		page, err := parsePageRequest(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tx, err := applyKeyset[int64](db.Where("user_id = ?", userUUID), page, "created_at", "id", true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		var badges []models.Badge
-		if err := db.Where("user_id = ?", userUUID).Find(&badges).Error; err != nil {
+		if err := tx.Find(&badges).Error; err != nil {
 			http.Error(w, "Failed to get badges", http.StatusInternalServerError)
 			return
 		}
 
-		var response []BadgeResponse
-		for _, b := range badges {
-			response = append(response, BadgeResponse{
+		badgePage := newPage(badges, page, "created_at", func(b models.Badge) (any, uuid.UUID) {
+			return b.CreatedAt, b.ID
+		})
+
+		response := Page[BadgeResponse]{
+			Items:      []BadgeResponse{},
+			NextCursor: badgePage.NextCursor,
+		}
+		for _, b := range badgePage.Items {
+			response.Items = append(response.Items, BadgeResponse{
 				ID:        b.ID,
 				UserID:    b.UserID,
 				Name:      b.Name,
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type CategoryInput struct {
+	Slug     *string `json:"slug"`
+	Name     *string `json:"name"`
+	Icon     *string `json:"icon"`
+	Position *int    `json:"position"`
+}
+
+type CategoryWithCount struct {
+	models.Category
+	SpotCount int64 `json:"spot_count"`
+}
+
+// findCategory looks a category up by slug; an empty slug means none
+func findCategory(db *gorm.DB, slug string) (*models.Category, error) {
+	if slug == "" {
+		return nil, nil
+	}
+	var category models.Category
+	if err := db.Where("slug = ?", strings.ToLower(strings.TrimSpace(slug))).First(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// categoryFormError reports a failed findCategory on a spot form
+func categoryFormError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Unknown category: see /categories", http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Failed to fetch category", http.StatusInternalServerError)
+}
+
+// GetCategoriesHandler lists the categories in display order with the number
+// of public spots in each
+func GetCategoriesHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		categories := []CategoryWithCount{}
+		err := db.Model(&models.Category{}).
+			Select("categories.*, (SELECT COUNT(*) FROM spots WHERE spots.category_id = categories.id AND spots.visibility = ?) AS spot_count", models.Public).
+			Order("position ASC, name ASC").
+			Scan(&categories).Error
+		if err != nil {
+			http.Error(w, "Failed to fetch categories", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(categories)
+	}
+}
+
+// applyCategoryInput copies the set fields onto category and validates the result
+func applyCategoryInput(category *models.Category, input CategoryInput) error {
+	if input.Slug != nil {
+		category.Slug = strings.ToLower(strings.TrimSpace(*input.Slug))
+	}
+	if input.Name != nil {
+		category.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Icon != nil {
+		category.Icon = strings.TrimSpace(*input.Icon)
+	}
+	if input.Position != nil {
+		category.Position = *input.Position
+	}
+
+	switch {
+	case !models.ValidCategorySlug(category.Slug):
+		return errors.New("Invalid slug: use lowercase words joined by underscores")
+	case category.Name == "" || len(category.Name) > 100:
+		return errors.New("Name must be between 1 and 100 characters")
+	case len(category.Icon) > 100:
+		return errors.New("Icon must be at most 100 characters")
+	}
+	return nil
+}
+
+func CreateCategoryHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input CategoryInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var category models.Category
+		if err := applyCategoryInput(&category, input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var existing int64
+		db.Model(&models.Category{}).Where("slug = ?", category.Slug).Count(&existing)
+		if existing > 0 {
+			http.Error(w, "A category with this slug already exists", http.StatusConflict)
+			return
+		}
+
+		if err := db.Create(&category).Error; err != nil {
+			http.Error(w, "Failed to create category", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(category)
+	}
+}
+
+// loadCategory fetches the category named by the {id} route variable
+func loadCategory(db *gorm.DB, w http.ResponseWriter, r *http.Request, category *models.Category) bool {
+	categoryID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return false
+	}
+
+	if err := db.Where("id = ?", categoryID).First(category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Category not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
+
+// UpdateCategoryHandler changes the fields present in the body
+func UpdateCategoryHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var category models.Category
+		if !loadCategory(db, w, r, &category) {
+			return
+		}
+
+		var input CategoryInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := applyCategoryInput(&category, input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var existing int64
+		db.Model(&models.Category{}).Where("slug = ? AND id <> ?", category.Slug, category.ID).Count(&existing)
+		if existing > 0 {
+			http.Error(w, "A category with this slug already exists", http.StatusConflict)
+			return
+		}
+
+		if err := db.Save(&category).Error; err != nil {
+			http.Error(w, "Failed to update category", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(category)
+	}
+}
+
+// DeleteCategoryHandler removes a category; its spots become uncategorized
+func DeleteCategoryHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var category models.Category
+		if !loadCategory(db, w, r, &category) {
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// UpdateColumn skips the spot hooks, so no revisions are recorded
+			if err := tx.Model(&models.Spot{}).Where("category_id = ?", category.ID).
+				UpdateColumn("category_id", nil).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&category).Error
+		})
+		if err != nil {
+			http.Error(w, "Failed to delete category", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Category deleted"})
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"chillspot-backend/internal/models"
+)
+
+// Defaults of the VISIT_RADIUS, VISIT_MAX_ACCURACY and VISIT_MAX_AGE settings
+const (
+	defaultVisitRadius      = 100.0 // meters from the spot
+	defaultVisitMaxAccuracy = 100.0 // meters
+	defaultVisitMaxAge      = 300.0 // seconds between the fix and the request
+	// Device clocks may run a little ahead of the server's
+	visitClockSkew = time.Minute
+)
+
+// VisitLocationInput is the position fix a client must send with a visit
+type VisitLocationInput struct {
+	Latitude  *float64   `json:"latitude"`
+	Longitude *float64   `json:"longitude"`
+	Accuracy  *float64   `json:"accuracy"`  // meters
+	Timestamp *time.Time `json:"timestamp"` // RFC 3339, when the fix was taken
+}
+
+func (in *VisitLocationInput) validate() error {
+	if in.Latitude == nil || in.Longitude == nil || in.Accuracy == nil || in.Timestamp == nil {
+		return errors.New("latitude, longitude, accuracy and timestamp are required")
+	}
+	if *in.Latitude < -90 || *in.Latitude > 90 || *in.Longitude < -180 || *in.Longitude > 180 {
+		return errors.New("Invalid coordinates")
+	}
+	if *in.Accuracy < 0 || math.IsNaN(*in.Accuracy) {
+		return errors.New("Invalid accuracy")
+	}
+	return nil
+}
+
+// verifyVisit checks the fix against the spot and stores it on the visit
+// along with the outcome
+func verifyVisit(visit *models.VisitedSpot, spot *models.Spot, in VisitLocationInput, now time.Time) {
+	distance := calculateDistance(*in.Latitude, *in.Longitude, spot.Latitude, spot.Longitude)
+	visit.Latitude = in.Latitude
+	visit.Longitude = in.Longitude
+	visit.Accuracy = in.Accuracy
+	visit.ReportedAt = in.Timestamp
+	visit.Distance = &distance
+
+	maxAge := time.Duration(envFloat("VISIT_MAX_AGE", defaultVisitMaxAge) * float64(time.Second))
+	switch {
+	case in.Timestamp.Before(now.Add(-maxAge)) || in.Timestamp.After(now.Add(visitClockSkew)):
+		visit.Verification = models.VisitStale
+	case *in.Accuracy > envFloat("VISIT_MAX_ACCURACY", defaultVisitMaxAccuracy):
+		visit.Verification = models.VisitInaccurate
+	case distance > envFloat("VISIT_RADIUS", defaultVisitRadius):
+		visit.Verification = models.VisitTooFar
+	default:
+		visit.Verification = models.VisitVerified
+	}
+	visit.Verified = visit.Verification == models.VisitVerified
+}
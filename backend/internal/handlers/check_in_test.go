@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"chillspot-backend/internal/geo"
+	"chillspot-backend/internal/models"
+)
+
+// fix is a position fix north of the spot, taken age before now
+func fix(spot *models.Spot, north, accuracy float64, age time.Duration, now time.Time) VisitLocationInput {
+	lat, lng := geo.Offset(spot.Latitude, spot.Longitude, north, 0)
+	at := now.Add(-age)
+	return VisitLocationInput{Latitude: &lat, Longitude: &lng, Accuracy: &accuracy, Timestamp: &at}
+}
+
+func TestVerifyVisit(t *testing.T) {
+	spot := &models.Spot{Latitude: 41.9981, Longitude: 21.4254}
+	now := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		in   VisitLocationInput
+		want models.VisitVerification
+	}{
+		{"at the spot", nil, fix(spot, 0, 5, 0, now), models.VisitVerified},
+		{"inside the radius", nil, fix(spot, 99, 20, time.Minute, now), models.VisitVerified},
+		{"outside the radius", nil, fix(spot, 101, 20, time.Minute, now), models.VisitTooFar},
+		{"on the accuracy limit", nil, fix(spot, 10, 100, 0, now), models.VisitVerified},
+		{"inaccurate", nil, fix(spot, 10, 101, 0, now), models.VisitInaccurate},
+		{"old fix", nil, fix(spot, 10, 5, 301*time.Second, now), models.VisitStale},
+		{"clock a little ahead", nil, fix(spot, 10, 5, -30*time.Second, now), models.VisitVerified},
+		{"fix from the future", nil, fix(spot, 10, 5, -2*time.Minute, now), models.VisitStale},
+		// Staleness wins over the other checks
+		{"stale and far", nil, fix(spot, 5000, 500, time.Hour, now), models.VisitStale},
+		{"inaccurate and far", nil, fix(spot, 5000, 500, 0, now), models.VisitInaccurate},
+		{"wider radius", map[string]string{"VISIT_RADIUS": "250"}, fix(spot, 200, 20, 0, now), models.VisitVerified},
+		{"looser accuracy", map[string]string{"VISIT_MAX_ACCURACY": "500"}, fix(spot, 10, 400, 0, now), models.VisitVerified},
+		{"shorter max age", map[string]string{"VISIT_MAX_AGE": "30"}, fix(spot, 10, 5, time.Minute, now), models.VisitStale},
+		{"invalid setting ignored", map[string]string{"VISIT_RADIUS": "far"}, fix(spot, 150, 5, 0, now), models.VisitTooFar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"VISIT_RADIUS", "VISIT_MAX_ACCURACY", "VISIT_MAX_AGE"} {
+				t.Setenv(name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			var visit models.VisitedSpot
+			verifyVisit(&visit, spot, tt.in, now)
+			if visit.Verification != tt.want {
+				t.Errorf("verification = %s, want %s", visit.Verification, tt.want)
+			}
+			if visit.Verified != (tt.want == models.VisitVerified) {
+				t.Errorf("verified = %v for %s", visit.Verified, visit.Verification)
+			}
+
+			// The fix is kept whatever the outcome
+			if visit.Latitude != tt.in.Latitude || visit.Accuracy != tt.in.Accuracy || visit.ReportedAt != tt.in.Timestamp {
+				t.Error("fix not stored on the visit")
+			}
+			want := geo.Distance(*tt.in.Latitude, *tt.in.Longitude, spot.Latitude, spot.Longitude)
+			if visit.Distance == nil || math.Abs(*visit.Distance-want) > 1e-6 {
+				t.Errorf("distance = %v, want %v", visit.Distance, want)
+			}
+		})
+	}
+}
+
+func TestVisitLocationInputValidate(t *testing.T) {
+	spot := &models.Spot{Latitude: 41.9981, Longitude: 21.4254}
+	now := time.Now()
+	with := func(edit func(*VisitLocationInput)) VisitLocationInput {
+		in := fix(spot, 0, 5, 0, now)
+		edit(&in)
+		return in
+	}
+	float := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		in      VisitLocationInput
+		wantErr string
+	}{
+		{"complete", with(func(*VisitLocationInput) {}), ""},
+		{"zero accuracy", with(func(in *VisitLocationInput) { in.Accuracy = float(0) }), ""},
+		{"no latitude", with(func(in *VisitLocationInput) { in.Latitude = nil }), "latitude, longitude, accuracy and timestamp are required"},
+		{"no accuracy", with(func(in *VisitLocationInput) { in.Accuracy = nil }), "latitude, longitude, accuracy and timestamp are required"},
+		{"no timestamp", with(func(in *VisitLocationInput) { in.Timestamp = nil }), "latitude, longitude, accuracy and timestamp are required"},
+		{"latitude out of range", with(func(in *VisitLocationInput) { in.Latitude = float(90.5) }), "Invalid coordinates"},
+		{"longitude out of range", with(func(in *VisitLocationInput) { in.Longitude = float(-181) }), "Invalid coordinates"},
+		{"negative accuracy", with(func(in *VisitLocationInput) { in.Accuracy = float(-1) }), "Invalid accuracy"},
+		{"NaN accuracy", with(func(in *VisitLocationInput) { in.Accuracy = float(math.NaN()) }), "Invalid accuracy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.in.validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("err = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
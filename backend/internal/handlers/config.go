@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// envFloat reads a numeric setting from the environment, falling back to def
+// when it is unset or invalid
+func envFloat(name string, def float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Ignoring invalid %s=%q", name, value)
+		return def
+	}
+	return parsed
+}
+
+// envInt reads a whole number setting from the environment, falling back to
+// def when it is unset or invalid
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s=%q", name, value)
+		return def
+	}
+	return parsed
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"sort"
+
+	"chillspot-backend/internal/imaging"
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// Spots further apart than this are never considered duplicates
+	duplicateRadius = 100.0 // meters
+	// pg_trgm similarity from which two titles count as the same
+	duplicateTitleSimilarity = 0.5
+	// Photos whose hashes differ in at most this many bits count as the same
+	duplicateHashDistance = 6
+)
+
+// Reasons a spot is reported as a probable duplicate
+const (
+	DuplicateSimilarTitle = "similar_title"
+	DuplicateSimilarPhoto = "similar_photo"
+)
+
+type DuplicateCandidate struct {
+	Spot            models.Spot `json:"spot"`
+	Distance        float64     `json:"distance"` // meters
+	TitleSimilarity float64     `json:"title_similarity"`
+	PhotoDistance   *int        `json:"photo_distance,omitempty"` // differing hash bits of the closest photo pair
+	Reasons         []string    `json:"reasons"`
+}
+
+// DuplicatePair is a probable duplicate found in existing data
+type DuplicatePair struct {
+	Spot      models.Spot
+	Duplicate DuplicateCandidate
+}
+
+// findDuplicateSpots returns the spots near (lat, lng) with a title similar to
+// title or a photo matching one of hashes, closest first. excludeID leaves a
+// spot out, so existing spots can be checked against the rest. A non-nil
+// viewerID limits the check to the listed spots the viewer sees exactly, as
+// a match would otherwise give away where a hidden, fuzzed or geocache spot is.
+func findDuplicateSpots(db *gorm.DB, lat, lng float64, title string, hashes []uint64, excludeID, viewerID *uuid.UUID) ([]DuplicateCandidate, error) {
+	inner := db.Model(&models.Spot{}).
+		Select("spots.*, "+distanceSQL+" AS distance, similarity(spots.title, ?) AS title_similarity", lat, lat, lng, title).
+		Where(geohashPrefixFilter(db, lat, lng, duplicateRadius))
+	if excludeID != nil {
+		inner = inner.Where("spots.id <> ?", *excludeID)
+	}
+	if viewerID != nil {
+		inner = inner.Where(visibleSpots(db, *viewerID, true)).
+			Where(`NOT spots.fuzz_location OR spots.user_id = ?
+				OR EXISTS (SELECT 1 FROM visited_spots WHERE visited_spots.spot_id = spots.id AND visited_spots.user_id = ? AND visited_spots.verified)`,
+				*viewerID, *viewerID).
+			Where(discoveredSQL, *viewerID, *viewerID)
+	}
+
+	var nearby []struct {
+		models.Spot
+		Distance        float64
+		TitleSimilarity float64
+	}
+	if err := db.Table("(?) AS candidates", inner).
+		Where("distance <= ?", duplicateRadius).
+		Order("distance ASC").
+		Scan(&nearby).Error; err != nil {
+		return nil, err
+	}
+	if len(nearby) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(nearby))
+	for i, spot := range nearby {
+		ids[i] = spot.ID
+	}
+
+	// Closest hash distance between any of the new photos and each spot's gallery
+	photoDistances := make(map[uuid.UUID]int)
+	if len(hashes) > 0 {
+		var photos []models.SpotPhoto
+		if err := db.Select("spot_id", "hash").
+			Where("spot_id IN ? AND hash IS NOT NULL", ids).
+			Find(&photos).Error; err != nil {
+			return nil, err
+		}
+		for _, photo := range photos {
+			for _, hash := range hashes {
+				distance := imaging.HashDistance(uint64(*photo.Hash), hash)
+				if best, ok := photoDistances[photo.SpotID]; !ok || distance < best {
+					photoDistances[photo.SpotID] = distance
+				}
+			}
+		}
+	}
+
+	var candidates []DuplicateCandidate
+	for _, spot := range nearby {
+		candidate := DuplicateCandidate{
+			Spot:            spot.Spot,
+			Distance:        spot.Distance,
+			TitleSimilarity: spot.TitleSimilarity,
+		}
+		if spot.TitleSimilarity >= duplicateTitleSimilarity {
+			candidate.Reasons = append(candidate.Reasons, DuplicateSimilarTitle)
+		}
+		if distance, ok := photoDistances[spot.ID]; ok {
+			candidate.PhotoDistance = &distance
+			if distance <= duplicateHashDistance {
+				candidate.Reasons = append(candidate.Reasons, DuplicateSimilarPhoto)
+			}
+		}
+		if len(candidate.Reasons) == 0 {
+			continue
+		}
+
+		candidate.Spot.FillImageURLs()
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// FindDuplicatePairs checks every spot against its neighbours and reports
+// each probable duplicate pair once, the older spot first.
+func FindDuplicatePairs(db *gorm.DB) ([]DuplicatePair, error) {
+	var pairs []DuplicatePair
+	var spots []models.Spot
+	err := db.FindInBatches(&spots, 500, func(tx *gorm.DB, batch int) error {
+		for _, spot := range spots {
+			var photoHashes []int64
+			if err := db.Model(&models.SpotPhoto{}).
+				Where("spot_id = ? AND hash IS NOT NULL", spot.ID).
+				Pluck("hash", &photoHashes).Error; err != nil {
+				return err
+			}
+			hashes := make([]uint64, len(photoHashes))
+			for i, hash := range photoHashes {
+				hashes[i] = uint64(hash)
+			}
+
+			candidates, err := findDuplicateSpots(db, spot.Latitude, spot.Longitude, spot.Title, hashes, &spot.ID, nil)
+			if err != nil {
+				return err
+			}
+			for _, candidate := range candidates {
+				// The pair is reported when visiting the older spot
+				if candidate.Spot.CreatedAt.Before(spot.CreatedAt) ||
+					(candidate.Spot.CreatedAt.Equal(spot.CreatedAt) && candidate.Spot.ID.String() < spot.ID.String()) {
+					continue
+				}
+				pairs = append(pairs, DuplicatePair{Spot: spot, Duplicate: candidate})
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Duplicate.Distance < pairs[j].Duplicate.Distance
+	})
+	return pairs, nil
+}
@@ -170,11 +170,26 @@ func GetFriendRequestsHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		requests, err := currentUser.GetPendingFriendRequests(db)
+		page, err := parsePageRequest(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tx, err := applyKeyset[int64](db, page, "created_at", "id", true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requests, err := currentUser.GetPendingFriendRequests(tx)
 		if err != nil {
 			http.Error(w, "Failed to get friend requests", http.StatusInternalServerError)
 			return
 		}
+		requestPage := newPage(requests, page, "created_at", func(req models.FriendRequest) (any, uuid.UUID) {
+			return req.CreatedAt, req.ID
+		})
 
 		// Create a custom response with necessary user details
 		type FriendRequestResponse struct {
@@ -187,8 +202,11 @@ func GetFriendRequestsHandler(db *gorm.DB) http.HandlerFunc {
 			CreatedAt int64 `json:"created_at"`
 		}
 
-		var response []FriendRequestResponse
-		for _, req := range requests {
+		response := Page[FriendRequestResponse]{
+			Items:      []FriendRequestResponse{},
+			NextCursor: requestPage.NextCursor,
+		}
+		for _, req := range requestPage.Items {
 			res := FriendRequestResponse{
 				ID:        req.ID,
 				CreatedAt: req.CreatedAt,
@@ -196,7 +214,7 @@ func GetFriendRequestsHandler(db *gorm.DB) http.HandlerFunc {
 			res.Sender.ID = req.Sender.ID
 			res.Sender.Username = req.Sender.Username
 			res.Sender.ProfilePic = req.Sender.ProfilePic
-			response = append(response, res)
+			response.Items = append(response.Items, res)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -315,13 +333,34 @@ func GetFriendsHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		// Get user with friends
-		var user models.User
-		if err := db.Preload("Friends").First(&user, userUUID).Error; err != nil {
-			http.Error(w, "User not found", http.StatusNotFound)
+		page, err := parsePageRequest(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
+		// Get user's friends, alphabetically
+		query := db.Model(&models.User{}).
+			Select("users.*").
+			Joins("JOIN user_friends ON user_friends.friend_id = users.id").
+			Where("user_friends.user_id = ?", userUUID)
+
+		tx, err := applyKeyset[string](query, page, "users.username", "users.id", false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var friends []models.User
+		if err := tx.Find(&friends).Error; err != nil {
+			http.Error(w, "Failed to get friends", http.StatusInternalServerError)
+			return
+		}
+
+		friendPage := newPage(friends, page, "users.username", func(friend models.User) (any, uuid.UUID) {
+			return friend.Username, friend.ID
+		})
+
 		// Create response with XP
 		type FriendResponse struct {
 			ID         string  `json:"id"`
@@ -330,9 +369,12 @@ func GetFriendsHandler(db *gorm.DB) http.HandlerFunc {
 			XP         int     `json:"xp"` // Add your XP field here
 		}
 
-		var friendsResponse []FriendResponse
-		for _, friend := range user.Friends {
-			friendsResponse = append(friendsResponse, FriendResponse{
+		friendsResponse := Page[FriendResponse]{
+			Items:      []FriendResponse{},
+			NextCursor: friendPage.NextCursor,
+		}
+		for _, friend := range friendPage.Items {
+			friendsResponse.Items = append(friendsResponse.Items, FriendResponse{
 				ID:         friend.ID.String(),
 				Username:   friend.Username,
 				ProfilePic: friend.ProfilePic,
@@ -374,13 +416,20 @@ func GetFriendsSpotsHandler(db *gorm.DB) http.HandlerFunc {
 		}
 
 		// Get spots for friends
-		var spots []models.Spot
-		if err := db.Where("user_id IN (?)", friendIDs).Find(&spots).Error; err != nil {
+		q, err := ParseSpotQuery(r, userUUID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.OwnerIn = &friendIDs
+
+		response, err := q.Run(db)
+		if err != nil {
 			http.Error(w, "Failed to fetch spots", http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(spots)
+		json.NewEncoder(w).Encode(response)
 	}
 }
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// XP for revealing a geocache spot, on top of the visit
+const discoveryBonusXP = 50
+
+// discoveredSQL matches the spots the viewer ((?, ?) = (viewerID, viewerID))
+// isn't kept from: everything but other users' undiscovered geocache spots
+const discoveredSQL = `(NOT spots.geocache OR spots.user_id = ?
+	OR EXISTS (SELECT 1 FROM spot_discoveries WHERE spot_discoveries.spot_id = spots.id AND spot_discoveries.user_id = ?))`
+
+func hasDiscovered(db *gorm.DB, userID, spotID uuid.UUID) bool {
+	var discoveries int64
+	db.Model(&models.SpotDiscovery{}).Where("spot_id = ? AND user_id = ?", spotID, userID).Count(&discoveries)
+	return discoveries > 0
+}
+
+// isConcealed reports whether the spot is a geocache the viewer has yet to find
+func isConcealed(db *gorm.DB, viewerID uuid.UUID, spot *models.Spot) bool {
+	return spot.Geocache && spot.UserID != viewerID && !hasDiscovered(db, viewerID, spot.ID)
+}
+
+// requireDiscovered rejects requests for the details of a concealed spot
+func requireDiscovered(db *gorm.DB, w http.ResponseWriter, viewerID uuid.UUID, spot *models.Spot) bool {
+	if isConcealed(db, viewerID, spot) {
+		http.Error(w, "Discover this spot first", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// parseGeocacheForm sets the geocache, hint_radius and clue fields present in
+// a spot form
+func parseGeocacheForm(form url.Values, spot *models.Spot) error {
+	if values, ok := form["geocache"]; ok {
+		geocache, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return errors.New("Invalid geocache")
+		}
+		spot.Geocache = geocache
+	}
+	if values, ok := form["hint_radius"]; ok {
+		radius, err := strconv.ParseUint(values[0], 10, 32)
+		if err != nil || radius < models.MinHintRadius || radius > models.MaxHintRadius {
+			return errors.New("hint_radius must be between " + strconv.Itoa(models.MinHintRadius) +
+				" and " + strconv.Itoa(models.MaxHintRadius) + " meters")
+		}
+		spot.HintRadius = uint(radius)
+	}
+	if values, ok := form["clue"]; ok {
+		spot.Clue = nil
+		if clue := strings.TrimSpace(values[0]); clue != "" {
+			if len(clue) > models.MaxClueLength {
+				return errors.New("clue must be at most " + strconv.Itoa(models.MaxClueLength) + " characters")
+			}
+			spot.Clue = &clue
+		}
+	}
+	if spot.Geocache && spot.Clue == nil {
+		return errors.New("A geocache spot needs a clue")
+	}
+	return nil
+}
+
+// discoverSpots reveals the geocache spots within proximityThreshold of
+// (lat, lng) the user hasn't found yet, recording each discovery and its
+// bonus XP
+func discoverSpots(db *gorm.DB, userID uuid.UUID, lat, lng float64) ([]models.SpotDiscovery, error) {
+	var spots []models.Spot
+	if err := db.Where("spots.geocache AND spots.user_id <> ?", userID).
+		Where(visibleSpots(db, userID, true)).
+		Where("NOT EXISTS (SELECT 1 FROM spot_discoveries WHERE spot_discoveries.spot_id = spots.id AND spot_discoveries.user_id = ?)", userID).
+		Where(geohashPrefixFilter(db, lat, lng, proximityThreshold)).
+		Find(&spots).Error; err != nil {
+		return nil, err
+	}
+
+	discoveries := []models.SpotDiscovery{}
+	for _, spot := range spots {
+		if calculateDistance(lat, lng, spot.Latitude, spot.Longitude) > proximityThreshold {
+			continue
+		}
+
+		discovery := models.SpotDiscovery{
+			UserID:       userID,
+			SpotID:       spot.ID,
+			XPGained:     discoveryBonusXP,
+			DiscoveredAt: time.Now(),
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// A concurrent check may have recorded it already
+			created := tx.Omit("Spot").Clauses(clause.OnConflict{DoNothing: true}).Create(&discovery)
+			if created.Error != nil || created.RowsAffected == 0 {
+				return created.Error
+			}
+			if err := tx.Model(&models.User{}).
+				Where("id = ?", userID).
+				Update("xp", gorm.Expr("xp + ?", discoveryBonusXP)).Error; err != nil {
+				return err
+			}
+			discovery.Spot = spot
+			discoveries = append(discoveries, discovery)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return discoveries, nil
+}
+
+// GetDiscoveriesHandler lists the geocache spots the user revealed, latest first
+func GetDiscoveriesHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		page, err := parsePageRequest(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		discoveries := db.Preload("Spot").
+			Where("user_id = ?", userUUID).
+			Where("spot_id IN (?)", db.Model(&models.Spot{}).Select("spots.id").Where(visibleSpots(db, userUUID, false)))
+
+		tx, err := applyKeyset[time.Time](discoveries, page, "discovered_at", "id", true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var found []models.SpotDiscovery
+		if err := tx.Find(&found).Error; err != nil {
+			http.Error(w, "Failed to fetch discoveries", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newPage(found, page, "discovered_at", func(d models.SpotDiscovery) (any, uuid.UUID) {
+			return d.DiscoveredAt, d.ID
+		}))
+	}
+}
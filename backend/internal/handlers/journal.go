@@ -0,0 +1,570 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chillspot-backend/internal/models"
+	"chillspot-backend/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Photos whose capture time is further than this from the visit weren't
+// taken during it
+const visitPhotoWindow = 12 * time.Hour
+
+// journalVerifications are the visits kept in the journal: verified ones and
+// those from before check-ins were verified. Failed check-ins are not.
+var journalVerifications = []models.VisitVerification{models.VisitVerified, models.VisitUnverified}
+
+type UpdateJournalInput struct {
+	Notes        *string      `json:"notes"`
+	Weather      *string      `json:"weather"`    // "" clears it
+	Crowd        *string      `json:"crowd"`      // "" clears it
+	Visibility   *string      `json:"visibility"` // public, friends or private
+	CompanionIDs *[]uuid.UUID `json:"companion_ids"`
+}
+
+// visibleJournal is the condition on visited_spots matching the journal
+// entries the viewer may read: all of their own, and other users' public
+// ones and, from friends, friends-only ones, as long as the viewer may see
+// the spot
+func visibleJournal(db *gorm.DB, viewerID uuid.UUID) *gorm.DB {
+	spots := db.Model(&models.Spot{}).
+		Select("spots.id").
+		Where(visibleSpots(db, viewerID, false)).
+		Where(discoveredSQL, viewerID, viewerID)
+	shared := db.Where("visited_spots.visibility = ?", models.Public).
+		Or("visited_spots.visibility = ? AND visited_spots.user_id IN (SELECT friend_id FROM user_friends WHERE user_id = ?)",
+			models.FriendsOnly, viewerID)
+	return db.Where("visited_spots.verification IN ?", journalVerifications).
+		Where(db.Where("visited_spots.user_id = ?", viewerID).
+			Or(db.Where(shared).Where("visited_spots.spot_id IN (?)", spots)))
+}
+
+// preloadJournal loads what a journal entry shows along with the visit
+func preloadJournal(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Spot").
+		Preload("Photos", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("created_at, id")
+		}).
+		Preload("Companions", func(tx *gorm.DB) *gorm.DB {
+			return tx.Select("visit_companions.*, users.username").
+				Joins("JOIN users ON users.id = visit_companions.user_id").
+				Order("users.username")
+		})
+}
+
+// presentJournal prepares entries for the viewer. Other users don't get the
+// position the visit was reported from, which would locate a fuzzed spot,
+// nor its anti-cheat state.
+func presentJournal(db *gorm.DB, viewerID uuid.UUID, entries ...*models.VisitedSpot) error {
+	spots := make([]*models.Spot, len(entries))
+	for i, entry := range entries {
+		spots[i] = &entry.Spot
+		if entry.UserID == viewerID {
+			continue
+		}
+		entry.Latitude, entry.Longitude, entry.Accuracy = nil, nil, nil
+		entry.ReportedAt = nil
+		entry.FlagStatus = models.FlagNone
+		entry.EscrowXP = 0
+		entry.Flags = nil
+	}
+	return maskSpots(db, viewerID, spots...)
+}
+
+// loadJournalEntry fetches journal entry {id} if the caller may read it.
+// Entries the caller can't read are reported as not found; with owned set,
+// so are other users' entries.
+func loadJournalEntry(db *gorm.DB, w http.ResponseWriter, r *http.Request, owned bool, entry *models.VisitedSpot) (uuid.UUID, bool) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	visitUUID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid visit ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	tx := preloadJournal(db).Where("visited_spots.id = ?", visitUUID)
+	if owned {
+		tx = tx.Where("visited_spots.user_id = ? AND visited_spots.verification IN ?", userUUID, journalVerifications)
+	} else {
+		tx = tx.Where(visibleJournal(db, userUUID))
+	}
+	if err := tx.First(entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Journal entry not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return uuid.Nil, false
+	}
+	return userUUID, true
+}
+
+// parseJournalInput turns the fields present in an update into column
+// updates, checking each
+func parseJournalInput(input UpdateJournalInput) (map[string]any, error) {
+	updates := map[string]any{}
+	if input.Notes != nil {
+		notes := strings.TrimSpace(*input.Notes)
+		if utf8.RuneCountInString(notes) > models.MaxJournalNotesLength {
+			return nil, errors.New("Notes are too long")
+		}
+		updates["notes"] = notes
+	}
+	if input.Weather != nil {
+		var weather models.WeatherCondition
+		if strings.TrimSpace(*input.Weather) != "" {
+			var ok bool
+			weather, ok = models.ParseWeatherCondition(*input.Weather)
+			if !ok || weather == models.AnyWeather {
+				return nil, errors.New("Invalid weather")
+			}
+		}
+		updates["weather"] = weather
+	}
+	if input.Crowd != nil {
+		crowd := models.CrowdLevel(strings.ToLower(strings.TrimSpace(*input.Crowd)))
+		if crowd != "" && !models.ValidCrowdLevel(crowd) {
+			return nil, errors.New("Invalid crowd: use empty, quiet, moderate, busy or packed")
+		}
+		updates["crowd"] = crowd
+	}
+	if input.Visibility != nil {
+		visibility := models.Visibility(strings.ToLower(strings.TrimSpace(*input.Visibility)))
+		if !models.ValidJournalVisibility(visibility) {
+			return nil, errors.New("Invalid visibility: use public, friends or private")
+		}
+		updates["visibility"] = visibility
+	}
+	return updates, nil
+}
+
+// parseCompanions dedupes the tagged users
+func parseCompanions(ownerID uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool)
+	companions := []uuid.UUID{}
+	for _, id := range ids {
+		if id == ownerID {
+			return nil, errors.New("You can't tag yourself")
+		}
+		if !seen[id] {
+			seen[id] = true
+			companions = append(companions, id)
+		}
+	}
+	if len(companions) > models.MaxVisitCompanions {
+		return nil, errors.New("Too many companions")
+	}
+	return companions, nil
+}
+
+// GetJournalEntryHandler shows a visit's journal entry
+func GetJournalEntryHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var entry models.VisitedSpot
+		viewerID, ok := loadJournalEntry(db, w, r, false, &entry)
+		if !ok {
+			return
+		}
+		if err := presentJournal(db, viewerID, &entry); err != nil {
+			http.Error(w, "Failed to fetch journal entry", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entry)
+	}
+}
+
+// UpdateJournalEntryHandler edits the owner's journal entry. Only the fields
+// sent change; companion_ids replaces the tagged friends.
+func UpdateJournalEntryHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var entry models.VisitedSpot
+		userUUID, ok := loadJournalEntry(db, w, r, true, &entry)
+		if !ok {
+			return
+		}
+
+		var input UpdateJournalInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		updates, err := parseJournalInput(input)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var companions []uuid.UUID
+		if input.CompanionIDs != nil {
+			companions, err = parseCompanions(userUUID, *input.CompanionIDs)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			// Only friends can be tagged
+			var friends int64
+			if err := db.Table("user_friends").
+				Where("user_id = ? AND friend_id IN ?", userUUID, companions).
+				Count(&friends).Error; err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			if int(friends) != len(companions) {
+				http.Error(w, "Companions must be your friends", http.StatusBadRequest)
+				return
+			}
+		}
+
+		updates["edited_at"] = time.Now()
+		err = db.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.VisitedSpot{}).Where("id = ?", entry.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+			if input.CompanionIDs == nil {
+				return nil
+			}
+			if err := tx.Where("visit_id = ?", entry.ID).Delete(&models.VisitCompanion{}).Error; err != nil {
+				return err
+			}
+			for _, id := range companions {
+				if err := tx.Omit("User").Create(&models.VisitCompanion{VisitID: entry.ID, UserID: id}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(w, "Failed to update journal entry", http.StatusInternalServerError)
+			return
+		}
+
+		if err := preloadJournal(db).Where("id = ?", entry.ID).First(&entry).Error; err != nil {
+			http.Error(w, "Failed to fetch journal entry", http.StatusInternalServerError)
+			return
+		}
+		if err := presentJournal(db, userUUID, &entry); err != nil {
+			http.Error(w, "Failed to fetch journal entry", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": "Journal entry updated",
+			"visit":   entry,
+		})
+	}
+}
+
+// DeleteJournalEntryHandler removes a visit along with its journal. A
+// verified visit stops counting toward the spot, its XP and any visit badge
+// it earned are taken back; if it was the first visit, the next one takes its
+// place and its XP is topped up to firstVisitXP. Visits awaiting an
+// anti-cheat review can't be deleted.
+func DeleteJournalEntryHandler(db *gorm.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var entry models.VisitedSpot
+		if _, ok := loadJournalEntry(db, w, r, true, &entry); !ok {
+			return
+		}
+		if entry.FlagStatus == models.FlagPending {
+			http.Error(w, "Visit is awaiting review", http.StatusConflict)
+			return
+		}
+
+		var revoked []models.Badge
+		xpRevoked := entry.XPAwarded
+		err := db.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+			// Serialises with the user's check-ins, which decide first visits
+			if err := tx.Exec("SELECT 1 FROM users WHERE id = ? FOR UPDATE", entry.UserID).Error; err != nil {
+				return err
+			}
+			for _, model := range []any{&models.VisitPhoto{}, &models.VisitCompanion{}, &models.VisitFlag{}} {
+				if err := tx.Where("visit_id = ?", entry.ID).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Delete(&models.VisitedSpot{}, "id = ?", entry.ID).Error; err != nil {
+				return err
+			}
+			// Only verified visits that weren't rejected as faked were counted
+			if entry.Verification == models.VisitVerified && entry.FlagStatus != models.FlagConfirmed {
+				if err := tx.Model(&models.Spot{}).
+					Where("id = ? AND visit_count > 0", entry.SpotID).
+					Update("visit_count", gorm.Expr("visit_count - 1")).Error; err != nil {
+					return err
+				}
+			}
+			if entry.FirstVisit {
+				topUp, err := promoteFirstVisit(tx, entry.UserID, entry.SpotID)
+				if err != nil {
+					return err
+				}
+				xpRevoked -= topUp
+			}
+			if xpRevoked != 0 {
+				if err := tx.Model(&models.User{}).
+					Where("id = ?", entry.UserID).
+					Update("xp", gorm.Expr("xp - ?", xpRevoked)).Error; err != nil {
+					return err
+				}
+			}
+			var err error
+			revoked, err = revokeVisitBadges(tx, entry.UserID)
+			return err
+		})
+		if err != nil {
+			http.Error(w, "Failed to delete journal entry", http.StatusInternalServerError)
+			return
+		}
+
+		for _, photo := range entry.Photos {
+			deleteImage(store, &photo.Filename)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":        "Journal entry deleted",
+			"xp_revoked":     xpRevoked,
+			"badges_revoked": badgeResponses(revoked),
+		})
+	}
+}
+
+// promoteFirstVisit makes the user's earliest remaining verified visit to the
+// spot their first, topping its XP, or the XP it holds in escrow, up to
+// firstVisitXP. It returns the XP paid out on top of what the visit had.
+func promoteFirstVisit(tx *gorm.DB, userID, spotID uuid.UUID) (int, error) {
+	var next models.VisitedSpot
+	err := tx.Where("user_id = ? AND spot_id = ? AND verified", userID, spotID).
+		Order("visited_at, id").
+		First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	updates := map[string]any{"first_visit": true}
+	topUp := 0
+	switch {
+	case next.FlagStatus == models.FlagPending:
+		updates["escrow_xp"] = max(next.EscrowXP, firstVisitXP)
+	case next.XPAwarded < firstVisitXP:
+		updates["xp_awarded"] = firstVisitXP
+		topUp = firstVisitXP - next.XPAwarded
+	}
+	return topUp, tx.Model(&models.VisitedSpot{}).Where("id = ?", next.ID).Updates(updates).Error
+}
+
+// UploadVisitPhotoHandler adds a photo taken during the visit to the
+// owner's journal entry
+func UploadVisitPhotoHandler(db *gorm.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var entry models.VisitedSpot
+		userUUID, ok := loadJournalEntry(db, w, r, true, &entry)
+		if !ok {
+			return
+		}
+		if len(entry.Photos) >= models.MaxVisitPhotos {
+			http.Error(w, "This visit has too many photos", http.StatusBadRequest)
+			return
+		}
+
+		if err := r.ParseMultipartForm(32 << 20); err != nil { // 32 MB max
+			http.Error(w, "Failed to parse form data", http.StatusBadRequest)
+			return
+		}
+
+		photo := models.VisitPhoto{
+			VisitID: entry.ID,
+			UserID:  userUUID,
+			Caption: r.FormValue("caption"),
+		}
+		if utf8.RuneCountInString(photo.Caption) > maxCaptionLength {
+			http.Error(w, "Caption is too long", http.StatusBadRequest)
+			return
+		}
+		if capturedAtStr := r.FormValue("captured_at"); capturedAtStr != "" {
+			capturedAt, err := time.Parse(time.RFC3339, capturedAtStr)
+			if err != nil {
+				http.Error(w, "Invalid captured_at, expected RFC 3339", http.StatusBadRequest)
+				return
+			}
+			photo.CapturedAt = &capturedAt
+		}
+
+		processed, err := readImage(r, "photo")
+		if err != nil {
+			imageUploadError(w, "photo", err)
+			return
+		}
+		if processed == nil {
+			http.Error(w, "Missing photo", http.StatusBadRequest)
+			return
+		}
+		if photo.CapturedAt == nil {
+			photo.CapturedAt = captureTime(processed.Metadata(), entry.Spot.Longitude)
+		}
+		if photo.CapturedAt != nil {
+			if gap := photo.CapturedAt.Sub(entry.VisitedAt); gap > visitPhotoWindow || gap < -visitPhotoWindow {
+				http.Error(w, "The photo wasn't taken during the visit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		photo.Filename, err = storeImage(r.Context(), store, processed)
+		if err != nil {
+			imageUploadError(w, "photo", err)
+			return
+		}
+
+		if err := db.WithContext(r.Context()).Create(&photo).Error; err != nil {
+			deleteImage(store, &photo.Filename)
+			http.Error(w, "Failed to save photo", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": "Photo added",
+			"photo":   photo,
+		})
+	}
+}
+
+// DeleteVisitPhotoHandler removes a photo from the owner's journal entry
+func DeleteVisitPhotoHandler(db *gorm.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var entry models.VisitedSpot
+		if _, ok := loadJournalEntry(db, w, r, true, &entry); !ok {
+			return
+		}
+
+		photoUUID, err := uuid.Parse(mux.Vars(r)["photoId"])
+		if err != nil {
+			http.Error(w, "Invalid photo ID", http.StatusBadRequest)
+			return
+		}
+
+		var photo models.VisitPhoto
+		if err := db.Where("id = ? AND visit_id = ?", photoUUID, entry.ID).First(&photo).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(w, "Photo not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if err := db.WithContext(r.Context()).Delete(&photo).Error; err != nil {
+			http.Error(w, "Failed to delete photo", http.StatusInternalServerError)
+			return
+		}
+		deleteImage(store, &photo.Filename)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Photo deleted"})
+	}
+}
+
+// GetJournalHandler is a user's journal as a timeline, latest visit first
+// or, with ?order=oldest, earliest first. ?user_id= picks whose journal,
+// by default the caller's; other users' show what the caller may read.
+func GetJournalHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		viewerUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		page, err := parsePageRequest(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ownerUUID := viewerUUID
+		if ownerID := query.Get("user_id"); ownerID != "" {
+			ownerUUID, err = uuid.Parse(ownerID)
+			if err != nil {
+				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var desc bool
+		switch query.Get("order") {
+		case "", "newest":
+			desc = true
+		case "oldest":
+		default:
+			http.Error(w, "Invalid order: use newest or oldest", http.StatusBadRequest)
+			return
+		}
+
+		entries := preloadJournal(db).
+			Where("visited_spots.user_id = ?", ownerUUID).
+			Where(visibleJournal(db, viewerUUID))
+
+		tx, err := applyKeyset[time.Time](entries, page, "visited_at", "id", desc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var journal []models.VisitedSpot
+		if err := tx.Find(&journal).Error; err != nil {
+			http.Error(w, "Failed to fetch journal", http.StatusInternalServerError)
+			return
+		}
+
+		presented := make([]*models.VisitedSpot, len(journal))
+		for i := range journal {
+			presented[i] = &journal[i]
+		}
+		if err := presentJournal(db, viewerUUID, presented...); err != nil {
+			http.Error(w, "Failed to fetch journal", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newPage(journal, page, "visited_at", func(v models.VisitedSpot) (any, uuid.UUID) {
+			return v.VisitedAt, v.ID
+		}))
+	}
+}
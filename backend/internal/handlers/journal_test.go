@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"slices"
+	"strings"
+	"testing"
+
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestPromoteFirstVisit(t *testing.T) {
+	next := uuid.New()
+	visit := func(xp, escrow int, status models.FlagStatus) [][]driver.Value {
+		return [][]driver.Value{{next.String(), int64(xp), int64(escrow), string(status)}}
+	}
+
+	tests := []struct {
+		name      string
+		rows      [][]driver.Value
+		wantTopUp int
+		wantSet   map[string]any // the columns updated on the next visit
+	}{
+		{"no visit left", nil, 0, nil},
+		{"repeat visit", visit(repeatVisitXP, 0, models.FlagNone), firstVisitXP - repeatVisitXP,
+			map[string]any{"first_visit": true, "xp_awarded": int64(firstVisitXP)}},
+		{"cleared repeat visit", visit(repeatVisitXP, 0, models.FlagCleared), firstVisitXP - repeatVisitXP,
+			map[string]any{"first_visit": true, "xp_awarded": int64(firstVisitXP)}},
+		// The XP is paid out, if at all, when the review clears the visit
+		{"visit in review", visit(0, repeatVisitXP, models.FlagPending), 0,
+			map[string]any{"first_visit": true, "escrow_xp": int64(firstVisitXP)}},
+		{"already earned the full amount", visit(firstVisitXP, 0, models.FlagNone), 0,
+			map[string]any{"first_visit": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubDriver{columns: []string{"id", "xp_awarded", "escrow_xp", "flag_status"}, rows: tt.rows}
+			topUp, err := promoteFirstVisit(stubDB(t, stub), uuid.New(), uuid.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if topUp != tt.wantTopUp {
+				t.Errorf("top-up = %d, want %d", topUp, tt.wantTopUp)
+			}
+
+			if tt.wantSet == nil {
+				if len(stub.queries) != 1 {
+					t.Errorf("ran %q, want only the lookup", stub.queries)
+				}
+				return
+			}
+			if len(stub.queries) != 2 {
+				t.Fatalf("ran %q, want the lookup and an update", stub.queries)
+			}
+			// GORM sets the columns in name order, the visit's ID last
+			update, args := stub.queries[1], stub.args[1]
+			columns := make([]string, 0, len(tt.wantSet))
+			for column := range tt.wantSet {
+				columns = append(columns, column)
+			}
+			slices.Sort(columns)
+			if len(args) != len(columns)+1 {
+				t.Fatalf("%s with %v, want %v", update, args, tt.wantSet)
+			}
+			for i, column := range columns {
+				if !strings.Contains(update, `"`+column+`"=$`) || args[i] != tt.wantSet[column] {
+					t.Errorf("%s with %v, want %s = %v", update, args, column, tt.wantSet[column])
+				}
+			}
+			if args[len(args)-1] != next.String() {
+				t.Errorf("updated visit %v, want %s", args[len(args)-1], next)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"chillspot-backend/internal/geo"
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// At or above this zoom level individual markers are always returned
+	clusterMaxZoom = 15
+	// Viewports with at most this many spots are never clustered
+	clusterMinSpots = 200
+	// Upper bound on markers returned for a single viewport
+	maxMapMarkers = 1000
+)
+
+type BoundingBox struct {
+	MinLng float64
+	MinLat float64
+	MaxLng float64
+	MaxLat float64
+}
+
+type SpotCluster struct {
+	Cell      string      `json:"cell"`
+	Count     int64       `json:"count"`
+	Latitude  float64     `json:"latitude"`  // centroid
+	Longitude float64     `json:"longitude"` // centroid
+	Spot      models.Spot `json:"spot"`      // representative spot
+}
+
+type MapSpotsResponse struct {
+	Zoom      int           `json:"zoom"`
+	Clustered bool          `json:"clustered"`
+	Spots     []models.Spot `json:"spots"`
+	Clusters  []SpotCluster `json:"clusters"`
+}
+
+func parseBoundingBox(value string) (BoundingBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return BoundingBox{}, errors.New("bbox must be minLng,minLat,maxLng,maxLat")
+	}
+
+	var coords [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return BoundingBox{}, errors.New("bbox contains an invalid number")
+		}
+		coords[i] = v
+	}
+
+	box := BoundingBox{MinLng: coords[0], MinLat: coords[1], MaxLng: coords[2], MaxLat: coords[3]}
+	if box.MinLat < -90 || box.MaxLat > 90 || box.MinLat > box.MaxLat {
+		return BoundingBox{}, errors.New("bbox latitudes are out of range")
+	}
+	if box.MinLng < -180 || box.MaxLng > 180 {
+		return BoundingBox{}, errors.New("bbox longitudes are out of range")
+	}
+	return box, nil
+}
+
+// bboxFilter restricts a spots query to the box, handling viewports that
+// cross the antimeridian (minLng > maxLng).
+func bboxFilter(db *gorm.DB, box BoundingBox) *gorm.DB {
+	filter := db.Where("spots.latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat)
+	if box.MinLng <= box.MaxLng {
+		return filter.Where("spots.longitude BETWEEN ? AND ?", box.MinLng, box.MaxLng)
+	}
+	return filter.Where("(spots.longitude >= ? OR spots.longitude <= ?)", box.MinLng, box.MaxLng)
+}
+
+// clusterPrecision maps a web map zoom level to the geohash length used as the
+// clustering grid, so clusters stay roughly the same size on screen.
+func clusterPrecision(zoom int) int {
+	precision := zoom/2 + 1
+	if precision < 1 {
+		return 1
+	}
+	if precision > 8 {
+		return 8
+	}
+	return precision
+}
+
+// gridCell returns the geohash of the cell in the given row and column of the
+// geohash grid, counted from the south-west corner of the map.
+func gridCell(precision int, row, col int64) string {
+	dLat, dLng := geo.CellSize(precision)
+	return geo.Encode(-90+(float64(row)+0.5)*dLat, -180+(float64(col)+0.5)*dLng, precision)
+}
+
+func GetMapSpotsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		box, err := parseBoundingBox(query.Get("bbox"))
+		if err != nil {
+			http.Error(w, "Invalid bbox: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		zoom, err := strconv.Atoi(query.Get("zoom"))
+		if err != nil || zoom < 0 || zoom > 22 {
+			http.Error(w, "Invalid zoom", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		// The listing filters (weather, altitude, visited...) apply to the map too
+		q, err := ParseSpotQuery(r, userUUID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		inView := func() *gorm.DB {
+			return q.filters(db, true).Where(viewerBBoxFilter(db, userUUID, box))
+		}
+
+		var total int64
+		if err := inView().Count(&total).Error; err != nil {
+			http.Error(w, "Failed to count spots", http.StatusInternalServerError)
+			return
+		}
+
+		response := MapSpotsResponse{
+			Zoom:     zoom,
+			Spots:    []models.Spot{},
+			Clusters: []SpotCluster{},
+		}
+
+		if zoom >= clusterMaxZoom || total <= clusterMinSpots {
+			if err := inView().
+				Order("favorites_count DESC, id ASC").
+				Limit(maxMapMarkers).
+				Find(&response.Spots).Error; err != nil {
+				http.Error(w, "Failed to fetch spots", http.StatusInternalServerError)
+				return
+			}
+
+			markers := make([]*models.Spot, len(response.Spots))
+			for i := range response.Spots {
+				markers[i] = &response.Spots[i]
+			}
+			if err := maskSpots(db, userUUID, markers...); err != nil {
+				http.Error(w, "Failed to fetch spots", http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		response.Clustered = true
+		precision := clusterPrecision(zoom)
+
+		type clusterRow struct {
+			CellRow, CellCol int64
+			Count            int64
+			Latitude         float64
+			Longitude        float64
+			RepresentativeID uuid.UUID
+		}
+
+		// Spots are grouped on the geohash grid by the position the viewer
+		// sees, so a fuzzed or concealed spot's cell gives nothing away. The
+		// most liked spot stands in for the whole cluster.
+		dLat, dLng := geo.CellSize(precision)
+		clusterArgs := append(viewerArgs(userUUID), dLat, int64(180/dLat)-1)
+		clusterArgs = append(clusterArgs, viewerArgs(userUUID)...)
+		clusterArgs = append(clusterArgs, dLng, int64(360/dLng)-1)
+		clusterArgs = append(clusterArgs, viewerArgs(userUUID)...)
+		clusterArgs = append(clusterArgs, viewerArgs(userUUID)...)
+		var rows []clusterRow
+		if err := inView().
+			Select(`LEAST(FLOOR((`+coordinateSQL("latitude")+` + 90) / ?), ?)::bigint AS row,
+				LEAST(FLOOR((`+coordinateSQL("longitude")+` + 180) / ?), ?)::bigint AS col,
+				COUNT(*) AS count,
+				AVG(`+coordinateSQL("latitude")+`) AS latitude,
+				AVG(`+coordinateSQL("longitude")+`) AS longitude,
+				(ARRAY_AGG(id ORDER BY favorites_count DESC, created_at ASC))[1] AS representative_id`,
+				clusterArgs...).
+			Group("cell_row, cell_col").
+			Scan(&rows).Error; err != nil {
+			http.Error(w, "Failed to cluster spots", http.StatusInternalServerError)
+			return
+		}
+
+		representativeIDs := make([]uuid.UUID, len(rows))
+		for i, row := range rows {
+			representativeIDs[i] = row.RepresentativeID
+		}
+
+		var representatives []models.Spot
+		if len(representativeIDs) > 0 {
+			if err := db.Where("id IN ?", representativeIDs).Find(&representatives).Error; err != nil {
+				http.Error(w, "Failed to fetch spots", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		shown := make([]*models.Spot, len(representatives))
+		for i := range representatives {
+			shown[i] = &representatives[i]
+		}
+		if err := maskSpots(db, userUUID, shown...); err != nil {
+			http.Error(w, "Failed to fetch spots", http.StatusInternalServerError)
+			return
+		}
+
+		spotsByID := make(map[uuid.UUID]models.Spot, len(representatives))
+		for _, spot := range representatives {
+			spotsByID[spot.ID] = spot
+		}
+
+		for _, row := range rows {
+			spot := spotsByID[row.RepresentativeID]
+
+			// A cluster of one is just a marker
+			if row.Count == 1 {
+				response.Spots = append(response.Spots, spot)
+				continue
+			}
+
+			response.Clusters = append(response.Clusters, SpotCluster{
+				Cell:      gridCell(precision, row.CellRow, row.CellCol),
+				Count:     row.Count,
+				Latitude:  row.Latitude,
+				Longitude: row.Longitude,
+				Spot:      spot,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
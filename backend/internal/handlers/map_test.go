@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+
+	"chillspot-backend/internal/geo"
+)
+
+func TestGridCell(t *testing.T) {
+	points := []struct {
+		name     string
+		lat, lng float64
+	}{
+		{"Skopje", 41.9981, 21.4254},
+		{"Sydney", -33.8688, 151.2093},
+		{"south-west corner", -90, -180},
+		{"on the equator and meridian", 0, 0},
+		// The grid's last row and column are closed, like the geohash's
+		{"north-east corner", 90, 180},
+	}
+	for _, p := range points {
+		t.Run(p.name, func(t *testing.T) {
+			for precision := 1; precision <= 8; precision++ {
+				// Row and column as the clustering query computes them
+				dLat, dLng := geo.CellSize(precision)
+				row := min(int64(math.Floor((p.lat+90)/dLat)), int64(180/dLat)-1)
+				col := min(int64(math.Floor((p.lng+180)/dLng)), int64(360/dLng)-1)
+
+				if got, want := gridCell(precision, row, col), geo.Encode(p.lat, p.lng, precision); got != want {
+					t.Errorf("precision %d: cell %s, want %s", precision, got, want)
+				}
+			}
+		})
+	}
+}
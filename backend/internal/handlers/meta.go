@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"chillspot-backend/internal/models"
+)
+
+// GetWeatherConditionsHandler lists the weather conditions spots can recommend
+func GetWeatherConditionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(models.WeatherConditionInfos)
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetNearbySpotsHandler lists spots of every user around lat/lng, nearest
+// first unless another sort is requested.
+func GetNearbySpotsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		q, err := ParseSpotQuery(r, userUUID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !q.HasLocation {
+			http.Error(w, "Invalid latitude", http.StatusBadRequest)
+			return
+		}
+
+		// The geohash prefix filter hits the index; the exact distance is only
+		// computed for the few candidate rows it returns.
+		response, err := q.Run(db)
+		if err != nil {
+			http.Error(w, "Failed to fetch nearby spots", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+var errInvalidCursor = errors.New("Invalid cursor")
+
+// Page is the envelope every list endpoint returns. NextCursor is nil on the
+// last page; otherwise it is passed back as ?cursor= to get the next one.
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// pageCursor points just past the last row of a page. Pages are keyset based
+// on (sort key, id), so rows inserted meanwhile never shift or repeat rows.
+type pageCursor struct {
+	Column string          `json:"c"` // sort column the cursor belongs to
+	Key    json.RawMessage `json:"k"`
+	ID     uuid.UUID       `json:"id"`
+}
+
+type PageRequest struct {
+	Limit  int
+	Cursor *pageCursor
+}
+
+// parsePageRequest reads the limit and cursor query params
+func parsePageRequest(query url.Values) (PageRequest, error) {
+	page := PageRequest{Limit: defaultPageLimit}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return page, errors.New("Invalid limit")
+		}
+		page.Limit = min(limit, maxPageLimit)
+	}
+
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		data, err := base64.RawURLEncoding.DecodeString(cursorStr)
+		if err != nil {
+			return page, errInvalidCursor
+		}
+		var cursor pageCursor
+		if err := json.Unmarshal(data, &cursor); err != nil {
+			return page, errInvalidCursor
+		}
+		page.Cursor = &cursor
+	}
+
+	return page, nil
+}
+
+// applyKeyset orders the query by keyColumn then idColumn, resumes after the
+// cursor and fetches one row more than the limit so newPage can tell whether
+// another page follows. K is the Go type of the sort key.
+func applyKeyset[K any](db *gorm.DB, page PageRequest, keyColumn, idColumn string, desc bool) (*gorm.DB, error) {
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if page.Cursor != nil {
+		if page.Cursor.Column != keyColumn {
+			return nil, errInvalidCursor
+		}
+		var key K
+		if err := json.Unmarshal(page.Cursor.Key, &key); err != nil {
+			return nil, errInvalidCursor
+		}
+		db = db.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", keyColumn, idColumn, comparison), key, page.Cursor.ID)
+	}
+
+	return db.
+		Order(fmt.Sprintf("%s %s, %s %s", keyColumn, direction, idColumn, direction)).
+		Limit(page.Limit + 1), nil
+}
+
+// newPage trims the extra row fetched by applyKeyset and builds the cursor to
+// the next page from the last row kept. cursorOf returns a row's sort key and id.
+func newPage[T any](items []T, page PageRequest, keyColumn string, cursorOf func(T) (any, uuid.UUID)) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	if len(items) <= page.Limit {
+		return Page[T]{Items: items}
+	}
+
+	items = items[:page.Limit]
+	key, id := cursorOf(items[len(items)-1])
+
+	rawKey, _ := json.Marshal(key)
+	data, _ := json.Marshal(pageCursor{Column: keyColumn, Key: rawKey, ID: id})
+	next := base64.RawURLEncoding.EncodeToString(data)
+
+	return Page[T]{Items: items, NextCursor: &next}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB builds Postgres SQL without running it
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost sslmode=disable"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestParsePageRequest(t *testing.T) {
+	encoded := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name      string
+		query     string
+		wantLimit int
+		wantErr   string
+	}{
+		{"defaults", "", defaultPageLimit, ""},
+		{"limit", "limit=5", 5, ""},
+		{"limit capped", "limit=1000", maxPageLimit, ""},
+		{"zero limit", "limit=0", 0, "Invalid limit"},
+		{"negative limit", "limit=-3", 0, "Invalid limit"},
+		{"text limit", "limit=ten", 0, "Invalid limit"},
+		{"cursor not base64", "cursor=not*base64", 0, "Invalid cursor"},
+		{"cursor not json", "cursor=" + encoded("nope"), 0, "Invalid cursor"},
+		{"padded cursor", "cursor=" + base64.URLEncoding.EncodeToString([]byte(`{"c":"id"}`)), 0, "Invalid cursor"},
+		{"cursor", "cursor=" + encoded(`{"c":"created_at","k":3,"id":"00000000-0000-0000-0000-000000000001"}`), defaultPageLimit, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			page, err := parsePageRequest(query)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Limit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", page.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	cursorOf := func(id uuid.UUID) (any, uuid.UUID) { return 7, id }
+
+	tests := []struct {
+		name      string
+		items     []uuid.UUID
+		limit     int
+		wantItems int
+		wantNext  bool
+	}{
+		{"nil items", nil, 2, 0, false},
+		{"short page", ids[:1], 2, 1, false},
+		{"exactly full", ids[:2], 2, 2, false},
+		// applyKeyset fetches one extra row when another page follows
+		{"more to come", ids, 2, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := newPage(tt.items, PageRequest{Limit: tt.limit}, "position", cursorOf)
+			if page.Items == nil || len(page.Items) != tt.wantItems {
+				t.Errorf("items = %v, want %d", page.Items, tt.wantItems)
+			}
+			if (page.NextCursor != nil) != tt.wantNext {
+				t.Errorf("next cursor = %v, want one: %v", page.NextCursor, tt.wantNext)
+			}
+		})
+	}
+}
+
+// A cursor from newPage resumes the next query right after the last row
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+	at := time.Date(2025, 3, 14, 15, 9, 26, 535000000, time.UTC)
+
+	tests := []struct {
+		name   string
+		key    any
+		apply  func(*gorm.DB, PageRequest) (*gorm.DB, error)
+		want   string
+		wantTo any
+	}{
+		{"time descending", at, func(db *gorm.DB, page PageRequest) (*gorm.DB, error) {
+			return applyKeyset[time.Time](db, page, "created_at", "id", true)
+		}, "(created_at, id) < ($1, $2) ORDER BY created_at DESC, id DESC LIMIT $3", at},
+		{"int ascending", 42, func(db *gorm.DB, page PageRequest) (*gorm.DB, error) {
+			return applyKeyset[int](db, page, "created_at", "id", false)
+		}, "(created_at, id) > ($1, $2) ORDER BY created_at ASC, id ASC LIMIT $3", 42},
+		{"string key", "Lake", func(db *gorm.DB, page PageRequest) (*gorm.DB, error) {
+			return applyKeyset[string](db, page, "created_at", "id", false)
+		}, "(created_at, id) > ($1, $2)", "Lake"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := newPage([]uuid.UUID{uuid.New(), id}, PageRequest{Limit: 1}, "created_at", func(uuid.UUID) (any, uuid.UUID) {
+				return tt.key, id
+			})
+			if first.NextCursor == nil {
+				t.Fatal("no next cursor")
+			}
+
+			page, err := parsePageRequest(url.Values{"cursor": {*first.NextCursor}, "limit": {"1"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx, err := tt.apply(dryRunDB(t).Model(&models.Spot{}), page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stmt := tx.Find(&[]models.Spot{}).Statement
+
+			if sql := stmt.SQL.String(); !strings.Contains(sql, tt.want) {
+				t.Errorf("SQL = %s, want it to contain %s", sql, tt.want)
+			}
+			if len(stmt.Vars) != 3 || stmt.Vars[0] != tt.wantTo || stmt.Vars[1] != id || stmt.Vars[2] != 2 {
+				t.Errorf("vars = %v, want [%v %v 2]", stmt.Vars, tt.wantTo, id)
+			}
+		})
+	}
+}
+
+func TestApplyKeysetRejectsForeignCursor(t *testing.T) {
+	page := newPage([]int{1, 2}, PageRequest{Limit: 1}, "created_at", func(int) (any, uuid.UUID) {
+		return "not a time", uuid.Nil
+	})
+	request, err := parsePageRequest(url.Values{"cursor": {*page.NextCursor}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		apply func(*gorm.DB) (*gorm.DB, error)
+	}{
+		{"other sort column", func(db *gorm.DB) (*gorm.DB, error) {
+			return applyKeyset[string](db, request, "position", "id", false)
+		}},
+		{"wrong key type", func(db *gorm.DB) (*gorm.DB, error) {
+			return applyKeyset[time.Time](db, request, "created_at", "id", true)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.apply(dryRunDB(t)); !errors.Is(err, errInvalidCursor) {
+				t.Errorf("err = %v, want %v", err, errInvalidCursor)
+			}
+		})
+	}
+}
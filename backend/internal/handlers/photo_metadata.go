@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"chillspot-backend/internal/astro"
+	"chillspot-backend/internal/imaging"
+)
+
+// Default for EXIF_MAX_LOCATION_MISMATCH, in meters
+const defaultMaxLocationMismatch = 500.0
+
+// captureTime returns when a photo taken at longitude lng was shot according
+// to its EXIF. A camera clock without a zone is assumed to be on local solar
+// time, which is within an hour or so of the real zone almost everywhere.
+func captureTime(meta imaging.Metadata, lng float64) *time.Time {
+	if meta.CapturedAt == nil {
+		return nil
+	}
+	captured := *meta.CapturedAt
+	if meta.CapturedAtLocal {
+		captured = captured.Add(-time.Duration(lng / 15 * float64(time.Hour)))
+	}
+	return &captured
+}
+
+// photoLocationMismatch compares the EXIF position of a photo with the
+// submitted coordinates. It returns a warning when they are further apart
+// than EXIF_MAX_LOCATION_MISMATCH meters.
+func photoLocationMismatch(fieldName string, meta imaging.Metadata, lat, lng float64) (string, bool) {
+	if meta.Latitude == nil || meta.Longitude == nil {
+		return "", false
+	}
+
+	distance := calculateDistance(lat, lng, *meta.Latitude, *meta.Longitude)
+	if distance <= envFloat("EXIF_MAX_LOCATION_MISMATCH", defaultMaxLocationMismatch) {
+		return "", false
+	}
+	return fmt.Sprintf("The %s was taken %.0f m from the spot's coordinates", fieldName, distance), true
+}
+
+// takenInDark reports whether the photo was shot while the sun was down at
+// (lat, lng). ok is false when the photo has no capture time.
+func takenInDark(meta imaging.Metadata, lat, lng float64) (dark, ok bool) {
+	captured := captureTime(meta, lng)
+	if captured == nil {
+		return false, false
+	}
+	return astro.IsDark(*captured, lat, lng), true
+}
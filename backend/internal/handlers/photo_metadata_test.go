@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"chillspot-backend/internal/geo"
+	"chillspot-backend/internal/imaging"
+)
+
+func TestCaptureTime(t *testing.T) {
+	shot := time.Date(2025, 6, 1, 18, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		meta imaging.Metadata
+		lng  float64
+		want *time.Time
+	}{
+		{"no capture time", imaging.Metadata{}, 21.4254, nil},
+		{"exact time", imaging.Metadata{CapturedAt: &shot}, 21.4254, &shot},
+		// Local solar time runs an hour ahead per 15° east
+		{"local time east", imaging.Metadata{CapturedAt: &shot, CapturedAtLocal: true}, 30, ptr(shot.Add(-2 * time.Hour))},
+		{"local time west", imaging.Metadata{CapturedAt: &shot, CapturedAtLocal: true}, -75, ptr(shot.Add(5 * time.Hour))},
+		{"local time off the hour", imaging.Metadata{CapturedAt: &shot, CapturedAtLocal: true}, 7.5, ptr(shot.Add(-30 * time.Minute))},
+		{"local time at Greenwich", imaging.Metadata{CapturedAt: &shot, CapturedAtLocal: true}, 0, &shot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureTime(tt.meta, tt.lng)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil || !got.Equal(*tt.want):
+				t.Errorf("captureTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The metadata is left as it was
+	captured := time.Date(2025, 6, 1, 18, 30, 0, 0, time.UTC)
+	captureTime(imaging.Metadata{CapturedAt: &captured, CapturedAtLocal: true}, 90)
+	if !captured.Equal(shot) {
+		t.Errorf("capture time changed to %s", captured)
+	}
+}
+
+func TestPhotoLocationMismatch(t *testing.T) {
+	const lat, lng = 41.9981, 21.4254
+	at := func(north float64) imaging.Metadata {
+		photoLat, photoLng := geo.Offset(lat, lng, north, 0)
+		return imaging.Metadata{Latitude: &photoLat, Longitude: &photoLng}
+	}
+	onlyLatitude := at(5000)
+	onlyLatitude.Longitude = nil
+
+	tests := []struct {
+		name     string
+		limit    string
+		meta     imaging.Metadata
+		want     bool
+		wantText string
+	}{
+		{"no position", "", imaging.Metadata{}, false, ""},
+		{"half a position", "", onlyLatitude, false, ""},
+		{"on the spot", "", at(0), false, ""},
+		{"within the default", "", at(450), false, ""},
+		{"past the default", "", at(1200), true, "The day image was taken 1200 m from the spot's coordinates"},
+		{"stricter limit", "100", at(450), true, "The day image was taken 450 m from the spot's coordinates"},
+		{"looser limit", "2000", at(1200), false, ""},
+		{"invalid limit uses the default", "far", at(1200), true, "The day image was taken 1200 m from the spot's coordinates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("EXIF_MAX_LOCATION_MISMATCH", tt.limit)
+
+			text, mismatch := photoLocationMismatch("day image", tt.meta, lat, lng)
+			if mismatch != tt.want || text != tt.wantText {
+				t.Errorf("photoLocationMismatch = %q, %v; want %q, %v", text, mismatch, tt.wantText, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
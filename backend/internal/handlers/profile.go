@@ -2,11 +2,12 @@ package handlers
 
 import (
 	"chillspot-backend/internal/models"
+	"chillspot-backend/internal/storage"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
-	"os"
 	"path/filepath"
 
 	"github.com/google/uuid"
@@ -18,7 +19,7 @@ type UpdateProfileRequest struct {
 	Username string `json:"username"`
 }
 
-func UpdateProfile(db *gorm.DB) http.HandlerFunc {
+func UpdateProfile(db *gorm.DB, store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := r.Context().Value("user_id").(string)
 		if !ok {
@@ -47,23 +48,15 @@ func UpdateProfile(db *gorm.DB) http.HandlerFunc {
 			// Generate unique filename
 			ext := filepath.Ext(handler.Filename)
 			newFilename := uuid.New().String() + ext
-			filePath := filepath.Join("uploads", newFilename)
 
-			// Create upload directory if not exists
-			if err := os.MkdirAll("uploads", os.ModePerm); err != nil {
-				http.Error(w, "Failed to create upload directory", http.StatusInternalServerError)
-				return
-			}
-
-			// Save file
-			dst, err := os.Create(filePath)
+			data, err := io.ReadAll(file)
 			if err != nil {
-				http.Error(w, "Failed to save image", http.StatusInternalServerError)
+				http.Error(w, "Failed to read image", http.StatusBadRequest)
 				return
 			}
-			defer dst.Close()
 
-			if _, err := io.Copy(dst, file); err != nil {
+			// Save file
+			if err := store.Put(r.Context(), storage.UploadsPrefix+newFilename, data, mime.TypeByExtension(ext)); err != nil {
 				http.Error(w, "Failed to save image", http.StatusInternalServerError)
 				return
 			}
@@ -104,6 +97,15 @@ func UpdateProfile(db *gorm.DB) http.HandlerFunc {
 			updateData["profile_pic"] = profilePicPath
 		}
 
+		// Visits are deduplicated per day in this timezone
+		if timezone := r.FormValue("timezone"); timezone != "" {
+			if _, err := parseTimezone(timezone); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			updateData["timezone"] = timezone
+		}
+
 		// Update user
 		result := db.Model(&models.User{}).
 			Where("id = ?", userID).
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"chillspot-backend/internal/models"
+	"chillspot-backend/internal/weather"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultForecastHours = 3
+	maxForecastHours     = 12
+	// Nearest spots considered for recommendation; each needs a forecast
+	maxRecommendationCandidates = 200
+	// How long the forecasts of all candidates may take together
+	recommendationForecastTimeout = 5 * time.Second
+)
+
+type RecommendedSpot struct {
+	SpotListItem
+	GoodRightNow   bool             `json:"good_right_now"`
+	MatchingHours  int              `json:"matching_hours"` // of the next ?hours=
+	Score          float64          `json:"score"`
+	CurrentWeather weather.Snapshot `json:"current_weather"`
+}
+
+// GetRecommendedSpotsHandler ranks the spots around lat/lng whose recommended
+// weather matches the forecast: by the share of the next ?hours= (default 3)
+// with matching weather, then whether it matches right now, then distance.
+// The usual listing filters apply. Spots whose forecast can't be had in time
+// are left out.
+func GetRecommendedSpotsHandler(db *gorm.DB, forecasts weather.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		q, err := ParseSpotQuery(r, userUUID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !q.HasLocation {
+			http.Error(w, "Invalid latitude", http.StatusBadRequest)
+			return
+		}
+
+		hours := defaultForecastHours
+		if hoursStr := r.URL.Query().Get("hours"); hoursStr != "" {
+			hours, err = strconv.Atoi(hoursStr)
+			if err != nil || hours < 1 || hours > maxForecastHours {
+				http.Error(w, "Invalid hours", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var spots []SpotListItem
+		if err := q.candidates(db, true).
+			Where("cardinality(recommended_weathers) > 0").
+			Order("distance ASC").
+			Limit(maxRecommendationCandidates).
+			Scan(&spots).Error; err != nil {
+			http.Error(w, "Failed to fetch spots", http.StatusInternalServerError)
+			return
+		}
+
+		masked := make([]*models.Spot, len(spots))
+		for i := range spots {
+			masked[i] = &spots[i].Spot
+		}
+		if err := maskSpots(db, userUUID, masked...); err != nil {
+			http.Error(w, "Failed to fetch spots", http.StatusInternalServerError)
+			return
+		}
+
+		// A slow or failing forecast leaves its spots out rather than
+		// holding up or failing the whole ranking
+		ctx, cancel := context.WithTimeout(r.Context(), recommendationForecastTimeout)
+		defer cancel()
+		locations := make([]weather.Location, len(spots))
+		for i, spot := range spots {
+			locations[i] = weather.Location{Lat: spot.Latitude, Lng: spot.Longitude}
+		}
+		fetched := weather.Forecasts(ctx, forecasts, locations)
+		if len(spots) > 0 && !slices.ContainsFunc(fetched, func(f *weather.Forecast) bool { return f != nil }) {
+			http.Error(w, "Failed to fetch the weather forecast", http.StatusBadGateway)
+			return
+		}
+
+		recommended := rankRecommendations(spots, fetched, time.Now(), hours)
+		if len(recommended) > q.Page.Limit {
+			recommended = recommended[:q.Page.Limit]
+		}
+
+		// Rankings change with every forecast, so there is only ever one page
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Page[RecommendedSpot]{Items: recommended})
+	}
+}
+
+// rankRecommendations scores each spot against its forecast and returns the
+// ones whose weather matches now or within hours, best first: by the share
+// of matching hours, then matching now, then distance. Spots without a
+// forecast are left out.
+func rankRecommendations(spots []SpotListItem, forecasts []*weather.Forecast, now time.Time, hours int) []RecommendedSpot {
+	recommended := []RecommendedSpot{}
+	for i, spot := range spots {
+		forecast := forecasts[i]
+		if forecast == nil {
+			continue
+		}
+
+		item := RecommendedSpot{
+			SpotListItem:   spot,
+			GoodRightNow:   spot.SuitsWeather(forecast.Current.Condition),
+			CurrentWeather: forecast.Current,
+		}
+		next := forecast.Next(now, hours)
+		for _, snapshot := range next {
+			if spot.SuitsWeather(snapshot.Condition) {
+				item.MatchingHours++
+			}
+		}
+		if len(next) > 0 {
+			item.Score = float64(item.MatchingHours) / float64(len(next))
+		}
+		if item.MatchingHours == 0 && !item.GoodRightNow {
+			continue
+		}
+
+		item.FillImageURLs()
+		recommended = append(recommended, item)
+	}
+
+	sort.SliceStable(recommended, func(i, j int) bool {
+		a, b := recommended[i], recommended[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.GoodRightNow != b.GoodRightNow {
+			return a.GoodRightNow
+		}
+		return *a.Distance < *b.Distance
+	})
+	return recommended
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"chillspot-backend/internal/models"
+	"chillspot-backend/internal/weather"
+)
+
+func TestRankRecommendations(t *testing.T) {
+	fixture, err := weather.NewFixture("../weather/testdata/forecast.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A clear night, clouding over in the fourth hour
+	clearNight, err := fixture.Forecast(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := clearNight.Current.Time
+	overcast := *clearNight
+	overcast.Current.Condition = models.Cloudy
+
+	spot := func(name string, distance float64, weathers ...models.WeatherCondition) SpotListItem {
+		return SpotListItem{Spot: models.Spot{Title: name, RecommendedWeathers: weathers}, Distance: &distance}
+	}
+	spots := []SpotListItem{
+		spot("stargazing", 500, models.ClearNight),
+		spot("cloud watching", 100, models.Cloudy),
+		spot("anytime", 900, models.AnyWeather),
+		spot("rain walk", 50, models.Rainy),
+		spot("closer stargazing", 200, models.ClearNight),
+		spot("no forecast", 10, models.AnyWeather),
+		spot("cloudy now", 1000, models.Cloudy),
+	}
+	forecasts := []*weather.Forecast{clearNight, clearNight, clearNight, clearNight, clearNight, nil, &overcast}
+
+	want := []struct {
+		name     string
+		matching int
+		score    float64
+		now      bool
+	}{
+		{"anytime", 4, 1, true},
+		// Equal scores go to the spot that suits the weather now, then the closer one
+		{"closer stargazing", 3, 0.75, true},
+		{"stargazing", 3, 0.75, true},
+		{"cloudy now", 1, 0.25, true},
+		{"cloud watching", 1, 0.25, false},
+	}
+
+	got := rankRecommendations(spots, forecasts, now, 4)
+	if len(got) != len(want) {
+		names := make([]string, len(got))
+		for i, item := range got {
+			names[i] = item.Title
+		}
+		t.Fatalf("recommended %q, want %d spots", names, len(want))
+	}
+	for i, w := range want {
+		item := got[i]
+		if item.Title != w.name || item.MatchingHours != w.matching || item.Score != w.score || item.GoodRightNow != w.now {
+			t.Errorf("#%d: %s with %d hours, score %v, now %v; want %+v",
+				i+1, item.Title, item.MatchingHours, item.Score, item.GoodRightNow, w)
+		}
+		if item.CurrentWeather != forecasts[indexOfSpot(spots, item.Title)].Current {
+			t.Errorf("%s: current weather %+v", item.Title, item.CurrentWeather)
+		}
+	}
+}
+
+func indexOfSpot(spots []SpotListItem, title string) int {
+	for i, spot := range spots {
+		if spot.Title == title {
+			return i
+		}
+	}
+	return -1
+}
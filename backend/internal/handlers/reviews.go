@@ -8,7 +8,6 @@ import (
 	"chillspot-backend/internal/models"
 
 	"github.com/google/uuid"
-	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
@@ -37,9 +36,14 @@ func CreateReviewHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		var spot models.Spot
+		if !findVisibleSpot(db, w, userUUID, input.SpotID, &spot) {
+			return
+		}
+
 		// Check if user has visited this spot
 		var visited models.VisitedSpot
-		if err := db.Where("user_id = ? AND spot_id = ?", userUUID, input.SpotID).First(&visited).Error; err != nil {
+		if err := db.Where("user_id = ? AND spot_id = ? AND verified", userUUID, input.SpotID).First(&visited).Error; err != nil {
 			http.Error(w, "You must visit a spot before reviewing it", http.StatusForbidden)
 			return
 		}
@@ -91,24 +95,41 @@ func GetUserReviewsHandler(db *gorm.DB) http.HandlerFunc {
 			SpotTitle string `json:"spot_title"`
 		}
 
-		var reviews []ReviewWithSpot
+		page, err := parsePageRequest(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		query := `
-            SELECT reviews.*, spots.title as spot_title 
-            FROM reviews
-            JOIN spots ON spots.id = reviews.spot_id
-            WHERE reviews.user_id = ?
-        `
+		query := db.Table("reviews").
+			Select("reviews.*, spots.title as spot_title").
+			Joins("JOIN spots ON spots.id = reviews.spot_id").
+			Where("reviews.user_id = ?", userUUID).
+			Where(visibleSpots(db, userUUID, false))
 
-		if err := db.Raw(query, userUUID).Scan(&reviews).Error; err != nil {
+		tx, err := applyKeyset[int64](query, page, "reviews.credited_at", "reviews.id", true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var reviews []ReviewWithSpot
+		if err := tx.Scan(&reviews).Error; err != nil {
 			http.Error(w, "Failed to fetch reviews", http.StatusInternalServerError)
 			return
 		}
 
+		reviewPage := newPage(reviews, page, "reviews.credited_at", func(review ReviewWithSpot) (any, uuid.UUID) {
+			return review.CreditedAt, review.ID
+		})
+
 		// Format response
-		response := make([]map[string]interface{}, len(reviews))
-		for i, review := range reviews {
-			response[i] = map[string]interface{}{
+		response := Page[map[string]interface{}]{
+			Items:      make([]map[string]interface{}, len(reviewPage.Items)),
+			NextCursor: reviewPage.NextCursor,
+		}
+		for i, review := range reviewPage.Items {
+			response.Items[i] = map[string]interface{}{
 				"id":         review.ID,
 				"text":       review.Text,
 				"likes":      review.Likes,
@@ -124,16 +145,34 @@ func GetUserReviewsHandler(db *gorm.DB) http.HandlerFunc {
 
 func GetSpotReviewsHandler(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		spotID := vars["spotId"]
+		var spot models.Spot
+		viewerID, ok := loadVisibleSpot(db, w, r, "spotId", &spot)
+		if !ok || !requireDiscovered(db, w, viewerID, &spot) {
+			return
+		}
+		spotID := spot.ID
+
+		page, err := parsePageRequest(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tx, err := applyKeyset[int64](db.Where("spot_id = ?", spotID), page, "credited_at", "id", true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		var reviews []models.Review
-		if err := db.Where("spot_id = ?", spotID).Find(&reviews).Error; err != nil {
+		if err := tx.Find(&reviews).Error; err != nil {
 			http.Error(w, "Failed to fetch reviews", http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(reviews)
+		json.NewEncoder(w).Encode(newPage(reviews, page, "credited_at", func(review models.Review) (any, uuid.UUID) {
+			return review.CreditedAt, review.ID
+		}))
 	}
 }
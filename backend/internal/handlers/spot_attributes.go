@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const maxSuggestionCommentLength = 500
+
+type AttributeSuggestionInput struct {
+	Attributes models.SpotAttributes `json:"attributes"`
+	Comment    string                `json:"comment"`
+}
+
+// AttributeFilter narrows spot listings by their attributes. Spots whose
+// attribute is unknown never match a filter on it.
+type AttributeFilter struct {
+	MaxDifficulty        *models.Difficulty
+	MaxHikeMinutes       *uint
+	Parking              []models.Parking
+	DogFriendly          *bool
+	WheelchairAccessible *bool
+	WaterSource          *bool
+	CampingAllowed       *bool
+	FeeRequired          *bool
+	Month                time.Month // 0 for any
+}
+
+func parseOptionalBool(query url.Values, name string) (*bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, errors.New("Invalid " + name)
+	}
+	return &b, nil
+}
+
+// parseAttributeFilter reads max_difficulty, max_hike_minutes, parking (comma
+// separated), dog_friendly, wheelchair_accessible, water_source,
+// camping_allowed, fee_required and month
+func parseAttributeFilter(query url.Values) (AttributeFilter, error) {
+	var f AttributeFilter
+	var err error
+
+	if value := query.Get("max_difficulty"); value != "" {
+		difficulty := models.Difficulty(strings.ToLower(value))
+		if !models.ValidDifficulty(difficulty) {
+			return f, errors.New("Invalid max_difficulty")
+		}
+		f.MaxDifficulty = &difficulty
+	}
+	if f.MaxHikeMinutes, err = parseOptionalUint(query, "max_hike_minutes"); err != nil {
+		return f, err
+	}
+	if value := query.Get("parking"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			parking := models.Parking(strings.ToLower(strings.TrimSpace(part)))
+			if !models.ValidParking(parking) {
+				return f, errors.New("Invalid parking")
+			}
+			f.Parking = append(f.Parking, parking)
+		}
+	}
+
+	for _, flag := range []struct {
+		name  string
+		value **bool
+	}{
+		{"dog_friendly", &f.DogFriendly},
+		{"wheelchair_accessible", &f.WheelchairAccessible},
+		{"water_source", &f.WaterSource},
+		{"camping_allowed", &f.CampingAllowed},
+		{"fee_required", &f.FeeRequired},
+	} {
+		if *flag.value, err = parseOptionalBool(query, flag.name); err != nil {
+			return f, err
+		}
+	}
+
+	if value := query.Get("month"); value != "" {
+		month, ok := models.ParseMonth(value)
+		if !ok {
+			return f, errors.New("Invalid month")
+		}
+		f.Month = month
+	}
+	return f, nil
+}
+
+// apply adds the filter's conditions to a spots query
+func (f *AttributeFilter) apply(tx *gorm.DB) *gorm.DB {
+	if f.MaxDifficulty != nil {
+		tx = tx.Where("spots.access_difficulty IN ?", models.Difficulties[:f.MaxDifficulty.Rank()+1])
+	}
+	if f.MaxHikeMinutes != nil {
+		tx = tx.Where("spots.hike_minutes <= ?", *f.MaxHikeMinutes)
+	}
+	if len(f.Parking) > 0 {
+		tx = tx.Where("spots.parking IN ?", f.Parking)
+	}
+	for _, flag := range []struct {
+		column string
+		value  *bool
+	}{
+		{"dog_friendly", f.DogFriendly},
+		{"wheelchair_accessible", f.WheelchairAccessible},
+		{"water_source", f.WaterSource},
+		{"camping_allowed", f.CampingAllowed},
+		{"fee_required", f.FeeRequired},
+	} {
+		if flag.value != nil {
+			tx = tx.Where("spots."+flag.column+" = ?", *flag.value)
+		}
+	}
+	if f.Month != 0 {
+		tx = tx.Where("spots.best_months & ? <> 0", 1<<(f.Month-1))
+	}
+	return tx
+}
+
+// parseAttributeForm sets the attributes present in a spot form. An empty
+// value makes the attribute unknown again.
+func parseAttributeForm(form url.Values, attrs *models.SpotAttributes) error {
+	if values, ok := form["access_difficulty"]; ok {
+		attrs.AccessDifficulty = nil
+		if values[0] != "" {
+			difficulty := models.Difficulty(strings.ToLower(values[0]))
+			attrs.AccessDifficulty = &difficulty
+		}
+	}
+	if values, ok := form["hike_minutes"]; ok {
+		attrs.HikeMinutes = nil
+		if values[0] != "" {
+			minutes, err := strconv.Atoi(values[0])
+			if err != nil {
+				return errors.New("Invalid hike_minutes")
+			}
+			attrs.HikeMinutes = &minutes
+		}
+	}
+	if values, ok := form["parking"]; ok {
+		attrs.Parking = nil
+		if values[0] != "" {
+			parking := models.Parking(strings.ToLower(values[0]))
+			attrs.Parking = &parking
+		}
+	}
+
+	for _, flag := range []struct {
+		name  string
+		value **bool
+	}{
+		{"dog_friendly", &attrs.DogFriendly},
+		{"wheelchair_accessible", &attrs.WheelchairAccessible},
+		{"water_source", &attrs.WaterSource},
+		{"camping_allowed", &attrs.CampingAllowed},
+		{"fee_required", &attrs.FeeRequired},
+	} {
+		if values, ok := form[flag.name]; ok {
+			*flag.value = nil
+			if values[0] != "" {
+				b, err := strconv.ParseBool(values[0])
+				if err != nil {
+					return errors.New("Invalid " + flag.name)
+				}
+				*flag.value = &b
+			}
+		}
+	}
+
+	if values, ok := form["fee_note"]; ok {
+		attrs.FeeNote = nil
+		if note := strings.TrimSpace(values[0]); note != "" {
+			attrs.FeeNote = &note
+		}
+	}
+	if values, ok := form["best_months"]; ok {
+		months, err := models.ParseMonths(strings.Join(values, ","))
+		if err != nil {
+			return errors.New("Invalid best_months")
+		}
+		attrs.BestMonths = months
+	}
+
+	return attrs.Validate()
+}
+
+// CreateAttributeSuggestionHandler lets a user who visited a spot propose
+// corrections to its attributes
+func CreateAttributeSuggestionHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		spotUUID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid spot ID", http.StatusBadRequest)
+			return
+		}
+
+		var spot models.Spot
+		if !findVisibleSpot(db, w, userUUID, spotUUID, &spot) {
+			return
+		}
+		if spot.UserID == userUUID {
+			http.Error(w, "Edit your own spot instead of suggesting changes", http.StatusBadRequest)
+			return
+		}
+
+		var visits int64
+		db.Model(&models.VisitedSpot{}).Where("spot_id = ? AND user_id = ? AND verified", spot.ID, userUUID).Count(&visits)
+		if visits == 0 {
+			http.Error(w, "Only visitors can suggest changes to a spot", http.StatusForbidden)
+			return
+		}
+
+		var input AttributeSuggestionInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := input.Attributes.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if input.Attributes.IsEmpty() {
+			http.Error(w, "Suggest at least one attribute", http.StatusBadRequest)
+			return
+		}
+		input.Comment = strings.TrimSpace(input.Comment)
+		if len(input.Comment) > maxSuggestionCommentLength {
+			http.Error(w, "Comment must be at most 500 characters", http.StatusBadRequest)
+			return
+		}
+
+		suggestion := models.AttributeSuggestion{
+			SpotID:     spot.ID,
+			UserID:     userUUID,
+			Comment:    input.Comment,
+			Status:     models.SuggestionPending,
+			Attributes: input.Attributes,
+		}
+		if err := db.Omit("User").Create(&suggestion).Error; err != nil {
+			http.Error(w, "Failed to save suggestion", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":    "Suggestion sent to the spot's owner",
+			"suggestion": suggestion,
+		})
+	}
+}
+
+// GetAttributeSuggestionsHandler lists a spot's suggestions, newest first.
+// The owner sees everyone's; other users only their own. ?status= filters.
+func GetAttributeSuggestionsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		spotUUID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid spot ID", http.StatusBadRequest)
+			return
+		}
+
+		var spot models.Spot
+		if !findVisibleSpot(db, w, userUUID, spotUUID, &spot) {
+			return
+		}
+
+		query := r.URL.Query()
+		page, err := parsePageRequest(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		suggestions := db.Preload("User").Where("spot_id = ?", spot.ID)
+		if spot.UserID != userUUID {
+			suggestions = suggestions.Where("user_id = ?", userUUID)
+		}
+		if status := query.Get("status"); status != "" {
+			switch models.SuggestionStatus(status) {
+			case models.SuggestionPending, models.SuggestionAccepted, models.SuggestionRejected:
+				suggestions = suggestions.Where("status = ?", status)
+			default:
+				http.Error(w, "Invalid status", http.StatusBadRequest)
+				return
+			}
+		}
+
+		tx, err := applyKeyset[time.Time](suggestions, page, "created_at", "id", true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var results []models.AttributeSuggestion
+		if err := tx.Find(&results).Error; err != nil {
+			http.Error(w, "Failed to fetch suggestions", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newPage(results, page, "created_at", func(s models.AttributeSuggestion) (any, uuid.UUID) {
+			return s.CreatedAt, s.ID
+		}))
+	}
+}
+
+// ReviewAttributeSuggestionHandler lets the spot's owner accept a pending
+// suggestion, which applies its attributes to the spot, or reject it
+func ReviewAttributeSuggestionHandler(db *gorm.DB, accept bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		if !loadOwnedSpot(db, w, r, &spot) {
+			return
+		}
+
+		suggestionUUID, err := uuid.Parse(mux.Vars(r)["suggestionId"])
+		if err != nil {
+			http.Error(w, "Invalid suggestion ID", http.StatusBadRequest)
+			return
+		}
+
+		var suggestion models.AttributeSuggestion
+		if err := db.Where("id = ? AND spot_id = ?", suggestionUUID, spot.ID).First(&suggestion).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(w, "Suggestion not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+			}
+			return
+		}
+		if suggestion.Status != models.SuggestionPending {
+			http.Error(w, "Suggestion was already reviewed", http.StatusConflict)
+			return
+		}
+
+		now := time.Now()
+		suggestion.Status = models.SuggestionRejected
+		if accept {
+			suggestion.Status = models.SuggestionAccepted
+		}
+		suggestion.ReviewedAt = &now
+
+		err = db.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+			if accept {
+				spot.SpotAttributes.Merge(suggestion.Attributes)
+				spot.UpdatedAt = now
+				if err := tx.Omit("User", "Category").Save(&spot).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(&suggestion).Updates(map[string]any{
+				"status":      suggestion.Status,
+				"reviewed_at": suggestion.ReviewedAt,
+			}).Error
+		})
+		if err != nil {
+			http.Error(w, "Failed to review suggestion", http.StatusInternalServerError)
+			return
+		}
+
+		message := "Suggestion rejected"
+		if accept {
+			message = "Suggestion accepted"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message":    message,
+			"suggestion": suggestion,
+			"spot":       spot,
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"chillspot-backend/internal/models"
+)
+
+func TestParseAttributeForm(t *testing.T) {
+	easy, dogs, minutes := models.Easy, true, 30
+	summer := models.Months(1<<5 | 1<<6 | 1<<7)
+	stored := func() models.SpotAttributes {
+		return models.SpotAttributes{AccessDifficulty: &easy, HikeMinutes: &minutes, DogFriendly: &dogs, BestMonths: summer}
+	}
+
+	tests := []struct {
+		name    string
+		form    url.Values
+		check   func(a models.SpotAttributes) bool
+		wantErr string
+	}{
+		{"nothing changes", url.Values{}, func(a models.SpotAttributes) bool {
+			return *a.AccessDifficulty == models.Easy && *a.HikeMinutes == 30 && *a.DogFriendly && a.BestMonths == summer
+		}, ""},
+		{"difficulty in any case", url.Values{"access_difficulty": {"Hard"}}, func(a models.SpotAttributes) bool {
+			return *a.AccessDifficulty == models.Hard && *a.HikeMinutes == 30
+		}, ""},
+		{"difficulty cleared", url.Values{"access_difficulty": {""}}, func(a models.SpotAttributes) bool {
+			return a.AccessDifficulty == nil
+		}, ""},
+		{"unknown difficulty", url.Values{"access_difficulty": {"extreme"}}, nil, "Invalid access_difficulty"},
+		{"longest hike", url.Values{"hike_minutes": {"1440"}}, func(a models.SpotAttributes) bool {
+			return *a.HikeMinutes == 1440
+		}, ""},
+		{"hike over a day", url.Values{"hike_minutes": {"1441"}}, nil, "hike_minutes must be between 0 and 1440"},
+		{"negative hike", url.Values{"hike_minutes": {"-5"}}, nil, "hike_minutes must be between 0 and 1440"},
+		{"hike not a number", url.Values{"hike_minutes": {"half an hour"}}, nil, "Invalid hike_minutes"},
+		{"flag set to false", url.Values{"dog_friendly": {"false"}}, func(a models.SpotAttributes) bool {
+			return a.DogFriendly != nil && !*a.DogFriendly
+		}, ""},
+		{"invalid flag", url.Values{"water_source": {"maybe"}}, nil, "Invalid water_source"},
+		// Months can be names, abbreviations or numbers, over several values
+		{"months", url.Values{"best_months": {"Dec, jan", "2"}}, func(a models.SpotAttributes) bool {
+			return slices.Equal(a.BestMonths.List(), []int{1, 2, 12})
+		}, ""},
+		{"months cleared", url.Values{"best_months": {""}}, func(a models.SpotAttributes) bool {
+			return a.BestMonths == 0
+		}, ""},
+		{"month 13", url.Values{"best_months": {"12,13"}}, nil, "Invalid best_months"},
+		{"month 0", url.Values{"best_months": {"0"}}, nil, "Invalid best_months"},
+		{"ambiguous abbreviation", url.Values{"best_months": {"ju"}}, nil, "Invalid best_months"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := stored()
+			err := parseAttributeForm(tt.form, &attrs)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(attrs) {
+				data, _ := json.Marshal(attrs)
+				t.Errorf("parsed %s", data)
+			}
+		})
+	}
+}
+
+func TestParseAttributeFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantSQL   string
+		wantVars  []any
+		wantError string
+	}{
+		{"no filter", "", "", nil, ""},
+		// Each difficulty includes the easier ones
+		{"easiest", "max_difficulty=easy", "spots.access_difficulty IN ($1)", []any{models.Easy}, ""},
+		{"up to hard", "max_difficulty=Hard", "spots.access_difficulty IN ($1,$2,$3)",
+			[]any{models.Easy, models.Moderate, models.Hard}, ""},
+		{"unknown difficulty", "max_difficulty=extreme", "", nil, "Invalid max_difficulty"},
+		{"January", "month=1", "spots.best_months & $1 <> 0", []any{1}, ""},
+		{"December by name", "month=December", "spots.best_months & $1 <> 0", []any{1 << 11}, ""},
+		{"abbreviated month", "month=sep", "spots.best_months & $1 <> 0", []any{1 << 8}, ""},
+		{"month 0", "month=0", "", nil, "Invalid month"},
+		{"month 13", "month=13", "", nil, "Invalid month"},
+		{"too short to tell", "month=ma", "", nil, "Invalid month"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			filter, err := parseAttributeFilter(query)
+			if tt.wantError != "" {
+				if err == nil || err.Error() != tt.wantError {
+					t.Errorf("err = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stmt := filter.apply(dryRunDB(t).Model(&models.Spot{})).Find(&[]models.Spot{}).Statement
+			sql := stmt.SQL.String()
+			if tt.wantSQL == "" {
+				if strings.Contains(sql, "WHERE") {
+					t.Errorf("SQL = %s, want no conditions", sql)
+				}
+				return
+			}
+			if !strings.Contains(sql, tt.wantSQL) || !slices.Equal(stmt.Vars, tt.wantVars) {
+				t.Errorf("SQL = %s with %v, want %s with %v", sql, stmt.Vars, tt.wantSQL, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestAcceptedSuggestion(t *testing.T) {
+	easy, dogs, minutes, fee := models.Easy, true, 30, "5 EUR per car"
+	spot := models.Spot{SpotAttributes: models.SpotAttributes{
+		AccessDifficulty: &easy,
+		HikeMinutes:      &minutes,
+		DogFriendly:      &dogs,
+		FeeNote:          &fee,
+		BestMonths:       models.Months(1<<5 | 1<<6 | 1<<7),
+	}}
+
+	// Decoded and checked as CreateAttributeSuggestionHandler does
+	var input AttributeSuggestionInput
+	body := `{"attributes": {"access_difficulty": "moderate", "dog_friendly": false, "parking": "limited", "best_months": [5, 6]}}`
+	if err := json.NewDecoder(strings.NewReader(body)).Decode(&input); err != nil {
+		t.Fatal(err)
+	}
+	if err := input.Attributes.Validate(); err != nil || input.Attributes.IsEmpty() {
+		t.Fatalf("suggestion refused: %v", err)
+	}
+
+	// What ReviewAttributeSuggestionHandler does on accepting it
+	spot.SpotAttributes.Merge(input.Attributes)
+
+	got := spot.SpotAttributes
+	switch {
+	case *got.AccessDifficulty != models.Moderate:
+		t.Errorf("difficulty %s, want the suggested moderate", *got.AccessDifficulty)
+	case *got.DogFriendly:
+		t.Error("a suggested false didn't replace true")
+	case got.Parking == nil || *got.Parking != models.LimitedParking:
+		t.Errorf("parking %v, want the suggested limited", got.Parking)
+	case got.HikeMinutes == nil || *got.HikeMinutes != 30 || got.FeeNote == nil || *got.FeeNote != fee:
+		t.Error("attributes the suggestion left unknown were changed")
+	case !slices.Equal(got.BestMonths.List(), []int{5, 6}):
+		// Suggested months replace the spot's rather than adding to them
+		t.Errorf("best months %v, want [5 6]", got.BestMonths.List())
+	}
+
+	for name, body := range map[string]string{
+		"month 13":           `{"attributes": {"best_months": [12, 13]}}`,
+		"month 0":            `{"attributes": {"best_months": [0]}}`,
+		"unknown difficulty": `{"attributes": {"access_difficulty": "extreme"}}`,
+		"hike over a day":    `{"attributes": {"hike_minutes": 1441}}`,
+		"nothing suggested":  `{"attributes": {"best_months": []}, "comment": "looks fine"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var input AttributeSuggestionInput
+			err := json.NewDecoder(strings.NewReader(body)).Decode(&input)
+			if err == nil {
+				err = input.Attributes.Validate()
+			}
+			if err == nil && !input.Attributes.IsEmpty() {
+				t.Errorf("accepted %s", body)
+			}
+		})
+	}
+}
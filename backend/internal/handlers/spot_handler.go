@@ -1,17 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"log"
+	"mime"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	"chillspot-backend/internal/astro"
+	"chillspot-backend/internal/imaging"
 	"chillspot-backend/internal/models"
+	"chillspot-backend/internal/storage"
+	"chillspot-backend/internal/weather"
 
 	"gorm.io/gorm"
 
@@ -28,7 +34,7 @@ type AddSpotInput struct {
 	UserID      string  `json:"user_id"`
 }
 
-func AddSpotHandler(db *gorm.DB) http.HandlerFunc {
+func AddSpotHandler(db *gorm.DB, store storage.Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get user ID from context
 		userID, ok := r.Context().Value("user_id").(string)
@@ -55,20 +61,86 @@ func AddSpotHandler(db *gorm.DB) http.HandlerFunc {
 		longitudeStr := r.FormValue("longitude")
 		title := r.FormValue("title")
 		description := r.FormValue("description")
-		weather := r.FormValue("weather")
-
-		// Convert coordinates
-		latitude, err := strconv.ParseFloat(latitudeStr, 64)
+		// Several conditions may be sent comma separated or as repeated fields
+		weathers, err := models.ParseWeatherConditions(strings.Join(r.Form["weather"], ","))
+		if err != nil {
+			http.Error(w, "Invalid weather: see /meta/weather-conditions", http.StatusBadRequest)
+			return
+		}
+		category, err := findCategory(db, r.FormValue("category"))
 		if err != nil {
-			http.Error(w, "Invalid latitude", http.StatusBadRequest)
+			categoryFormError(w, err)
+			return
+		}
+		tags, err := models.ParseTags(r.Form["tags"]...)
+		if err != nil {
+			tagFormError(w, err)
 			return
 		}
 
-		longitude, err := strconv.ParseFloat(longitudeStr, 64)
+		// Process both images first: their EXIF can fill in the coordinates,
+		// and a duplicate is turned away before anything is stored
+		dayImage, err := readImage(r, "day_image")
 		if err != nil {
-			http.Error(w, "Invalid longitude", http.StatusBadRequest)
+			imageUploadError(w, "day_image", err)
 			return
 		}
+		nightImage, err := readImage(r, "night_image")
+		if err != nil {
+			imageUploadError(w, "night_image", err)
+			return
+		}
+
+		var dayMeta, nightMeta imaging.Metadata
+		if dayImage != nil {
+			dayMeta = dayImage.Metadata()
+		}
+		if nightImage != nil {
+			nightMeta = nightImage.Metadata()
+		}
+
+		var latitude, longitude float64
+		warnings := []string{}
+		locationMismatch := false
+		if latitudeStr == "" && longitudeStr == "" {
+			// Without submitted coordinates the photos' GPS position is used
+			switch {
+			case dayMeta.Latitude != nil && dayMeta.Longitude != nil:
+				latitude, longitude = *dayMeta.Latitude, *dayMeta.Longitude
+			case nightMeta.Latitude != nil && nightMeta.Longitude != nil:
+				latitude, longitude = *nightMeta.Latitude, *nightMeta.Longitude
+			default:
+				http.Error(w, "Latitude and longitude are required when the photos have no GPS data", http.StatusBadRequest)
+				return
+			}
+		} else {
+			// Convert coordinates
+			latitude, err = strconv.ParseFloat(latitudeStr, 64)
+			if err != nil {
+				http.Error(w, "Invalid latitude", http.StatusBadRequest)
+				return
+			}
+
+			longitude, err = strconv.ParseFloat(longitudeStr, 64)
+			if err != nil {
+				http.Error(w, "Invalid longitude", http.StatusBadRequest)
+				return
+			}
+		}
+
+		for _, image := range []struct {
+			name string
+			meta imaging.Metadata
+		}{
+			{"day image", dayMeta},
+			{"night image", nightMeta},
+		} {
+			if warning, mismatch := photoLocationMismatch(image.name, image.meta, latitude, longitude); mismatch {
+				locationMismatch = true
+				warnings = append(warnings, warning)
+			}
+		}
+
 		altitudeStr := r.FormValue("altitude")
 		var altitude float64
 		if altitudeStr != "" {
@@ -77,88 +149,197 @@ func AddSpotHandler(db *gorm.DB) http.HandlerFunc {
 				http.Error(w, "Invalid altitude", http.StatusBadRequest)
 				return
 			}
+		} else if dayMeta.Altitude != nil {
+			altitude = *dayMeta.Altitude
+		} else if nightMeta.Altitude != nil {
+			altitude = *nightMeta.Altitude
 		}
 		// Create spot
 		spot := models.Spot{
-			UserID:             userUUID,
-			Latitude:           latitude,
-			Longitude:          longitude,
-			Altitude:           altitude,
-			Title:              title,
-			Description:        description,
-			RecommendedWeather: models.WeatherCondition(weather),
-			CreatedAt:          time.Now(),
-			UpdatedAt:          time.Now(),
+			UserID:              userUUID,
+			Latitude:            latitude,
+			Longitude:           longitude,
+			Altitude:            altitude,
+			Title:               title,
+			Description:         description,
+			RecommendedWeathers: weathers,
+			LocationMismatch:    locationMismatch,
+			CreatedAt:           time.Now(),
+			UpdatedAt:           time.Now(),
+		}
+
+		if category != nil {
+			spot.CategoryID = &category.ID
+		}
+		if err := parseAttributeForm(r.Form, &spot.SpotAttributes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := parseVisibilityForm(r.Form, &spot); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := parseGeocacheForm(r.Form, &spot); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if dark, ok := takenInDark(nightMeta, latitude, longitude); ok {
+			spot.NightImageAfterSunset = &dark
+			if !dark {
+				warnings = append(warnings, "The night image was taken while the sun was up")
+			}
+		}
+
+		if r.FormValue("force") != "true" {
+			var hashes []uint64
+			for _, image := range []*imaging.Processed{dayImage, nightImage} {
+				if image != nil {
+					hashes = append(hashes, image.Hash)
+				}
+			}
+
+			candidates, err := findDuplicateSpots(db, latitude, longitude, title, hashes, nil, &userUUID)
+			if err != nil {
+				http.Error(w, "Failed to check for duplicates", http.StatusInternalServerError)
+				return
+			}
+			if len(candidates) > 0 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]any{
+					"error":      "This spot may already exist; resend with force=true to add it anyway",
+					"candidates": candidates,
+				})
+				return
+			}
 		}
 
 		// Save day image
-		dayImagePath, err := saveImage(r, "day_image")
+		dayImagePath, err := storeImage(r.Context(), store, dayImage)
 		if err != nil {
-			http.Error(w, "Failed to save day image", http.StatusInternalServerError)
+			imageUploadError(w, "day_image", err)
 			return
 		}
 		spot.DayImage = &dayImagePath
 
 		// Save night image
-		nightImagePath, err := saveImage(r, "night_image")
+		nightImagePath, err := storeImage(r.Context(), store, nightImage)
 		if err != nil {
-			http.Error(w, "Failed to save night image", http.StatusInternalServerError)
+			deleteImage(store, spot.DayImage)
+			imageUploadError(w, "night_image", err)
 			return
 		}
 		spot.NightImage = &nightImagePath
 
-		// Save to database
-		if err := db.Create(&spot).Error; err != nil {
+		// Save to database; the uploaded images also start the photo gallery
+		err = db.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&spot).Error; err != nil {
+				return err
+			}
+			if err := addSpotTags(tx, spot.ID, userUUID, tags); err != nil {
+				return err
+			}
+			if dayImage != nil {
+				photo := models.SpotPhoto{
+					Filename:   dayImagePath,
+					TimeOfDay:  models.Day,
+					CapturedAt: captureTime(dayMeta, longitude),
+				}
+				photo.SetHash(dayImage.Hash)
+				if err := addSlotPhoto(tx, &spot, photo, nil); err != nil {
+					return err
+				}
+			}
+			if nightImage != nil {
+				photo := models.SpotPhoto{
+					Filename:   nightImagePath,
+					TimeOfDay:  models.Night,
+					CapturedAt: captureTime(nightMeta, longitude),
+				}
+				photo.SetHash(nightImage.Hash)
+				if err := addSlotPhoto(tx, &spot, photo, nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			deleteImage(store, spot.DayImage)
+			deleteImage(store, spot.NightImage)
+			if errors.Is(err, errTooManyTags) {
+				tagFormError(w, err)
+				return
+			}
 			http.Error(w, "Failed to create spot", http.StatusInternalServerError)
 			return
 		}
+		spot.Category = category
 
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(map[string]any{
-			"message": "Spot created",
-			"spot":    spot,
+			"message":  "Spot created",
+			"spot":     spot,
+			"tags":     tags,
+			"warnings": warnings,
 		})
 	}
 }
 
-func saveImage(r *http.Request, fieldName string) (string, error) {
-	file, header, err := r.FormFile(fieldName)
+// readImage runs the uploaded form file through the image pipeline. It
+// returns nil when the field is absent.
+func readImage(r *http.Request, fieldName string) (*imaging.Processed, error) {
+	file, _, err := r.FormFile(fieldName)
 	if err != nil {
-		if err == http.ErrMissingFile {
-			return "", nil // No file is acceptable
+		// No file is acceptable, and urlencoded or JSON bodies have none
+		if err == http.ErrMissingFile || err == http.ErrNotMultipart {
+			return nil, nil
 		}
-		return "", err
+		return nil, err
 	}
 	defer file.Close()
 
-	// Create images directory if it doesn't exist
-	err = os.MkdirAll("internal/images", os.ModePerm)
-	if err != nil {
-		return "", err
-	}
-
-	// Create unique filename
-	ext := filepath.Ext(header.Filename)
-	if ext == "" {
-		ext = ".jpg"
-	}
-	filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
-	filePath := filepath.Join("internal", "images", filename)
+	return imaging.Process(file)
+}
 
-	// Save file
-	out, err := os.Create(filePath)
-	if err != nil {
-		return "", err
+// storeImage stores every rendition of a processed upload. It returns the
+// primary filename for the database, or "" for a nil upload.
+func storeImage(ctx context.Context, store storage.Storage, processed *imaging.Processed) (string, error) {
+	if processed == nil {
+		return "", nil
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, file)
-	if err != nil {
-		return "", err
+	// Create unique filenames
+	base := fmt.Sprintf("%d", time.Now().UnixNano())
+	var written []string
+	for rendition, formats := range processed.Files {
+		for format, data := range formats {
+			key := storage.ImagesPrefix + imaging.Filename(base, rendition, format)
+			if err := store.Put(ctx, key, data, mime.TypeByExtension("."+format)); err != nil {
+				for _, k := range written {
+					store.Delete(context.Background(), k)
+				}
+				return "", err
+			}
+			written = append(written, key)
+		}
 	}
 
 	// Return just the filename for database storage
-	return filename, nil
+	return imaging.PrimaryFilename(base), nil
+}
+
+// imageUploadError reports a readImage or storeImage failure; bad uploads are the client's fault
+func imageUploadError(w http.ResponseWriter, fieldName string, err error) {
+	label := strings.ReplaceAll(fieldName, "_", " ")
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedImage):
+		http.Error(w, "Unsupported "+label+" type", http.StatusBadRequest)
+	case errors.Is(err, imaging.ErrImageTooLarge):
+		http.Error(w, "The "+label+" is too large", http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to save "+label, http.StatusInternalServerError)
+	}
 }
 
 func GetSpotsByUserHandler(db *gorm.DB) http.HandlerFunc {
@@ -176,38 +357,54 @@ func GetSpotsByUserHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		var spots []models.Spot
-		if err := db.Where("user_id = ?", userUUID).Find(&spots).Error; err != nil {
+		q, err := ParseSpotQuery(r, userUUID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.OwnerID = &userUUID
+
+		response, err := q.Run(db)
+		if err != nil {
 			http.Error(w, "Failed to fetch spots", http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(spots)
+		json.NewEncoder(w).Encode(response)
 	}
 
 }
 
-func GetSpotHandler(db *gorm.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		spotID := vars["id"]
+// How long a spot waits for its current weather before going without
+const spotForecastTimeout = 2 * time.Second
 
+func GetSpotHandler(db *gorm.DB, forecasts weather.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		var spot models.Spot
-		if err := db.Preload("User").Where("id = ?", spotID).First(&spot).Error; err != nil {
+		viewerID, ok := loadVisibleSpot(db, w, r, "id", &spot)
+		if !ok {
+			return
+		}
+		spotID := spot.ID
+		if err := db.Preload("User").Preload("Category").Where("id = ?", spotID).First(&spot).Error; err != nil {
 			http.Error(w, "Spot not found", http.StatusNotFound)
 			return
 		}
 
-		// Get distinct users who visited this spot
-		var visitCount int64
-		err := db.Model(&models.VisitedSpot{}).
-			Distinct("user_id").
-			Where("spot_id = ?", spotID).
-			Count(&visitCount).Error
+		// Geocache spots stay concealed until found with the proximity check;
+		// fuzzed spots show their exact position once the viewer has a
+		// verified visit, never on a position the client merely claims
+		switch {
+		case isConcealed(db, viewerID, &spot):
+			spot.Conceal()
+		case !revealsLocation(db, viewerID, &spot):
+			spot.Fuzz()
+		}
 
+		tags, err := spotTagNames(db, spot.ID)
 		if err != nil {
-			http.Error(w, "Failed to count visits", http.StatusInternalServerError)
+			http.Error(w, "Failed to fetch tags", http.StatusInternalServerError)
 			return
 		}
 
@@ -217,22 +414,48 @@ func GetSpotHandler(db *gorm.DB) http.HandlerFunc {
 
 		// Update spot counts
 		spot.FavoritesCount = uint(likesCount)
-		spot.VisitCount = uint(visitCount)
 
 		// Create response with proper field names
+		now := time.Now()
 		response := map[string]interface{}{
-			"ID":                 spot.ID,
-			"UserID":             spot.UserID,
-			"User":               spot.User,
-			"Latitude":           spot.Latitude,
-			"Longitude":          spot.Longitude,
-			"Title":              spot.Title,
-			"Description":        spot.Description,
-			"RecommendedWeather": spot.RecommendedWeather,
-			"CreatedAt":          spot.CreatedAt,
-			"UpdatedAt":          spot.UpdatedAt,
-			"favorites_count":    spot.FavoritesCount,
-			"visit_count":        spot.VisitCount,
+			"ID":                   spot.ID,
+			"UserID":               spot.UserID,
+			"User":                 spot.User,
+			"Latitude":             spot.Latitude,
+			"Longitude":            spot.Longitude,
+			"Title":                spot.Title,
+			"Description":          spot.Description,
+			"RecommendedWeather":   spot.RecommendedWeather,
+			"recommended_weathers": spot.RecommendedWeathers,
+			"category":             spot.Category,
+			"tags":                 tags,
+			"attributes":           spot.SpotAttributes,
+			"visibility":           spot.Visibility,
+			"fuzz_location":        spot.FuzzLocation,
+			"location_fuzzed":      spot.LocationFuzzed,
+			"geocache":             spot.Geocache,
+			"hint_radius":          spot.HintRadius,
+			"hint_latitude":        spot.HintLatitude,
+			"hint_longitude":       spot.HintLongitude,
+			"clue":                 spot.Clue,
+			"concealed":            spot.Concealed,
+			"CreatedAt":            spot.CreatedAt,
+			"UpdatedAt":            spot.UpdatedAt,
+			"favorites_count":      spot.FavoritesCount,
+			"visit_count":          spot.VisitCount,
+			"images":               spot.Images,
+			"current_image":        currentImage(&spot, now), // "DayImage" or "NightImage"
+			"is_dark":              astro.IsDark(now, spot.Latitude, spot.Longitude),
+		}
+
+		// Left out when the weather provider is unavailable or slow
+		forecastCtx, cancel := context.WithTimeout(r.Context(), spotForecastTimeout)
+		defer cancel()
+		if forecast, err := forecasts.Forecast(forecastCtx, spot.Latitude, spot.Longitude); err == nil {
+			response["good_right_now"] = spot.SuitsWeather(forecast.Current.Condition)
+			response["current_weather"] = forecast.Current
+		} else {
+			log.Printf("Failed to fetch weather for spot %s: %v", spot.ID, err)
 		}
 
 		// Add image fields with proper handling
@@ -279,6 +502,11 @@ func LikeSpotHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		var spot models.Spot
+		if !findVisibleSpot(db, w, userUUID, spotUUID, &spot) {
+			return
+		}
+
 		// Check if user already liked this spot
 		var existingLike models.Like
 		result := db.Where("user_id = ? AND spot_id = ?", userUUID, spotUUID).First(&existingLike)
@@ -312,11 +540,14 @@ func LikeSpotHandler(db *gorm.DB) http.HandlerFunc {
 		}
 
 		// Return updated spot
-		var spot models.Spot
 		if err := db.Where("id = ?", spotUUID).First(&spot).Error; err != nil {
 			http.Error(w, "Failed to fetch spot", http.StatusInternalServerError)
 			return
 		}
+		if err := maskSpots(db, userUUID, &spot); err != nil {
+			http.Error(w, "Failed to fetch spot", http.StatusInternalServerError)
+			return
+		}
 
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(spot)
@@ -347,37 +578,415 @@ func TrackVisitHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		// Check if visit already exists today
-		var existingVisit models.VisitedSpot
-		today := time.Now().Format("2006-01-02")
-		result := db.Where("user_id = ? AND spot_id = ? AND DATE(visited_at) = ?",
-			userUUID, spotUUID, today).First(&existingVisit)
+		var spot models.Spot
+		if !findVisibleSpot(db, w, userUUID, spotUUID, &spot) || !requireDiscovered(db, w, userUUID, &spot) {
+			return
+		}
 
-		if result.Error == nil {
-			// Visit already tracked today
-			w.WriteHeader(http.StatusOK)
+		var input VisitInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid input", http.StatusBadRequest)
+			return
+		}
+		if err := input.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Create new visit
-		visit := models.VisitedSpot{
-			UserID:    userUUID,
-			SpotID:    spotUUID,
-			VisitedAt: time.Now(),
+		result, err := recordVisit(db.WithContext(r.Context()), &spot, userUUID, input)
+		if err != nil {
+			visitError(w, err)
+			return
 		}
+		writeVisitResult(w, result)
+	}
+}
+
+// deleteImage removes every rendition of a spot image saved by storeImage.
+// Missing files are ignored.
+func deleteImage(store storage.Storage, filename *string) {
+	if filename == nil || *filename == "" {
+		return
+	}
+	for _, name := range imaging.AllFilenames(filepath.Base(*filename)) {
+		key := storage.ImagesPrefix + name
+		if err := store.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete image %s: %v", key, err)
+		}
+	}
+}
+
+// deleteUnreferencedImage removes a replaced image unless a gallery photo or
+// a spot revision still points at it, since reverting would bring it back.
+// Images kept for a revision go once pruneSpotRevisions drops it.
+func deleteUnreferencedImage(db *gorm.DB, store storage.Storage, filename *string) {
+	if filename == nil || *filename == "" {
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.SpotRevision{}).
+		Where("day_image = ? OR night_image = ?", *filename, *filename).
+		Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	if err := db.Model(&models.SpotPhoto{}).
+		Where("filename = ?", *filename).
+		Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	deleteImage(store, filename)
+}
+
+// loadOwnedSpot fetches the spot from the route and makes sure the caller owns it.
+// It writes the error response itself and returns false on failure.
+func loadOwnedSpot(db *gorm.DB, w http.ResponseWriter, r *http.Request, spot *models.Spot) bool {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return false
+	}
+
+	spotUUID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid spot ID", http.StatusBadRequest)
+		return false
+	}
 
-		if err := db.Create(&visit).Error; err != nil {
-			http.Error(w, "Failed to track visit", http.StatusInternalServerError)
+	if err := db.Where("id = ?", spotUUID).First(spot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Spot not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return false
+	}
+
+	if spot.UserID != userUUID {
+		http.Error(w, "You can only modify your own spots", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// UpdateSpotHandler serves both PUT and PATCH. PUT replaces the editable
+// fields and requires all of them; PATCH only touches the fields present in
+// the form. New day_image/night_image files replace the old ones in storage.
+func UpdateSpotHandler(db *gorm.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		if !loadOwnedSpot(db, w, r, &spot) {
 			return
 		}
 
-		// Update spot's visit count
-		if err := db.Model(&models.Spot{}).Where("id = ?", spotUUID).
-			Update("visit_count", gorm.Expr("visit_count + 1")).Error; err != nil {
+		err := r.ParseMultipartForm(32 << 20) // 32 MB max
+		if err != nil && err != http.ErrNotMultipart {
+			http.Error(w, "Failed to parse form data", http.StatusBadRequest)
+			return
+		}
+
+		partial := r.Method == http.MethodPatch
+		if !partial {
+			for _, field := range []string{"latitude", "longitude", "title", "description"} {
+				if _, ok := r.Form[field]; !ok {
+					http.Error(w, "Missing field: "+field, http.StatusBadRequest)
+					return
+				}
+			}
+		}
+
+		if _, ok := r.Form["title"]; ok {
+			spot.Title = r.FormValue("title")
+			if spot.Title == "" {
+				http.Error(w, "Title cannot be empty", http.StatusBadRequest)
+				return
+			}
+		}
+		if _, ok := r.Form["description"]; ok {
+			spot.Description = r.FormValue("description")
+		}
+		if values, ok := r.Form["weather"]; ok {
+			spot.RecommendedWeathers, err = models.ParseWeatherConditions(strings.Join(values, ","))
+			if err != nil {
+				http.Error(w, "Invalid weather: see /meta/weather-conditions", http.StatusBadRequest)
+				return
+			}
+		}
+		if _, ok := r.Form["category"]; ok {
+			category, err := findCategory(db, r.FormValue("category"))
+			if err != nil {
+				categoryFormError(w, err)
+				return
+			}
+			spot.CategoryID = nil
+			if category != nil {
+				spot.CategoryID = &category.ID
+			}
+		}
+		if err := parseAttributeForm(r.Form, &spot.SpotAttributes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := parseVisibilityForm(r.Form, &spot); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := parseGeocacheForm(r.Form, &spot); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// The owner's tag list replaces every tag of the spot
+		var tags []string
+		values, replaceTags := r.Form["tags"]
+		if replaceTags {
+			if tags, err = models.ParseTags(values...); err != nil {
+				tagFormError(w, err)
+				return
+			}
+		}
+
+		if _, ok := r.Form["latitude"]; ok {
+			spot.Latitude, err = strconv.ParseFloat(r.FormValue("latitude"), 64)
+			if err != nil || spot.Latitude < -90 || spot.Latitude > 90 {
+				http.Error(w, "Invalid latitude", http.StatusBadRequest)
+				return
+			}
+		}
+		if _, ok := r.Form["longitude"]; ok {
+			spot.Longitude, err = strconv.ParseFloat(r.FormValue("longitude"), 64)
+			if err != nil || spot.Longitude < -180 || spot.Longitude > 180 {
+				http.Error(w, "Invalid longitude", http.StatusBadRequest)
+				return
+			}
+		}
+		if altitudeStr, ok := r.Form["altitude"]; ok {
+			spot.Altitude = 0
+			if altitudeStr[0] != "" {
+				spot.Altitude, err = strconv.ParseFloat(altitudeStr[0], 64)
+				if err != nil {
+					http.Error(w, "Invalid altitude", http.StatusBadRequest)
+					return
+				}
+			}
+		}
+
+		// Save replacement images; the old files are only removed once the
+		// database points at the new ones.
+		var orphaned, saved []*string
+		var slotPhotos []models.SpotPhoto
+		for _, field := range []struct {
+			name      string
+			image     **string
+			timeOfDay models.TimeOfDay
+		}{
+			{"day_image", &spot.DayImage, models.Day},
+			{"night_image", &spot.NightImage, models.Night},
+		} {
+			processed, err := readImage(r, field.name)
+			if err == nil && processed == nil {
+				continue
+			}
+			var path string
+			if err == nil {
+				path, err = storeImage(r.Context(), store, processed)
+			}
+			if err != nil {
+				for _, p := range saved {
+					deleteImage(store, p)
+				}
+				imageUploadError(w, field.name, err)
+				return
+			}
+			orphaned = append(orphaned, *field.image)
+			*field.image = &path
+			saved = append(saved, &path)
+
+			meta := processed.Metadata()
+			if field.timeOfDay == models.Night {
+				spot.NightImageAfterSunset = nil
+				if dark, ok := takenInDark(meta, spot.Latitude, spot.Longitude); ok {
+					spot.NightImageAfterSunset = &dark
+				}
+			}
+
+			photo := models.SpotPhoto{
+				Filename:   path,
+				TimeOfDay:  field.timeOfDay,
+				CapturedAt: captureTime(meta, spot.Longitude),
+			}
+			photo.SetHash(processed.Hash)
+			slotPhotos = append(slotPhotos, photo)
+		}
+
+		spot.UpdatedAt = time.Now()
+		err = db.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("User", "Category").Save(&spot).Error; err != nil {
+				return err
+			}
+			if replaceTags {
+				if err := setSpotTags(tx, spot.ID, spot.UserID, tags); err != nil {
+					return err
+				}
+			}
+			for i, photo := range slotPhotos {
+				if err := addSlotPhoto(tx, &spot, photo, orphaned[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			for _, p := range saved {
+				deleteImage(store, p)
+			}
+			if errors.Is(err, errTooManyTags) {
+				tagFormError(w, err)
+				return
+			}
 			http.Error(w, "Failed to update spot", http.StatusInternalServerError)
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
+		for _, p := range orphaned {
+			deleteUnreferencedImage(db, store, p)
+		}
+		pruneSpotRevisions(db, store, spot.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": "Spot updated",
+			"spot":    spot,
+		})
+	}
+}
+
+// DeleteSpotHandler removes the owner's spot with everything attached to it.
+// Its visitors lose the XP it earned them and any visit badge that goes with it.
+func DeleteSpotHandler(db *gorm.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		if !loadOwnedSpot(db, w, r, &spot) {
+			return
+		}
+
+		// Every image the spot ever had is kept alive by its revisions and photos
+		var revisions []models.SpotRevision
+		if err := db.Where("spot_id = ?", spot.ID).Find(&revisions).Error; err != nil {
+			http.Error(w, "Failed to delete spot", http.StatusInternalServerError)
+			return
+		}
+		var photos []models.SpotPhoto
+		if err := db.Where("spot_id = ?", spot.ID).Find(&photos).Error; err != nil {
+			http.Error(w, "Failed to delete spot", http.StatusInternalServerError)
+			return
+		}
+		// So are the photos in its visitors' journals
+		visits := db.Model(&models.VisitedSpot{}).Select("id").Where("spot_id = ?", spot.ID)
+		var visitPhotos []models.VisitPhoto
+		if err := db.Where("visit_id IN (?)", visits).Find(&visitPhotos).Error; err != nil {
+			http.Error(w, "Failed to delete spot", http.StatusInternalServerError)
+			return
+		}
+
+		// Remove everything that references the spot before the spot itself
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Holds off check-ins and discoveries, which reference the spot
+			if err := tx.Exec("SELECT 1 FROM spots WHERE id = ? FOR UPDATE", spot.ID).Error; err != nil {
+				return err
+			}
+
+			// Visitors lose what the spot earned them: the XP of their visits,
+			// the discovery bonus and the visit badges they no longer reach
+			var earnings []struct {
+				UserID uuid.UUID
+				XP     int
+			}
+			if err := tx.Raw(`SELECT user_id, SUM(xp) AS xp FROM (
+					SELECT user_id, xp_awarded AS xp FROM visited_spots WHERE spot_id = ?
+					UNION ALL
+					SELECT user_id, xp_gained FROM spot_discoveries WHERE spot_id = ?
+				) earned
+				GROUP BY user_id
+				ORDER BY user_id`, spot.ID, spot.ID).Scan(&earnings).Error; err != nil {
+				return err
+			}
+
+			visits := tx.Model(&models.VisitedSpot{}).Select("id").Where("spot_id = ?", spot.ID)
+			for _, model := range []any{&models.VisitPhoto{}, &models.VisitCompanion{}, &models.VisitFlag{}} {
+				if err := tx.Where("visit_id IN (?)", visits).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Where("spot_id = ?", spot.ID).Delete(&models.VisitedSpot{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("spot_id = ?", spot.ID).Delete(&models.Like{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("spot_id = ?", spot.ID).Delete(&models.Review{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM user_favorites WHERE spot_id = ?", spot.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("spot_id = ?", spot.ID).Delete(&models.SpotRevision{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("spot_id = ?", spot.ID).Delete(&models.SpotPhoto{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("spot_id = ?", spot.ID).Delete(&models.SpotTag{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("spot_id = ?", spot.ID).Delete(&models.AttributeSuggestion{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("spot_id = ?", spot.ID).Delete(&models.SpotDiscovery{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&spot).Error; err != nil {
+				return err
+			}
+
+			for _, earned := range earnings {
+				if earned.XP != 0 {
+					if err := tx.Model(&models.User{}).
+						Where("id = ?", earned.UserID).
+						Update("xp", gorm.Expr("xp - ?", earned.XP)).Error; err != nil {
+						return err
+					}
+				}
+				if _, err := revokeVisitBadges(tx, earned.UserID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(w, "Failed to delete spot", http.StatusInternalServerError)
+			return
+		}
+
+		deleteImage(store, spot.DayImage)
+		deleteImage(store, spot.NightImage)
+		for _, revision := range revisions {
+			deleteImage(store, revision.DayImage)
+			deleteImage(store, revision.NightImage)
+		}
+		for _, photo := range photos {
+			deleteImage(store, &photo.Filename)
+		}
+		for _, photo := range visitPhotos {
+			deleteImage(store, &photo.Filename)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Spot deleted"})
 	}
 }
@@ -0,0 +1,457 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"chillspot-backend/internal/models"
+	"chillspot-backend/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxCaptionLength = 500
+
+type ReorderSpotPhotosInput struct {
+	PhotoIDs []uuid.UUID `json:"photo_ids"`
+}
+
+// nextPhotoPosition is the position that puts a new photo last in the gallery
+func nextPhotoPosition(tx *gorm.DB, spotID uuid.UUID) (int, error) {
+	var position int
+	err := tx.Model(&models.SpotPhoto{}).
+		Select("COALESCE(MAX(position), -1) + 1").
+		Where("spot_id = ?", spotID).
+		Scan(&position).Error
+	return position, err
+}
+
+// addSlotPhoto adds an image uploaded into the spot's DayImage or NightImage
+// slot to the gallery; photo only needs its file fields and TimeOfDay set.
+// When it replaces the cover photo's image it becomes the new cover.
+func addSlotPhoto(tx *gorm.DB, spot *models.Spot, photo models.SpotPhoto, replaced *string) error {
+	position, err := nextPhotoPosition(tx, spot.ID)
+	if err != nil {
+		return err
+	}
+
+	photo.SpotID = spot.ID
+	photo.UserID = spot.UserID
+	photo.Position = position
+
+	var covers int64
+	if err := tx.Model(&models.SpotPhoto{}).Where("spot_id = ? AND is_cover", spot.ID).Count(&covers).Error; err != nil {
+		return err
+	}
+	if replaced != nil {
+		result := tx.Model(&models.SpotPhoto{}).
+			Where("spot_id = ? AND filename = ? AND is_cover", spot.ID, *replaced).
+			Update("is_cover", false)
+		if result.Error != nil {
+			return result.Error
+		}
+		covers -= result.RowsAffected
+	}
+	photo.IsCover = covers == 0
+
+	return tx.Create(&photo).Error
+}
+
+// loadSpotPhoto fetches photo {photoId} of spot {id}, writing the error
+// response on failure.
+func loadSpotPhoto(db *gorm.DB, w http.ResponseWriter, r *http.Request, photo *models.SpotPhoto) bool {
+	vars := mux.Vars(r)
+
+	spotUUID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid spot ID", http.StatusBadRequest)
+		return false
+	}
+
+	photoUUID, err := uuid.Parse(vars["photoId"])
+	if err != nil {
+		http.Error(w, "Invalid photo ID", http.StatusBadRequest)
+		return false
+	}
+
+	if err := db.Where("id = ? AND spot_id = ?", photoUUID, spotUUID).First(photo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Photo not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
+
+// UploadSpotPhotoHandler adds a photo to the gallery. The spot owner and
+// anyone who has visited the spot may contribute.
+func UploadSpotPhotoHandler(db *gorm.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		userUUID, ok := loadVisibleSpot(db, w, r, "id", &spot)
+		if !ok {
+			return
+		}
+
+		if spot.UserID != userUUID {
+			var visits int64
+			if err := db.Model(&models.VisitedSpot{}).
+				Where("user_id = ? AND spot_id = ? AND verified", userUUID, spot.ID).
+				Count(&visits).Error; err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			if visits == 0 {
+				http.Error(w, "Only visitors of this spot can upload photos", http.StatusForbidden)
+				return
+			}
+		}
+
+		if err := r.ParseMultipartForm(32 << 20); err != nil { // 32 MB max
+			http.Error(w, "Failed to parse form data", http.StatusBadRequest)
+			return
+		}
+
+		photo := models.SpotPhoto{
+			SpotID:    spot.ID,
+			UserID:    userUUID,
+			Caption:   r.FormValue("caption"),
+			Season:    models.Season(r.FormValue("season")),
+			TimeOfDay: models.TimeOfDay(r.FormValue("time_of_day")),
+		}
+
+		if utf8.RuneCountInString(photo.Caption) > maxCaptionLength {
+			http.Error(w, "Caption is too long", http.StatusBadRequest)
+			return
+		}
+		if photo.Season != "" && !models.ValidSeason(photo.Season) {
+			http.Error(w, "Invalid season", http.StatusBadRequest)
+			return
+		}
+		if photo.TimeOfDay != "" && !models.ValidTimeOfDay(photo.TimeOfDay) {
+			http.Error(w, "Invalid time of day", http.StatusBadRequest)
+			return
+		}
+		if capturedAtStr := r.FormValue("captured_at"); capturedAtStr != "" {
+			capturedAt, err := time.Parse(time.RFC3339, capturedAtStr)
+			if err != nil {
+				http.Error(w, "Invalid captured_at, expected RFC 3339", http.StatusBadRequest)
+				return
+			}
+			photo.CapturedAt = &capturedAt
+		}
+
+		processed, err := readImage(r, "photo")
+		if err != nil {
+			imageUploadError(w, "photo", err)
+			return
+		}
+		if processed == nil {
+			http.Error(w, "Missing photo", http.StatusBadRequest)
+			return
+		}
+		photo.SetHash(processed.Hash)
+		if photo.CapturedAt == nil {
+			photo.CapturedAt = captureTime(processed.Metadata(), spot.Longitude)
+		}
+
+		photo.Filename, err = storeImage(r.Context(), store, processed)
+		if err != nil {
+			imageUploadError(w, "photo", err)
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// Serialises concurrent uploads so positions stay unique
+			if err := tx.Exec("SELECT 1 FROM spots WHERE id = ? FOR UPDATE", spot.ID).Error; err != nil {
+				return err
+			}
+			photo.Position, err = nextPhotoPosition(tx, spot.ID)
+			if err != nil {
+				return err
+			}
+			return tx.Create(&photo).Error
+		})
+		if err != nil {
+			deleteImage(store, &photo.Filename)
+			http.Error(w, "Failed to save photo", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": "Photo uploaded",
+			"photo":   photo,
+		})
+	}
+}
+
+// GetSpotPhotosHandler lists the gallery in display order
+func GetSpotPhotosHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		viewerID, ok := loadVisibleSpot(db, w, r, "id", &spot)
+		if !ok || !requireDiscovered(db, w, viewerID, &spot) {
+			return
+		}
+
+		page, err := parsePageRequest(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tx, err := applyKeyset[int](db.Where("spot_id = ?", spot.ID), page, "position", "id", false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var photos []models.SpotPhoto
+		if err := tx.Find(&photos).Error; err != nil {
+			http.Error(w, "Failed to fetch photos", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newPage(photos, page, "position", func(photo models.SpotPhoto) (any, uuid.UUID) {
+			return photo.Position, photo.ID
+		}))
+	}
+}
+
+// DeleteSpotPhotoHandler lets the uploader remove their photo. A photo that
+// is featured as the day or night image is taken out of that slot as well,
+// and a deleted cover hands over to the next one picked by nextCover.
+func DeleteSpotPhotoHandler(db *gorm.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var photo models.SpotPhoto
+		if !loadSpotPhoto(db, w, r, &photo) {
+			return
+		}
+
+		if photo.UserID.String() != userID {
+			http.Error(w, "You can only delete your own photos", http.StatusForbidden)
+			return
+		}
+
+		err := db.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+			// Serialises changes to the spot's cover
+			var spot models.Spot
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", photo.SpotID).First(&spot).Error; err != nil {
+				return err
+			}
+
+			// Returning reloads the photo as deleted, cover flag included
+			result := tx.Clauses(clause.Returning{}).Delete(&photo)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+
+			featured := false
+			if spot.DayImage != nil && *spot.DayImage == photo.Filename {
+				spot.DayImage = nil
+				featured = true
+			}
+			if spot.NightImage != nil && *spot.NightImage == photo.Filename {
+				spot.NightImage = nil
+				featured = true
+			}
+			if photo.IsCover {
+				var photos []models.SpotPhoto
+				if err := tx.Where("spot_id = ?", spot.ID).Order("position, id").Find(&photos).Error; err != nil {
+					return err
+				}
+				if next := nextCover(&spot, photos); next != nil {
+					if err := setCover(tx, &spot, next); err != nil {
+						return err
+					}
+					featured = true
+				}
+			}
+			if !featured {
+				return nil
+			}
+			spot.UpdatedAt = time.Now()
+			return tx.Omit("User").Save(&spot).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Photo not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to delete photo", http.StatusInternalServerError)
+			return
+		}
+
+		deleteUnreferencedImage(db, store, &photo.Filename)
+		pruneSpotRevisions(db, store, photo.SpotID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Photo deleted"})
+	}
+}
+
+// setCover makes photo the spot's only cover and mirrors it into its slot:
+// night photos into NightImage, all others into DayImage. The caller saves
+// the spot. It fails with gorm.ErrRecordNotFound if the photo is gone.
+func setCover(tx *gorm.DB, spot *models.Spot, photo *models.SpotPhoto) error {
+	if err := tx.Model(&models.SpotPhoto{}).
+		Where("spot_id = ? AND id <> ? AND is_cover", spot.ID, photo.ID).
+		Update("is_cover", false).Error; err != nil {
+		return err
+	}
+	result := tx.Model(&models.SpotPhoto{}).Where("id = ?", photo.ID).Update("is_cover", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	photo.IsCover = true
+
+	filename := photo.Filename
+	if photo.IsNight() {
+		spot.NightImage = &filename
+	} else {
+		spot.DayImage = &filename
+	}
+	return nil
+}
+
+// nextCover picks the photo that takes over from a deleted cover: the one
+// still shown as the day image, else the night image, else the first in the
+// gallery. photos are in gallery order; it returns nil when there are none.
+func nextCover(spot *models.Spot, photos []models.SpotPhoto) *models.SpotPhoto {
+	for _, slot := range []*string{spot.DayImage, spot.NightImage} {
+		if slot == nil {
+			continue
+		}
+		for i := range photos {
+			if photos[i].Filename == *slot {
+				return &photos[i]
+			}
+		}
+	}
+	if len(photos) == 0 {
+		return nil
+	}
+	return &photos[0]
+}
+
+// FeatureSpotPhotoHandler makes the photo the spot's cover, mirrored into
+// its day or night slot by setCover.
+func FeatureSpotPhotoHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		if !loadOwnedSpot(db, w, r, &spot) {
+			return
+		}
+
+		var photo models.SpotPhoto
+		if !loadSpotPhoto(db, w, r, &photo) {
+			return
+		}
+
+		err := db.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+			// Serialises changes to the spot's cover, and saves the spot as
+			// it is now rather than as it was loaded
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", spot.ID).First(&spot).Error; err != nil {
+				return err
+			}
+			if err := setCover(tx, &spot, &photo); err != nil {
+				return err
+			}
+			spot.UpdatedAt = time.Now()
+			return tx.Omit("User").Save(&spot).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Photo not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to feature photo", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": "Photo featured",
+			"photo":   photo,
+			"spot":    spot,
+		})
+	}
+}
+
+// ReorderSpotPhotosHandler sets the gallery order. The owner must list every
+// photo of the spot exactly once.
+func ReorderSpotPhotosHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		if !loadOwnedSpot(db, w, r, &spot) {
+			return
+		}
+
+		var input ReorderSpotPhotosInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var photoIDs []uuid.UUID
+		if err := db.Model(&models.SpotPhoto{}).Where("spot_id = ?", spot.ID).Pluck("id", &photoIDs).Error; err != nil {
+			http.Error(w, "Failed to fetch photos", http.StatusInternalServerError)
+			return
+		}
+
+		remaining := make(map[uuid.UUID]bool, len(photoIDs))
+		for _, id := range photoIDs {
+			remaining[id] = true
+		}
+		for _, id := range input.PhotoIDs {
+			if !remaining[id] {
+				http.Error(w, "photo_ids must list every photo of the spot once", http.StatusBadRequest)
+				return
+			}
+			delete(remaining, id)
+		}
+		if len(remaining) > 0 {
+			http.Error(w, "photo_ids must list every photo of the spot once", http.StatusBadRequest)
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for position, id := range input.PhotoIDs {
+				if err := tx.Model(&models.SpotPhoto{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(w, "Failed to reorder photos", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Photos reordered"})
+	}
+}
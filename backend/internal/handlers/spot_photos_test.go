@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestNextCover(t *testing.T) {
+	file := func(name string) *string { return &name }
+	gallery := []models.SpotPhoto{
+		{Filename: "first.jpg"},
+		{Filename: "night.jpg", TimeOfDay: models.Night},
+		{Filename: "day.jpg", TimeOfDay: models.Day},
+	}
+
+	tests := []struct {
+		name       string
+		day, night *string
+		photos     []models.SpotPhoto
+		want       string // "" for no cover
+	}{
+		{"day image", file("day.jpg"), file("night.jpg"), gallery, "day.jpg"},
+		{"night image once the day slot is empty", nil, file("night.jpg"), gallery, "night.jpg"},
+		{"first photo with empty slots", nil, nil, gallery, "first.jpg"},
+		// A slot can hold an image from before galleries that isn't a photo
+		{"slot image outside the gallery", file("legacy.jpg"), nil, gallery, "first.jpg"},
+		{"empty gallery", file("day.jpg"), nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spot := &models.Spot{DayImage: tt.day, NightImage: tt.night}
+			got := nextCover(spot, tt.photos)
+			switch {
+			case got == nil && tt.want != "":
+				t.Errorf("no cover, want %s", tt.want)
+			case got != nil && got.Filename != tt.want:
+				t.Errorf("cover %s, want %q", got.Filename, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetCover(t *testing.T) {
+	old := "old.jpg"
+	for _, timeOfDay := range []models.TimeOfDay{"", models.Day, models.Sunset, models.Night} {
+		t.Run(string(timeOfDay)+" photo", func(t *testing.T) {
+			stub := &stubDriver{}
+			spot := &models.Spot{ID: uuid.New(), DayImage: &old, NightImage: &old}
+			photo := &models.SpotPhoto{ID: uuid.New(), Filename: "new.jpg", TimeOfDay: timeOfDay}
+			if err := setCover(stubDB(t, stub), spot, photo); err != nil {
+				t.Fatal(err)
+			}
+			if !photo.IsCover {
+				t.Error("photo not marked as the cover")
+			}
+
+			// Clears the spot's other covers, then sets this one
+			if len(stub.queries) != 2 || stub.args[0][1] != spot.ID.String() || stub.args[0][2] != photo.ID.String() ||
+				stub.args[1][0] != true || stub.args[1][1] != photo.ID.String() {
+				t.Errorf("ran %q with %v", stub.queries, stub.args)
+			}
+
+			mirrored, other := spot.DayImage, spot.NightImage
+			if timeOfDay == models.Night {
+				mirrored, other = other, mirrored
+			}
+			if *mirrored != "new.jpg" || *other != old {
+				t.Errorf("day image %s, night image %s", *spot.DayImage, *spot.NightImage)
+			}
+		})
+	}
+}
+
+func TestSetCoverDeletedPhoto(t *testing.T) {
+	stub := &stubDriver{rowsAffected: []int64{0, 0}}
+	spot := &models.Spot{ID: uuid.New()}
+	photo := &models.SpotPhoto{ID: uuid.New(), Filename: "gone.jpg"}
+	if err := setCover(stubDB(t, stub), spot, photo); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("err = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	if spot.DayImage != nil {
+		t.Errorf("mirrored a deleted photo as %s", *spot.DayImage)
+	}
+}
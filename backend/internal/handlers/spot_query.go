@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"chillspot-backend/internal/geo"
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultNearbyRadius = 5000.0  // meters
+	maxNearbyRadius     = 50000.0 // meters
+)
+
+// Spot listing sort orders
+const (
+	SortNewest      = "newest"
+	SortMostLiked   = "most_liked"
+	SortMostVisited = "most_visited"
+	SortNearest     = "nearest"
+	SortRelevance   = "relevance"
+)
+
+// Haversine distance in meters between the spot row and the (?, ?, ?) = (lat, lat, lng) point
+const distanceSQL = `2 * 6371000 * ASIN(SQRT(
+	POWER(SIN(RADIANS(spots.latitude - ?) / 2), 2) +
+	COS(RADIANS(?)) * COS(RADIANS(spots.latitude)) *
+	POWER(SIN(RADIANS(spots.longitude - ?) / 2), 2)))`
+
+// SpotQuery is the filter, sort and paging state shared by every spot listing
+// endpoint. Handlers parse it from the request with ParseSpotQuery and narrow
+// it further (e.g. to the caller's friends) before calling Run.
+type SpotQuery struct {
+	ViewerID uuid.UUID
+
+	// Text search, see prefixTSQuery
+	Text string
+
+	// Location; Radius limits results when HasLocation is set
+	HasLocation bool
+	Lat, Lng    float64
+	Radius      float64
+
+	Weather      models.WeatherConditions
+	Category     string   // category slug
+	Tags         []string // spots must carry every tag
+	Attributes   AttributeFilter
+	MinAltitude  *float64
+	MaxAltitude  *float64
+	CreatedAfter *time.Time
+	MinFavorites *uint
+	MinVisits    *uint
+	Visited      *bool        // visited (or not) by the viewer
+	OwnerID      *uuid.UUID   // from the ?owner= param
+	OwnerIn      *[]uuid.UUID // set by endpoints that only list some owners
+
+	Sort string
+	Page PageRequest
+}
+
+type SpotListItem struct {
+	models.Spot
+	Rank     *float64 `json:"rank,omitempty"`     // only set for text searches
+	Distance *float64 `json:"distance,omitempty"` // in meters, only set around a location
+}
+
+type SpotListResponse struct {
+	Page[SpotListItem]
+	Facets map[string]int64 `json:"facets"` // spot count per weather condition
+}
+
+// parseLocation reads the lat, lng and optional radius query params
+func parseLocation(query url.Values) (lat, lng, radius float64, err error) {
+	lat, err = strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, 0, errors.New("Invalid latitude")
+	}
+
+	lng, err = strconv.ParseFloat(query.Get("lng"), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return 0, 0, 0, errors.New("Invalid longitude")
+	}
+
+	radius = defaultNearbyRadius
+	if radiusStr := query.Get("radius"); radiusStr != "" {
+		radius, err = strconv.ParseFloat(radiusStr, 64)
+		if err != nil || radius <= 0 {
+			return 0, 0, 0, errors.New("Invalid radius")
+		}
+		if radius > maxNearbyRadius {
+			radius = maxNearbyRadius
+		}
+	}
+	return lat, lng, radius, nil
+}
+
+func parseOptionalFloat(query url.Values, name string) (*float64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, errors.New("Invalid " + name)
+	}
+	return &f, nil
+}
+
+func parseOptionalUint(query url.Values, name string) (*uint, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, errors.New("Invalid " + name)
+	}
+	u := uint(n)
+	return &u, nil
+}
+
+// prefixTSQuery turns free text into a tsquery where every word may be the
+// start of a longer one ("sun lak" -> "sun:* & lak:*"). Punctuation is
+// dropped so user input can never produce tsquery syntax errors.
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = word + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// geohashPrefixFilter narrows a spots query to the geohash cells covering the circle
+func geohashPrefixFilter(db *gorm.DB, lat, lng, radius float64) *gorm.DB {
+	precision := geo.PrecisionForRadius(lat, radius)
+	cells := geo.CoveringCells(lat, lng, precision)
+
+	filter := db.Where("spots.geohash LIKE ?", cells[0]+"%")
+	for _, cell := range cells[1:] {
+		filter = filter.Or("spots.geohash LIKE ?", cell+"%")
+	}
+	return filter
+}
+
+// parseTaxonomy reads the category slug and tag query params, which every
+// spot listing accepts
+func parseTaxonomy(query url.Values) (category string, tags []string, err error) {
+	category = strings.ToLower(strings.TrimSpace(query.Get("category")))
+	if category != "" && !models.ValidCategorySlug(category) {
+		return "", nil, errors.New("Invalid category")
+	}
+
+	if tags, err = models.ParseTags(query["tag"]...); err != nil {
+		return "", nil, errors.New("Invalid tag")
+	}
+	return category, tags, nil
+}
+
+// taxonomyFilter narrows a spots query to a category and to spots carrying
+// all of tags. Empty values don't filter.
+func taxonomyFilter(tx *gorm.DB, category string, tags []string) *gorm.DB {
+	if category != "" {
+		tx = tx.Where("spots.category_id IN (SELECT id FROM categories WHERE slug = ?)", category)
+	}
+	if len(tags) > 0 {
+		tx = tx.Where(`spots.id IN (
+			SELECT spot_tags.spot_id FROM spot_tags JOIN tags ON tags.id = spot_tags.tag_id
+			WHERE tags.name IN ? GROUP BY spot_tags.spot_id HAVING COUNT(*) = ?)`, tags, len(tags))
+	}
+	return tx
+}
+
+// ParseSpotQuery reads the common listing params:
+//
+//	q, lat, lng, radius, weather (comma separated), category,
+//	tag (comma separated or repeated), the attribute filters (see
+//	parseAttributeFilter), min_altitude, max_altitude,
+//	created_after (RFC 3339 or YYYY-MM-DD), min_favorites, min_visits,
+//	visited (true/false), owner, sort, limit, cursor
+func ParseSpotQuery(r *http.Request, viewerID uuid.UUID) (SpotQuery, error) {
+	query := r.URL.Query()
+	q := SpotQuery{ViewerID: viewerID}
+	var err error
+
+	// Text without any searchable word is ignored
+	q.Text = strings.TrimSpace(query.Get("q"))
+	if prefixTSQuery(q.Text) == "" {
+		q.Text = ""
+	}
+
+	if query.Get("lat") != "" || query.Get("lng") != "" {
+		q.Lat, q.Lng, q.Radius, err = parseLocation(query)
+		if err != nil {
+			return q, err
+		}
+		q.HasLocation = true
+	}
+
+	if q.Weather, err = models.ParseWeatherConditions(query.Get("weather")); err != nil {
+		return q, errors.New("Invalid weather")
+	}
+
+	if q.Category, q.Tags, err = parseTaxonomy(query); err != nil {
+		return q, err
+	}
+
+	if q.Attributes, err = parseAttributeFilter(query); err != nil {
+		return q, err
+	}
+
+	if q.MinAltitude, err = parseOptionalFloat(query, "min_altitude"); err != nil {
+		return q, err
+	}
+	if q.MaxAltitude, err = parseOptionalFloat(query, "max_altitude"); err != nil {
+		return q, err
+	}
+
+	if createdAfter := query.Get("created_after"); createdAfter != "" {
+		t, err := time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", createdAfter)
+			if err != nil {
+				return q, errors.New("Invalid created_after")
+			}
+		}
+		q.CreatedAfter = &t
+	}
+
+	if q.MinFavorites, err = parseOptionalUint(query, "min_favorites"); err != nil {
+		return q, err
+	}
+	if q.MinVisits, err = parseOptionalUint(query, "min_visits"); err != nil {
+		return q, err
+	}
+
+	if visitedStr := query.Get("visited"); visitedStr != "" {
+		visited, err := strconv.ParseBool(visitedStr)
+		if err != nil {
+			return q, errors.New("Invalid visited")
+		}
+		q.Visited = &visited
+	}
+
+	if ownerStr := query.Get("owner"); ownerStr != "" {
+		ownerID, err := uuid.Parse(ownerStr)
+		if err != nil {
+			return q, errors.New("Invalid owner")
+		}
+		q.OwnerID = &ownerID
+	}
+
+	q.Sort = query.Get("sort")
+	switch q.Sort {
+	case "":
+	case SortNewest, SortMostLiked, SortMostVisited:
+	case SortNearest:
+		if !q.HasLocation {
+			return q, errors.New("Sorting by nearest requires lat and lng")
+		}
+	case SortRelevance:
+		if q.Text == "" {
+			return q, errors.New("Sorting by relevance requires q")
+		}
+	default:
+		return q, errors.New("Invalid sort")
+	}
+
+	if q.Page, err = parsePageRequest(query); err != nil {
+		return q, err
+	}
+
+	// A cursor is only valid for the sort it was issued for
+	if q.Page.Cursor != nil {
+		column, _ := q.sortKey()
+		if q.Page.Cursor.Column != column {
+			return q, errInvalidCursor
+		}
+
+		var key any = new(float64)
+		if column == "created_at" {
+			key = new(time.Time)
+		}
+		if err := json.Unmarshal(q.Page.Cursor.Key, key); err != nil {
+			return q, errInvalidCursor
+		}
+	}
+	return q, nil
+}
+
+// filters applies every condition on the spots table. Facets skip the
+// weather filter so clients can show counts for the other conditions too.
+func (q *SpotQuery) filters(db *gorm.DB, withWeather bool) *gorm.DB {
+	tx := db.Model(&models.Spot{}).Where(visibleSpots(db, q.ViewerID, true))
+
+	if q.Text != "" {
+		// Hidden titles must not be found by guessing them
+		tx = tx.Where("(spots.search_vector @@ to_tsquery('simple', ?) OR spots.title % ?)", prefixTSQuery(q.Text), q.Text).
+			Where(discoveredSQL, q.ViewerID, q.ViewerID)
+	}
+	if q.HasLocation {
+		tx = tx.Where(viewerAreaFilter(db, q.ViewerID, q.Lat, q.Lng, q.Radius))
+	}
+	if withWeather && len(q.Weather) > 0 {
+		// Spots good in any weather match every condition
+		tx = tx.Where("(spots.recommended_weathers && ?::text[] OR ? = ANY(spots.recommended_weathers))",
+			q.Weather, models.AnyWeather)
+	}
+	tx = taxonomyFilter(tx, q.Category, q.Tags)
+	tx = q.Attributes.apply(tx)
+	if q.MinAltitude != nil {
+		tx = tx.Where("spots.altitude >= ?", *q.MinAltitude)
+	}
+	if q.MaxAltitude != nil {
+		tx = tx.Where("spots.altitude <= ?", *q.MaxAltitude)
+	}
+	if q.CreatedAfter != nil {
+		tx = tx.Where("spots.created_at > ?", *q.CreatedAfter)
+	}
+	if q.MinFavorites != nil {
+		tx = tx.Where("spots.favorites_count >= ?", *q.MinFavorites)
+	}
+	if q.MinVisits != nil {
+		tx = tx.Where("spots.visit_count >= ?", *q.MinVisits)
+	}
+	if q.Visited != nil {
+		visitedSQL := "EXISTS (SELECT 1 FROM visited_spots WHERE visited_spots.spot_id = spots.id AND visited_spots.user_id = ? AND visited_spots.verified)"
+		if !*q.Visited {
+			visitedSQL = "NOT " + visitedSQL
+		}
+		tx = tx.Where(visitedSQL, q.ViewerID)
+	}
+	if q.OwnerID != nil {
+		tx = tx.Where("spots.user_id = ?", *q.OwnerID)
+	}
+	if q.OwnerIn != nil {
+		tx = tx.Where("spots.user_id IN ?", *q.OwnerIn)
+	}
+	return tx
+}
+
+// candidates selects the filtered spots with their rank and distance columns
+// as a subquery, so rank and distance can be filtered and sorted on.
+func (q *SpotQuery) candidates(db *gorm.DB, withWeather bool) *gorm.DB {
+	selectSQL := "spots.*"
+	var selectArgs []any
+
+	if q.Text != "" {
+		selectSQL += ", ts_rank_cd(spots.search_vector, to_tsquery('simple', ?)) + similarity(spots.title, ?) AS rank"
+		selectArgs = append(selectArgs, prefixTSQuery(q.Text), q.Text)
+	} else {
+		selectSQL += ", NULL::double precision AS rank"
+	}
+
+	if q.HasLocation {
+		distance, distanceArgs := viewerDistanceSQL(q.ViewerID, q.Lat, q.Lng)
+		selectSQL += ", " + distance + " AS distance"
+		selectArgs = append(selectArgs, distanceArgs...)
+	} else {
+		selectSQL += ", NULL::double precision AS distance"
+	}
+
+	tx := db.Table("(?) AS candidates", q.filters(db, withWeather).Select(selectSQL, selectArgs...))
+	if q.HasLocation {
+		tx = tx.Where("distance <= ?", q.Radius)
+	}
+	return tx
+}
+
+// sortKey returns the column a listing is ordered by and whether it's descending
+func (q *SpotQuery) sortKey() (string, bool) {
+	sort := q.Sort
+	if sort == "" {
+		switch {
+		case q.Text != "":
+			sort = SortRelevance
+		case q.HasLocation:
+			sort = SortNearest
+		default:
+			sort = SortNewest
+		}
+	}
+
+	switch sort {
+	case SortMostLiked:
+		return "favorites_count", true
+	case SortMostVisited:
+		return "visit_count", true
+	case SortNearest:
+		return "distance", false
+	case SortRelevance:
+		return "rank", true
+	default:
+		return "created_at", true
+	}
+}
+
+// Facets counts the matching spots per weather condition
+func (q *SpotQuery) Facets(db *gorm.DB) (map[string]int64, error) {
+	var rows []struct {
+		Condition string
+		Count     int64
+	}
+	err := q.candidates(db, false).
+		Joins("CROSS JOIN unnest(candidates.recommended_weathers) AS w(condition)").
+		Select("w.condition, COUNT(*) AS count").
+		Group("w.condition").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	facets := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		facets[row.Condition] = row.Count
+	}
+	return facets, nil
+}
+
+// Run fetches one page of spots together with the weather facets
+func (q *SpotQuery) Run(db *gorm.DB) (SpotListResponse, error) {
+	var response SpotListResponse
+
+	column, desc := q.sortKey()
+	var (
+		tx  *gorm.DB
+		err error
+	)
+	if column == "created_at" {
+		tx, err = applyKeyset[time.Time](q.candidates(db, true), q.Page, column, "id", desc)
+	} else {
+		tx, err = applyKeyset[float64](q.candidates(db, true), q.Page, column, "id", desc)
+	}
+	if err != nil {
+		return response, err
+	}
+
+	var spots []SpotListItem
+	if err := tx.Scan(&spots).Error; err != nil {
+		return response, err
+	}
+	masked := make([]*models.Spot, len(spots))
+	for i := range spots {
+		spots[i].FillImageURLs()
+		masked[i] = &spots[i].Spot
+	}
+	if err := maskSpots(db, q.ViewerID, masked...); err != nil {
+		return response, err
+	}
+
+	response.Page = newPage(spots, q.Page, column, func(spot SpotListItem) (any, uuid.UUID) {
+		switch column {
+		case "favorites_count":
+			return spot.FavoritesCount, spot.ID
+		case "visit_count":
+			return spot.VisitCount, spot.ID
+		case "distance":
+			return *spot.Distance, spot.ID
+		case "rank":
+			return *spot.Rank, spot.ID
+		default:
+			return spot.CreatedAt, spot.ID
+		}
+	})
+
+	facets, err := q.Facets(db)
+	if err != nil {
+		return response, err
+	}
+	response.Facets = facets
+
+	return response, nil
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chillspot-backend/internal/models"
+	"chillspot-backend/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Default for SPOT_REVISION_LIMIT: the full history is kept
+const defaultSpotRevisionLimit = 0
+
+// spotRevisionLimit is how many of a spot's latest revisions are kept, read
+// from SPOT_REVISION_LIMIT. Zero or less keeps every revision.
+func spotRevisionLimit() int {
+	return envInt("SPOT_REVISION_LIMIT", defaultSpotRevisionLimit)
+}
+
+type SpotRevisionSummary struct {
+	models.SpotRevision
+	ChangedFields []string `json:"changed_fields"`
+}
+
+// SpotRevisionPage is a page of a spot's history. RevisionLimit tells clients
+// how many revisions are kept, so they know older ones may be gone; it is nil
+// when the full history is kept.
+type SpotRevisionPage struct {
+	Page[SpotRevisionSummary]
+	RevisionLimit *int `json:"revision_limit"`
+}
+
+type SpotRevisionDiffResponse struct {
+	SpotID  uuid.UUID            `json:"spot_id"`
+	From    *int                 `json:"from"` // nil when diffing the first revision
+	To      int                  `json:"to"`
+	Changes []models.FieldChange `json:"changes"`
+}
+
+// findRevision loads revision number rev of the spot, writing the error response on failure
+func findRevision(db *gorm.DB, w http.ResponseWriter, spotID string, rev int) (*models.SpotRevision, bool) {
+	var revision models.SpotRevision
+	if err := db.Where("spot_id = ? AND number = ?", spotID, rev).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return &revision, true
+}
+
+// loadHistorySpot fetches the spot named by the {id} route variable if the
+// caller may see its history. Revisions hold exact positions, so the history
+// of a fuzzed or geocache spot stays hidden until its location is revealed.
+func loadHistorySpot(db *gorm.DB, w http.ResponseWriter, r *http.Request, spot *models.Spot) bool {
+	viewerID, ok := loadVisibleSpot(db, w, r, "id", spot)
+	if !ok {
+		return false
+	}
+	if !revealsLocation(db, viewerID, spot) {
+		http.Error(w, "The history of this spot is hidden until you visit it", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func GetSpotRevisionsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		if !loadHistorySpot(db, w, r, &spot) {
+			return
+		}
+		spotUUID := spot.ID
+
+		page, err := parsePageRequest(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tx, err := applyKeyset[int](db.Where("spot_id = ?", spotUUID), page, "number", "id", true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var revisions []models.SpotRevision
+		if err := tx.Find(&revisions).Error; err != nil {
+			http.Error(w, "Failed to fetch revisions", http.StatusInternalServerError)
+			return
+		}
+
+		revisionPage := newPage(revisions, page, "number", func(revision models.SpotRevision) (any, uuid.UUID) {
+			return revision.Number, revision.ID
+		})
+		revisions = revisionPage.Items
+
+		// The oldest revision on the page is diffed against one from the next page
+		var older *models.SpotRevision
+		if n := len(revisions); n > 0 && revisions[n-1].Number > 1 {
+			var revision models.SpotRevision
+			if err := db.Where("spot_id = ? AND number = ?", spotUUID, revisions[n-1].Number-1).First(&revision).Error; err == nil {
+				older = &revision
+			}
+		}
+
+		// Newest first, each listing what it changed relative to the one before
+		response := SpotRevisionPage{Page: Page[SpotRevisionSummary]{
+			Items:      make([]SpotRevisionSummary, len(revisions)),
+			NextCursor: revisionPage.NextCursor,
+		}}
+		if limit := spotRevisionLimit(); limit > 0 {
+			response.RevisionLimit = &limit
+		}
+		for i := range revisions {
+			prev := older
+			if i+1 < len(revisions) {
+				prev = &revisions[i+1]
+			}
+
+			changed := []string{}
+			for _, change := range revisions[i].Diff(prev) {
+				changed = append(changed, change.Field)
+			}
+
+			response.Items[i] = SpotRevisionSummary{
+				SpotRevision:  revisions[i],
+				ChangedFields: changed,
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// GetSpotRevisionDiffHandler diffs revision {rev} against the revision given
+// by ?against=, or against the revision right before it by default.
+func GetSpotRevisionDiffHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		if !loadHistorySpot(db, w, r, &spot) {
+			return
+		}
+		vars := mux.Vars(r)
+		spotID := spot.ID.String()
+
+		rev, err := strconv.Atoi(vars["rev"])
+		if err != nil {
+			http.Error(w, "Invalid revision", http.StatusBadRequest)
+			return
+		}
+
+		revision, ok := findRevision(db, w, spotID, rev)
+		if !ok {
+			return
+		}
+
+		against := rev - 1
+		if againstStr := r.URL.Query().Get("against"); againstStr != "" {
+			against, err = strconv.Atoi(againstStr)
+			if err != nil {
+				http.Error(w, "Invalid against revision", http.StatusBadRequest)
+				return
+			}
+		}
+
+		response := SpotRevisionDiffResponse{
+			SpotID: revision.SpotID,
+			To:     revision.Number,
+		}
+
+		var base *models.SpotRevision
+		if against > 0 {
+			base, ok = findRevision(db, w, spotID, against)
+			if !ok {
+				return
+			}
+			response.From = &base.Number
+		}
+		response.Changes = revision.Diff(base)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// RevertSpotRevisionHandler restores the spot to revision {rev}. The revert
+// itself is recorded as a new revision, so it can be undone as well.
+func RevertSpotRevisionHandler(db *gorm.DB, store storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		if !loadOwnedSpot(db, w, r, &spot) {
+			return
+		}
+
+		rev, err := strconv.Atoi(mux.Vars(r)["rev"])
+		if err != nil {
+			http.Error(w, "Invalid revision", http.StatusBadRequest)
+			return
+		}
+
+		revision, ok := findRevision(db, w, spot.ID.String(), rev)
+		if !ok {
+			return
+		}
+
+		revision.ApplyTo(&spot)
+		spot.UpdatedAt = time.Now()
+
+		// The revision's category may have been deleted since
+		if spot.CategoryID != nil {
+			var exists int64
+			db.Model(&models.Category{}).Where("id = ?", *spot.CategoryID).Count(&exists)
+			if exists == 0 {
+				spot.CategoryID = nil
+			}
+		}
+
+		if err := db.WithContext(r.Context()).Omit("User", "Category").Save(&spot).Error; err != nil {
+			http.Error(w, "Failed to revert spot", http.StatusInternalServerError)
+			return
+		}
+		pruneSpotRevisions(db, store, spot.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": "Spot reverted",
+			"spot":    spot,
+		})
+	}
+}
+
+// pruneSpotRevisions drops the spot's revisions beyond the latest
+// spotRevisionLimit and deletes the images only they still used. The
+// handlers that change a spot's images run it; revisions pushed out by other
+// writes go on the next such change. Without a limit nothing is pruned.
+func pruneSpotRevisions(db *gorm.DB, store storage.Storage, spotID uuid.UUID) {
+	limit := spotRevisionLimit()
+	if limit <= 0 {
+		return
+	}
+
+	var pruned []models.SpotRevision
+	if err := db.Clauses(clause.Returning{}).
+		Where("spot_id = ? AND number <= (SELECT MAX(number) FROM spot_revisions WHERE spot_id = ?) - ?",
+			spotID, spotID, limit).
+		Delete(&pruned).Error; err != nil {
+		log.Printf("Failed to prune revisions of spot %s: %v", spotID, err)
+		return
+	}
+
+	checked := map[string]bool{}
+	for _, revision := range pruned {
+		for _, image := range []*string{revision.DayImage, revision.NightImage} {
+			if image == nil || checked[*image] {
+				continue
+			}
+			checked[*image] = true
+			deleteUnreferencedImage(db, store, image)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"chillspot-backend/internal/models"
+	"chillspot-backend/internal/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestPruneSpotRevisions(t *testing.T) {
+	spotID := uuid.New()
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		limit string
+		want  int64 // limit passed to the DELETE, 0 when nothing runs
+	}{
+		{"full history by default", "", 0},
+		{"no limit", "0", 0},
+		{"invalid limit keeps everything", "fifty", 0},
+		{"limited", "50", 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SPOT_REVISION_LIMIT", tt.limit)
+			stub := &stubDriver{}
+			pruneSpotRevisions(stubDB(t, stub), store, spotID)
+
+			switch {
+			case tt.want == 0 && len(stub.queries) != 0:
+				t.Errorf("ran %q", stub.queries)
+			case tt.want != 0 && (len(stub.queries) != 1 || !strings.HasPrefix(stub.queries[0], "DELETE") ||
+				stub.args[0][len(stub.args[0])-1] != tt.want):
+				t.Errorf("ran %q with %v", stub.queries, stub.args)
+			}
+		})
+	}
+}
+
+func TestSpotCounterUpdatesSkipRevisions(t *testing.T) {
+	spot := func() *models.Spot { return &models.Spot{ID: uuid.New(), Title: "Lake"} }
+
+	tests := []struct {
+		name     string
+		update   func(db *gorm.DB) error
+		revision bool
+	}{
+		{"visit count", func(db *gorm.DB) error {
+			return db.Model(spot()).Update("visit_count", gorm.Expr("visit_count + 1")).Error
+		}, false},
+		{"both counters", func(db *gorm.DB) error {
+			return db.Model(spot()).Updates(map[string]any{"visit_count": 3, "favorites_count": 4}).Error
+		}, false},
+		{"counter and title", func(db *gorm.DB) error {
+			return db.Model(spot()).Updates(map[string]any{"visit_count": 3, "title": "Pond"}).Error
+		}, true},
+		{"title", func(db *gorm.DB) error {
+			return db.Model(spot()).Update("title", "Pond").Error
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubDriver{}
+			// Without a stored row the revision lookup fails, which is fine
+			// here: only whether it ran matters
+			tt.update(stubDB(t, stub))
+
+			locked := len(stub.queries) > 0 && strings.Contains(stub.queries[0], "FOR UPDATE")
+			if locked != tt.revision {
+				t.Errorf("ran %q", stub.queries)
+			}
+		})
+	}
+}
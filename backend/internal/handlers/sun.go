@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"chillspot-backend/internal/astro"
+	"chillspot-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GetSpotSunHandler returns the sun and moon events at the spot for ?date=
+// (YYYY-MM-DD), by default the current date at the spot. They are computed
+// where the viewer sees the spot and rounded to the minute, so they can't be
+// worked back into a location the viewer may not know.
+func GetSpotSunHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var spot models.Spot
+		viewerID, ok := loadVisibleSpot(db, w, r, "id", &spot)
+		if !ok {
+			return
+		}
+		if err := maskSpots(db, viewerID, &spot); err != nil {
+			http.Error(w, "Failed to fetch spot", http.StatusInternalServerError)
+			return
+		}
+
+		date := astro.LocalDate(time.Now(), spot.Longitude)
+		if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+			var err error
+			date, err = time.Parse("2006-01-02", dateStr)
+			if err != nil {
+				http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(astro.SunEvents(date, spot.Latitude, spot.Longitude, spot.Altitude).Round(time.Minute))
+	}
+}
+
+// currentImage names the image field that suits the time of day at the spot,
+// falling back to whichever image the spot has
+func currentImage(spot *models.Spot, now time.Time) string {
+	hasDay := spot.DayImage != nil && *spot.DayImage != ""
+	hasNight := spot.NightImage != nil && *spot.NightImage != ""
+
+	dark := astro.IsDark(now, spot.Latitude, spot.Longitude)
+	if hasNight && (dark || !hasDay) {
+		return "NightImage"
+	}
+	if hasDay {
+		return "DayImage"
+	}
+	return ""
+}
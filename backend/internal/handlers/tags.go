@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultTagSuggestions = 10
+	maxTagSuggestions     = 50
+)
+
+type TagCount struct {
+	Name      string `json:"name"`
+	SpotCount int64  `json:"spot_count"`
+}
+
+type SpotTagsInput struct {
+	Tags []string `json:"tags"`
+}
+
+// spotTagNames returns the tags of a spot in alphabetical order
+func spotTagNames(db *gorm.DB, spotID uuid.UUID) ([]string, error) {
+	names := []string{}
+	err := db.Table("spot_tags").
+		Joins("JOIN tags ON tags.id = spot_tags.tag_id").
+		Where("spot_tags.spot_id = ?", spotID).
+		Order("tags.name ASC").
+		Pluck("tags.name", &names).Error
+	return names, err
+}
+
+// addSpotTags attaches the normalized tag names to the spot on behalf of
+// userID, creating tags that don't exist yet. Tags already on the spot keep
+// their original author.
+func addSpotTags(tx *gorm.DB, spotID, userID uuid.UUID, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	tags := make([]models.Tag, len(names))
+	for i, name := range names {
+		tags[i] = models.Tag{Name: name}
+	}
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).
+		Create(&tags).Error; err != nil {
+		return err
+	}
+
+	// IDs of tags that already existed aren't returned by the insert
+	var tagIDs []uuid.UUID
+	if err := tx.Model(&models.Tag{}).Where("name IN ?", names).Pluck("id", &tagIDs).Error; err != nil {
+		return err
+	}
+
+	links := make([]models.SpotTag, len(tagIDs))
+	for i, tagID := range tagIDs {
+		links[i] = models.SpotTag{SpotID: spotID, TagID: tagID, UserID: userID}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error; err != nil {
+		return err
+	}
+
+	var count int64
+	if err := tx.Model(&models.SpotTag{}).Where("spot_id = ?", spotID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > models.MaxTagsPerSpot {
+		return errTooManyTags
+	}
+	return nil
+}
+
+var errTooManyTags = errors.New("A spot can have at most " + strconv.Itoa(models.MaxTagsPerSpot) + " tags")
+
+// setSpotTags replaces every tag of the spot with names
+func setSpotTags(tx *gorm.DB, spotID, userID uuid.UUID, names []string) error {
+	query := tx.Where("spot_id = ?", spotID)
+	if len(names) > 0 {
+		query = query.Where("tag_id NOT IN (?)", tx.Model(&models.Tag{}).Select("id").Where("name IN ?", names))
+	}
+	if err := query.Delete(&models.SpotTag{}).Error; err != nil {
+		return err
+	}
+	return addSpotTags(tx, spotID, userID, names)
+}
+
+// tagFormError reports a failed ParseTags or addSpotTags
+func tagFormError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidTag):
+		http.Error(w, "Invalid tag: use letters, digits and hyphens, at most "+strconv.Itoa(models.MaxTagLength)+" characters", http.StatusBadRequest)
+	case errors.Is(err, errTooManyTags):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Failed to save tags", http.StatusInternalServerError)
+	}
+}
+
+// GetTagsHandler lists tags with their spot counts, most used first. With
+// ?q= it autocompletes tags starting with the (normalized) prefix.
+func GetTagsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		limit := defaultTagSuggestions
+		if limitStr := query.Get("limit"); limitStr != "" {
+			n, err := strconv.Atoi(limitStr)
+			if err != nil || n <= 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = min(n, maxTagSuggestions)
+		}
+
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		// Only spots the user could find count, so hidden spots' tags don't leak
+		tx := db.Table("tags").
+			Select("tags.name, COUNT(spot_tags.spot_id) AS spot_count").
+			Joins("JOIN spot_tags ON spot_tags.tag_id = tags.id").
+			Joins("JOIN spots ON spots.id = spot_tags.spot_id").
+			Where(visibleSpots(db, userUUID, true)).
+			Group("tags.id").
+			Order("spot_count DESC, tags.name ASC").
+			Limit(limit)
+
+		if prefix := strings.TrimSpace(query.Get("q")); prefix != "" {
+			normalized, err := models.NormalizeTag(prefix)
+			if err != nil {
+				http.Error(w, "Invalid tag prefix", http.StatusBadRequest)
+				return
+			}
+			// Normalized tags never contain LIKE wildcards
+			tx = tx.Where("tags.name LIKE ?", normalized+"%")
+		}
+
+		tags := []TagCount{}
+		if err := tx.Scan(&tags).Error; err != nil {
+			http.Error(w, "Failed to fetch tags", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tags)
+	}
+}
+
+// AddSpotTagsHandler lets any user tag a spot
+func AddSpotTagsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		spotUUID, err := uuid.Parse(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid spot ID", http.StatusBadRequest)
+			return
+		}
+
+		var input SpotTagsInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		names, err := models.ParseTags(input.Tags...)
+		if err != nil {
+			tagFormError(w, err)
+			return
+		}
+		if len(names) == 0 {
+			http.Error(w, "No tags given", http.StatusBadRequest)
+			return
+		}
+
+		var spot models.Spot
+		if !findVisibleSpot(db, w, userUUID, spotUUID, &spot) {
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return addSpotTags(tx, spot.ID, userUUID, names)
+		})
+		if err != nil {
+			tagFormError(w, err)
+			return
+		}
+
+		tags, err := spotTagNames(db, spot.ID)
+		if err != nil {
+			http.Error(w, "Failed to fetch tags", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"message": "Tags added",
+			"tags":    tags,
+		})
+	}
+}
+
+// RemoveSpotTagHandler removes a tag from a spot. The spot's owner may remove
+// any tag, other users only the ones they added.
+func RemoveSpotTagHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		vars := mux.Vars(r)
+		spotUUID, err := uuid.Parse(vars["id"])
+		if err != nil {
+			http.Error(w, "Invalid spot ID", http.StatusBadRequest)
+			return
+		}
+
+		name, err := models.NormalizeTag(vars["tag"])
+		if err != nil {
+			http.Error(w, "Invalid tag", http.StatusBadRequest)
+			return
+		}
+
+		var link models.SpotTag
+		err = db.Joins("Tag").
+			Where("spot_tags.spot_id = ? AND \"Tag\".name = ?", spotUUID, name).
+			First(&link).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(w, "Tag not found on this spot", http.StatusNotFound)
+			} else {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if link.UserID != userUUID {
+			var owned int64
+			db.Model(&models.Spot{}).Where("id = ? AND user_id = ?", spotUUID, userUUID).Count(&owned)
+			if owned == 0 {
+				http.Error(w, "You can only remove tags you added", http.StatusForbidden)
+				return
+			}
+		}
+
+		if err := db.Where("spot_id = ? AND tag_id = ?", link.SpotID, link.TagID).Delete(&models.SpotTag{}).Error; err != nil {
+			http.Error(w, "Failed to remove tag", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Tag removed"})
+	}
+}
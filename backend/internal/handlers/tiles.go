@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"chillspot-backend/internal/models"
+	"chillspot-backend/internal/mvt"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTileZoom = 22
+	// Keeps low zoom tiles, which cover huge areas, to a sane size
+	maxTileFeatures = 10000
+)
+
+// tileETag fingerprints every spot the tile shows, so the tag changes whenever a
+// spot in it is created, edited, moved in or out, liked, visited or deleted.
+func tileETag(inTile *gorm.DB, z, x, y int) (string, error) {
+	var fingerprint struct {
+		Count int64
+		Hash  string
+	}
+	err := inTile.
+		Select(`COUNT(*) AS count,
+			COALESCE(MD5(STRING_AGG(
+				id::text || ':' || updated_at::text || ':' || favorites_count || ':' || visit_count,
+				',' ORDER BY id)), '') AS hash`).
+		Scan(&fingerprint).Error
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`"%d-%d-%d-%d-%s"`, z, x, y, fingerprint.Count, fingerprint.Hash), nil
+}
+
+func GetSpotTileHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		z, err := strconv.Atoi(vars["z"])
+		if err != nil || z < 0 || z > maxTileZoom {
+			http.Error(w, "Invalid zoom", http.StatusBadRequest)
+			return
+		}
+
+		n := 1 << z
+		x, err := strconv.Atoi(vars["x"])
+		if err != nil || x < 0 || x >= n {
+			http.Error(w, "Invalid tile x", http.StatusBadRequest)
+			return
+		}
+
+		y, err := strconv.Atoi(vars["y"])
+		if err != nil || y < 0 || y >= n {
+			http.Error(w, "Invalid tile y", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := r.Context().Value("user_id").(string)
+		if !ok || userID == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userUUID, err := uuid.Parse(userID)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		category, tags, err := parseTaxonomy(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var box BoundingBox
+		box.MinLng, box.MinLat, box.MaxLng, box.MaxLat = mvt.TileBounds(z, x, y)
+
+		inTile := func() *gorm.DB {
+			tx := db.Model(&models.Spot{}).
+				Where(visibleSpots(db, userUUID, true)).
+				Where(viewerBBoxFilter(db, userUUID, box))
+			return taxonomyFilter(tx, category, tags)
+		}
+
+		etag, err := tileETag(inTile(), z, x, y)
+		if err != nil {
+			http.Error(w, "Failed to fetch tile", http.StatusInternalServerError)
+			return
+		}
+
+		// Tiles are per-user once auth is involved, so only private caches may
+		// keep them, and they must revalidate with the ETag on every use.
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		var spots []models.Spot
+		if err := inTile().
+			Order("favorites_count DESC, id ASC").
+			Limit(maxTileFeatures).
+			Find(&spots).Error; err != nil {
+			http.Error(w, "Failed to fetch tile", http.StatusInternalServerError)
+			return
+		}
+
+		features := make([]*models.Spot, len(spots))
+		for i := range spots {
+			features[i] = &spots[i]
+		}
+		if err := maskSpots(db, userUUID, features...); err != nil {
+			http.Error(w, "Failed to fetch tile", http.StatusInternalServerError)
+			return
+		}
+
+		layer := mvt.Layer{Name: "spots"}
+		for i, spot := range spots {
+			px, py := mvt.Project(z, x, y, spot.Longitude, spot.Latitude)
+			properties := map[string]any{
+				"id":              spot.ID.String(),
+				"title":           spot.Title,
+				"weather":         string(spot.RecommendedWeather),
+				"weathers":        strings.Join(spot.RecommendedWeathers.Strings(), ","),
+				"favorites_count": spot.FavoritesCount,
+				"visit_count":     spot.VisitCount,
+			}
+			// Concealed geocache spots are drawn as their hint circle
+			if spot.Concealed {
+				properties["concealed"] = true
+				properties["hint_radius"] = spot.HintRadius
+			}
+			layer.Features = append(layer.Features, mvt.Feature{
+				ID:         uint64(i + 1),
+				X:          px,
+				Y:          py,
+				Properties: properties,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+		w.Write(mvt.Encode(layer))
+	}
+}
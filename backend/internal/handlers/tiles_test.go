@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"chillspot-backend/internal/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// stubDriver answers every query with the same rows and records the SQL and
+// its arguments. Statements that return no rows affect the next count in
+// rowsAffected, one row once those run out.
+type stubDriver struct {
+	columns      []string
+	rows         [][]driver.Value
+	rowsAffected []int64
+	queries      []string
+	args         [][]any
+}
+
+func (d *stubDriver) record(query string, args []driver.NamedValue) {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	d.queries = append(d.queries, query)
+	d.args = append(d.args, values)
+}
+
+func (d *stubDriver) Open(string) (driver.Conn, error) { return stubConn{d}, nil }
+
+type stubConn struct{ driver *stubDriver }
+
+func (c stubConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c stubConn) Close() error                        { return nil }
+func (c stubConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c stubConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.record(query, args)
+	return &stubRows{columns: c.driver.columns, rows: c.driver.rows}, nil
+}
+
+func (c stubConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.driver.record(query, args)
+	if len(c.driver.rowsAffected) == 0 {
+		return driver.RowsAffected(1), nil
+	}
+	affected := c.driver.rowsAffected[0]
+	c.driver.rowsAffected = c.driver.rowsAffected[1:]
+	return driver.RowsAffected(affected), nil
+}
+
+type stubRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *stubRows) Columns() []string { return r.columns }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// stubDB is a Postgres-flavoured *gorm.DB backed by the stub driver
+func stubDB(t *testing.T, stub *stubDriver) *gorm.DB {
+	t.Helper()
+	conn := sql.OpenDB(driverConnector{stub})
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+type driverConnector struct{ driver *stubDriver }
+
+func (c driverConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c driverConnector) Driver() driver.Driver                        { return c.driver }
+
+func TestTileETag(t *testing.T) {
+	tests := []struct {
+		name    string
+		z, x, y int
+		count   int64
+		hash    string
+		want    string
+	}{
+		{"empty tile", 0, 0, 0, 0, "", `"0-0-0-0-"`},
+		{"spots", 14, 9102, 6004, 2, "0cc175b9c0f1b6a8", `"14-9102-6004-2-0cc175b9c0f1b6a8"`},
+		// Tiles with the same spots still get their own tags
+		{"neighbouring tile", 14, 9103, 6004, 2, "0cc175b9c0f1b6a8", `"14-9103-6004-2-0cc175b9c0f1b6a8"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubDriver{
+				columns: []string{"count", "hash"},
+				rows:    [][]driver.Value{{tt.count, tt.hash}},
+			}
+			db := stubDB(t, stub)
+
+			etag, err := tileETag(db.Model(&models.Spot{}), tt.z, tt.x, tt.y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if etag != tt.want {
+				t.Errorf("tileETag(%d, %d, %d) = %s, want %s", tt.z, tt.x, tt.y, etag, tt.want)
+			}
+
+			// Every change a tile shows must change the fingerprint, in any row order
+			query := strings.Join(stub.queries, "\n")
+			for _, part := range []string{"id", "updated_at", "favorites_count", "visit_count", "ORDER BY id"} {
+				if !strings.Contains(query, part) {
+					t.Errorf("fingerprint lacks %s: %s", part, query)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"chillspot-backend/internal/geo"
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// visibleSpots is the condition on the spots table matching the spots the
+// viewer may see. Listings pass listed, which leaves out other users'
+// unlisted spots: those only open by ID.
+func visibleSpots(db *gorm.DB, viewerID uuid.UUID, listed bool) *gorm.DB {
+	visibilities := []models.Visibility{models.Public}
+	if !listed {
+		visibilities = append(visibilities, models.Unlisted)
+	}
+	return db.Where("spots.user_id = ?", viewerID).
+		Or("spots.visibility IN ?", visibilities).
+		Or("spots.visibility = ? AND spots.user_id IN (SELECT friend_id FROM user_friends WHERE user_id = ?)",
+			models.FriendsOnly, viewerID)
+}
+
+// parseVisibilityForm sets the visibility and fuzz_location fields present
+// in a spot form
+func parseVisibilityForm(form url.Values, spot *models.Spot) error {
+	if values, ok := form["visibility"]; ok {
+		visibility := models.Visibility(strings.ToLower(strings.TrimSpace(values[0])))
+		if !models.ValidVisibility(visibility) {
+			return errors.New("Invalid visibility: use public, friends, private or unlisted")
+		}
+		spot.Visibility = visibility
+	}
+	if values, ok := form["fuzz_location"]; ok {
+		fuzz, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return errors.New("Invalid fuzz_location")
+		}
+		spot.FuzzLocation = fuzz
+	}
+	return nil
+}
+
+// loadVisibleSpot fetches the spot named by the idVar route variable if the
+// caller may see it. Hidden spots are reported as not found, so their IDs
+// can't be probed.
+func loadVisibleSpot(db *gorm.DB, w http.ResponseWriter, r *http.Request, idVar string, spot *models.Spot) (uuid.UUID, bool) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, false
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	spotUUID, err := uuid.Parse(mux.Vars(r)[idVar])
+	if err != nil {
+		http.Error(w, "Invalid spot ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+
+	if !findVisibleSpot(db, w, userUUID, spotUUID, spot) {
+		return uuid.Nil, false
+	}
+	return userUUID, true
+}
+
+// findVisibleSpot is loadVisibleSpot for handlers that read the IDs themselves
+func findVisibleSpot(db *gorm.DB, w http.ResponseWriter, viewerID, spotID uuid.UUID, spot *models.Spot) bool {
+	err := db.Where("spots.id = ?", spotID).
+		Where(visibleSpots(db, viewerID, false)).
+		First(spot).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Spot not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
+
+// revealsLocation reports whether the viewer sees the exact position of the
+// spot: owners always do, other users once they discovered a geocache spot
+// and, for fuzzed spots, once they visited it
+func revealsLocation(db *gorm.DB, viewerID uuid.UUID, spot *models.Spot) bool {
+	if spot.UserID == viewerID {
+		return true
+	}
+	if spot.Geocache && !hasDiscovered(db, viewerID, spot.ID) {
+		return false
+	}
+	if !spot.FuzzLocation {
+		return true
+	}
+	var visits int64
+	db.Model(&models.VisitedSpot{}).Where("spot_id = ? AND user_id = ? AND verified", spot.ID, viewerID).Count(&visits)
+	return visits > 0
+}
+
+// maskSpots conceals the geocache spots the viewer hasn't discovered and
+// fuzzes the location of the spots they may only see approximately, in place
+func maskSpots(db *gorm.DB, viewerID uuid.UUID, spots ...*models.Spot) error {
+	var geocacheIDs, fuzzedIDs []uuid.UUID
+	for _, spot := range spots {
+		switch {
+		case spot.UserID == viewerID || spot.LocationFuzzed || spot.Concealed:
+		case spot.Geocache:
+			geocacheIDs = append(geocacheIDs, spot.ID)
+		case spot.FuzzLocation:
+			fuzzedIDs = append(fuzzedIDs, spot.ID)
+		}
+	}
+
+	discovered := make(map[uuid.UUID]bool)
+	if len(geocacheIDs) > 0 {
+		var discoveredIDs []uuid.UUID
+		if err := db.Model(&models.SpotDiscovery{}).
+			Where("user_id = ? AND spot_id IN ?", viewerID, geocacheIDs).
+			Pluck("spot_id", &discoveredIDs).Error; err != nil {
+			return err
+		}
+		for _, id := range discoveredIDs {
+			discovered[id] = true
+		}
+	}
+
+	visited := make(map[uuid.UUID]bool)
+	if len(fuzzedIDs) > 0 {
+		var visitedIDs []uuid.UUID
+		if err := db.Model(&models.VisitedSpot{}).
+			Where("user_id = ? AND spot_id IN ? AND verified", viewerID, fuzzedIDs).
+			Distinct().
+			Pluck("spot_id", &visitedIDs).Error; err != nil {
+			return err
+		}
+		for _, id := range visitedIDs {
+			visited[id] = true
+		}
+	}
+
+	for _, spot := range spots {
+		switch {
+		case spot.UserID == viewerID || spot.LocationFuzzed || spot.Concealed:
+		case spot.Geocache:
+			if !discovered[spot.ID] {
+				spot.Conceal()
+			}
+		case spot.FuzzLocation:
+			if !visited[spot.ID] {
+				spot.Fuzz()
+			}
+		}
+	}
+	return nil
+}
+
+// viewerArgs are the arguments of a coordinateSQL column
+func viewerArgs(viewerID uuid.UUID) []any {
+	return []any{viewerID, viewerID, viewerID, viewerID}
+}
+
+// viewerDistanceSQL is distanceSQL from (lat, lng) to each spot where the
+// viewer sees it, so fuzzed and concealed spots are sorted, filtered and
+// paged by their approximate distance only
+func viewerDistanceSQL(viewerID uuid.UUID, lat, lng float64) (string, []any) {
+	// The replacer doesn't rescan what it inserts
+	sql := strings.NewReplacer(
+		"spots.latitude", coordinateSQL("latitude"),
+		"spots.longitude", coordinateSQL("longitude"),
+	).Replace(distanceSQL)
+
+	viewer := viewerArgs(viewerID)
+	var args []any
+	args = append(args, viewer...)
+	args = append(args, lat, lat)
+	args = append(args, viewer...)
+	args = append(args, viewer...)
+	return sql, append(args, lng)
+}
+
+// coordinateSQL is the spots table column as the viewer sees it: the hint
+// circle center of undiscovered geocache spots, the grid cell center of
+// fuzzed ones. Its arguments are viewerArgs.
+func coordinateSQL(column string) string {
+	return fmt.Sprintf(`CASE WHEN NOT %[4]s THEN spots.hint_%[1]s
+		WHEN spots.fuzz_location AND spots.user_id <> ?
+			AND NOT EXISTS (SELECT 1 FROM visited_spots WHERE visited_spots.spot_id = spots.id AND visited_spots.user_id = ? AND visited_spots.verified)
+		THEN FLOOR(spots.%[1]s / %[2]g) * %[2]g + %[3]g ELSE spots.%[1]s END`,
+		column, models.FuzzGrid, models.FuzzGrid/2, discoveredSQL)
+}
+
+// exactSQL matches the spots the viewer sees at their real position: those
+// coordinateSQL leaves alone. Its arguments are viewerArgs.
+const exactSQL = `(` + discoveredSQL + ` AND NOT (spots.fuzz_location AND spots.user_id <> ?
+	AND NOT EXISTS (SELECT 1 FROM visited_spots WHERE visited_spots.spot_id = spots.id AND visited_spots.user_id = ? AND visited_spots.verified)))`
+
+// Masked coordinates are never further than this from the real position:
+// hint circles are centered within their radius, fuzz cells are far smaller
+const maxMaskOffset = float64(models.MaxHintRadius) // meters
+
+// circleBox is the bounding box of the circle around (lat, lng)
+func circleBox(lat, lng, radius float64) BoundingBox {
+	box := BoundingBox{}
+	box.MinLat, box.MinLng = geo.Offset(lat, lng, -radius, -radius)
+	box.MaxLat, box.MaxLng = geo.Offset(lat, lng, radius, radius)
+	if math.Abs(lat) > 89 || math.IsInf(box.MinLng, 0) || math.IsInf(box.MaxLng, 0) {
+		box.MinLng, box.MaxLng = -180, 180
+	}
+	return box
+}
+
+// maskedBBoxFilter is bboxFilter on the coordinates the viewer sees
+func maskedBBoxFilter(db *gorm.DB, viewerID uuid.UUID, box BoundingBox) *gorm.DB {
+	lat, lng := coordinateSQL("latitude"), coordinateSQL("longitude")
+	filter := db.Where(lat+" BETWEEN ? AND ?", append(viewerArgs(viewerID), box.MinLat, box.MaxLat)...)
+	if box.MinLng <= box.MaxLng {
+		return filter.Where(lng+" BETWEEN ? AND ?", append(viewerArgs(viewerID), box.MinLng, box.MaxLng)...)
+	}
+	args := append(viewerArgs(viewerID), box.MinLng)
+	args = append(args, viewerArgs(viewerID)...)
+	return filter.Where("("+lng+" >= ? OR "+lng+" <= ?)", append(args, box.MaxLng)...)
+}
+
+// viewerBBoxFilter is bboxFilter for the viewer. Spots they only see
+// approximately are matched where they are shown, or a narrow box would
+// tell which grid cell or hint circle holds the real position.
+func viewerBBoxFilter(db *gorm.DB, viewerID uuid.UUID, box BoundingBox) *gorm.DB {
+	return db.Where(db.Where(exactSQL, viewerArgs(viewerID)...).Where(bboxFilter(db, box))).
+		Or(db.Where("NOT "+exactSQL, viewerArgs(viewerID)...).Where(maskedBBoxFilter(db, viewerID, box)))
+}
+
+// viewerAreaFilter is geohashPrefixFilter for the viewer, matching the spots
+// they only see approximately where they are shown like viewerBBoxFilter.
+// Those are also narrowed to the cells around a circle wide enough to hold
+// every real position the shown one allows, so the index still applies
+// without telling anything.
+func viewerAreaFilter(db *gorm.DB, viewerID uuid.UUID, lat, lng, radius float64) *gorm.DB {
+	return db.Where(db.Where(exactSQL, viewerArgs(viewerID)...).Where(geohashPrefixFilter(db, lat, lng, radius))).
+		Or(db.Where("NOT "+exactSQL, viewerArgs(viewerID)...).
+			Where(geohashPrefixFilter(db, lat, lng, radius*math.Sqrt2+maxMaskOffset)).
+			Where(maskedBBoxFilter(db, viewerID, circleBox(lat, lng, radius))))
+}
@@ -3,10 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"log"
-	"math"
 	"net/http"
 	"time"
 
+	"chillspot-backend/internal/geo"
 	"chillspot-backend/internal/models"
 
 	"github.com/google/uuid"
@@ -15,7 +15,7 @@ import (
 
 type AddVisitedSpotInput struct {
 	SpotID string `json:"spot_id"`
-	Notes  string `json:"notes"`
+	VisitInput
 }
 
 type CheckProximityInput struct {
@@ -24,7 +24,9 @@ type CheckProximityInput struct {
 }
 
 type ProximityResponse struct {
-	NearbySpots []NearbySpot `json:"nearby_spots"`
+	NearbySpots []NearbySpot           `json:"nearby_spots"`
+	Discoveries []models.SpotDiscovery `json:"discoveries"` // geocache spots revealed by this check
+	NewBadges   []BadgeResponse        `json:"new_badges"`
 }
 
 type NearbySpot struct {
@@ -35,21 +37,12 @@ type NearbySpot struct {
 	Longitude float64   `json:"longitude"`
 }
 
-// Haversine formula to calculate distance between two points
-func calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	const R = 6371000 // Earth's radius in meters
-
-	lat1Rad := lat1 * math.Pi / 180
-	lat2Rad := lat2 * math.Pi / 180
-	deltaLat := (lat2 - lat1) * math.Pi / 180
-	deltaLon := (lon2 - lon1) * math.Pi / 180
-
-	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
-		math.Cos(lat1Rad)*math.Cos(lat2Rad)*
-			math.Sin(deltaLon/2)*math.Sin(deltaLon/2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+// Users within this distance of a spot are at it
+const proximityThreshold = 50.0 // meters
 
-	return R * c
+// calculateDistance returns the distance in meters between two points
+func calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	return geo.Distance(lat1, lon1, lat2, lon2)
 }
 
 func AddVisitedSpotHandler(db *gorm.DB) http.HandlerFunc {
@@ -78,49 +71,23 @@ func AddVisitedSpotHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		// Check if spot exists and belongs to user
+		// Any spot the user can see may be visited, hidden ones once found
 		var spot models.Spot
-		if err := db.Where("id = ? AND user_id = ?", spotUUID, userUUID).First(&spot).Error; err != nil {
-			http.Error(w, "Spot not found", http.StatusNotFound)
+		if !findVisibleSpot(db, w, userUUID, spotUUID, &spot) || !requireDiscovered(db, w, userUUID, &spot) {
 			return
 		}
 
-		// Check if already visited
-		var existingVisit models.VisitedSpot
-		if err := db.Where("user_id = ? AND spot_id = ?", userUUID, spotUUID).First(&existingVisit).Error; err == nil {
-			http.Error(w, "Spot already marked as visited", http.StatusConflict)
+		if err := input.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		visitedSpot := models.VisitedSpot{
-			UserID:    userUUID,
-			SpotID:    spotUUID,
-			VisitedAt: time.Now(),
-			Notes:     input.Notes,
-		}
-
-		// Create visited spot
-		if err := db.Create(&visitedSpot).Error; err != nil {
-			http.Error(w, "Failed to add visited spot", http.StatusInternalServerError)
+		result, err := recordVisit(db.WithContext(r.Context()), &spot, userUUID, input.VisitInput)
+		if err != nil {
+			visitError(w, err)
 			return
 		}
-
-		// Update user XP
-		if err := db.Model(&models.User{}).
-			Where("id = ?", userUUID).
-			Update("xp", gorm.Expr("xp + ?", 10)). // Add 10 XP
-			Error; err != nil {
-			// We don't rollback here, just log the error
-			// Consider adding proper error handling for production
-			log.Printf("Failed to update user XP: %v", err)
-		}
-
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(map[string]any{
-			"message":      "Visited spot added",
-			"xp_gained":    10,
-			"visited_spot": visitedSpot,
-		})
+		writeVisitResult(w, result)
 	}
 }
 
@@ -138,14 +105,52 @@ func GetVisitedSpotsHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		page, err := parsePageRequest(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		category, tags, err := parseTaxonomy(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Failed check-ins aren't visits, and spots the user may no longer
+		// see drop out
+		visits := db.Preload("Spot").
+			Where("user_id = ? AND verification IN ?", userUUID, journalVerifications).
+			Where("spot_id IN (?)", db.Model(&models.Spot{}).Select("spots.id").Where(visibleSpots(db, userUUID, false)))
+		if category != "" || len(tags) > 0 {
+			visits = visits.Where("spot_id IN (?)", taxonomyFilter(db.Model(&models.Spot{}).Select("spots.id"), category, tags))
+		}
+
+		tx, err := applyKeyset[time.Time](visits, page, "visited_at", "id", true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		var visitedSpots []models.VisitedSpot
-		if err := db.Preload("Spot").Where("user_id = ?", userUUID).Find(&visitedSpots).Error; err != nil {
+		if err := tx.Find(&visitedSpots).Error; err != nil {
+			http.Error(w, "Failed to fetch visited spots", http.StatusInternalServerError)
+			return
+		}
+
+		spots := make([]*models.Spot, len(visitedSpots))
+		for i := range visitedSpots {
+			spots[i] = &visitedSpots[i].Spot
+		}
+		if err := maskSpots(db, userUUID, spots...); err != nil {
 			http.Error(w, "Failed to fetch visited spots", http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(visitedSpots)
+		json.NewEncoder(w).Encode(newPage(visitedSpots, page, "visited_at", func(v models.VisitedSpot) (any, uuid.UUID) {
+			return v.VisitedAt, v.ID
+		}))
 	}
 }
 
@@ -169,36 +174,27 @@ func CheckProximityHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		// Get all user's spots
-		var spots []models.Spot
-		if err := db.Where("user_id = ?", userUUID).Find(&spots).Error; err != nil {
-			http.Error(w, "Failed to fetch spots", http.StatusInternalServerError)
+		// Hidden spots found here are revealed first, so they show up below
+		discoveries, err := discoverSpots(db, userUUID, input.Latitude, input.Longitude)
+		if err != nil {
+			http.Error(w, "Failed to check for hidden spots", http.StatusInternalServerError)
 			return
 		}
 
-		// Get already visited spots
-		var visitedSpots []models.VisitedSpot
-		if err := db.Where("user_id = ?", userUUID).Find(&visitedSpots).Error; err != nil {
-			http.Error(w, "Failed to fetch visited spots", http.StatusInternalServerError)
+		// Every spot the user can see and hasn't visited yet
+		var spots []models.Spot
+		if err := db.Where(visibleSpots(db, userUUID, true)).
+			Where(discoveredSQL, userUUID, userUUID).
+			Where("NOT EXISTS (SELECT 1 FROM visited_spots WHERE visited_spots.spot_id = spots.id AND visited_spots.user_id = ? AND visited_spots.verified)", userUUID).
+			Where(geohashPrefixFilter(db, input.Latitude, input.Longitude, proximityThreshold)).
+			Find(&spots).Error; err != nil {
+			http.Error(w, "Failed to fetch spots", http.StatusInternalServerError)
 			return
 		}
 
-		// Create map of visited spot IDs
-		visitedMap := make(map[uuid.UUID]bool)
-		for _, visited := range visitedSpots {
-			visitedMap[visited.SpotID] = true
-		}
-
-		// Check proximity (within 50 meters)
-		const proximityThreshold = 50.0
-		var nearbySpots []NearbySpot
-
+		// Check proximity
+		nearbySpots := []NearbySpot{}
 		for _, spot := range spots {
-			// Skip if already visited
-			if visitedMap[spot.ID] {
-				continue
-			}
-
 			distance := calculateDistance(
 				input.Latitude, input.Longitude,
 				spot.Latitude, spot.Longitude,
@@ -217,6 +213,14 @@ func CheckProximityHandler(db *gorm.DB) http.HandlerFunc {
 
 		response := ProximityResponse{
 			NearbySpots: nearbySpots,
+			Discoveries: discoveries,
+		}
+		if len(discoveries) > 0 {
+			newBadges, err := awardBadges(db, userUUID)
+			if err != nil {
+				log.Printf("Failed to award badges: %v", err)
+			}
+			response.NewBadges = badgeResponses(newBadges)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
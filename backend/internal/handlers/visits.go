@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"chillspot-backend/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// XP for a verified visit: the first to a spot earns the full amount, a
+// repeat visit on a later day a little
+const (
+	firstVisitXP  = 10
+	repeatVisitXP = 2
+)
+
+var errInvalidTimezone = errors.New("Invalid timezone")
+
+// VisitInput is what every visit endpoint accepts
+type VisitInput struct {
+	Notes    string `json:"notes"`
+	Timezone string `json:"timezone"` // optional, remembered for the user; see parseTimezone
+	VisitLocationInput
+}
+
+// VisitResult is the outcome of recordVisit
+type VisitResult struct {
+	Visit     models.VisitedSpot
+	Duplicate bool // the user had already visited the spot that day
+	XPGained  int
+	NewBadges []models.Badge
+}
+
+// parseTimezone reads an IANA timezone name or a UTC offset such as +02:00
+func parseTimezone(name string) (*time.Location, error) {
+	if strings.HasPrefix(name, "+") || strings.HasPrefix(name, "-") {
+		offset, err := time.Parse("-07:00", name)
+		if err != nil {
+			return nil, errInvalidTimezone
+		}
+		_, seconds := offset.Zone()
+		return time.FixedZone(name, seconds), nil
+	}
+	location, err := time.LoadLocation(name)
+	// "Local" would be the server's timezone
+	if err != nil || name == "Local" {
+		return nil, errInvalidTimezone
+	}
+	return location, nil
+}
+
+// visitTimezone is the timezone the user's days start in. A timezone sent
+// with the visit replaces the stored one.
+func visitTimezone(db *gorm.DB, userID uuid.UUID, name string) (*time.Location, error) {
+	if name != "" {
+		location, err := parseTimezone(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Model(&models.User{}).
+			Where("id = ? AND timezone <> ?", userID, name).
+			Update("timezone", name).Error; err != nil {
+			return nil, err
+		}
+		return location, nil
+	}
+
+	var user models.User
+	if err := db.Select("timezone").Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	location, err := parseTimezone(user.Timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return location, nil
+}
+
+// visitDay is the start and end of the day holding t in location
+func visitDay(t time.Time, location *time.Location) (time.Time, time.Time) {
+	local := t.In(location)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// recordVisit is the one way visits are made. Every check-in is verified
+// and stored, failed ones included, so the anti-cheat checks see them. A
+// verified visit then:
+//   - is dropped if the user already visited the spot that day in their
+//     timezone, returning that visit instead;
+//   - earns firstVisitXP the first time, repeatVisitXP afterwards, held in
+//     escrow if the anti-cheat checks flag it;
+//   - bumps the spot's visit_count;
+//   - checks the user's badges unless flagged.
+func recordVisit(db *gorm.DB, spot *models.Spot, userID uuid.UUID, input VisitInput) (VisitResult, error) {
+	var result VisitResult
+
+	location, err := visitTimezone(db, userID, input.Timezone)
+	if err != nil {
+		return result, err
+	}
+
+	now := time.Now()
+	visit := models.VisitedSpot{
+		UserID:    userID,
+		SpotID:    spot.ID,
+		VisitedAt: now,
+		Notes:     input.Notes,
+	}
+	verifyVisit(&visit, spot, input.VisitLocationInput, now)
+	if !visit.Verified {
+		result.Visit = visit
+		return result, db.Omit("User", "Spot").Create(&result.Visit).Error
+	}
+
+	dayStart, dayEnd := visitDay(now, location)
+	var flagged bool
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Serialises the user's check-ins, so concurrent ones can't both
+		// pass the checks below and be paid twice
+		if err := tx.Exec("SELECT 1 FROM users WHERE id = ? FOR UPDATE", userID).Error; err != nil {
+			return err
+		}
+
+		var today models.VisitedSpot
+		err := tx.Where("user_id = ? AND spot_id = ? AND verified AND visited_at >= ? AND visited_at < ?",
+			userID, spot.ID, dayStart, dayEnd).
+			First(&today).Error
+		switch {
+		case err == nil:
+			if input.Notes != "" && today.Notes == "" {
+				today.Notes = input.Notes
+				if err := tx.Model(&today).Update("notes", today.Notes).Error; err != nil {
+					return err
+				}
+			}
+			visit = today
+			result.Duplicate = true
+			return nil
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		var earlier int64
+		if err := tx.Model(&models.VisitedSpot{}).
+			Where("user_id = ? AND spot_id = ? AND verified", userID, spot.ID).
+			Count(&earlier).Error; err != nil {
+			return err
+		}
+		visit.FirstVisit = earlier == 0
+		xp := repeatVisitXP
+		if visit.FirstVisit {
+			xp = firstVisitXP
+		}
+
+		if flagged, err = screenVisit(tx, &visit, xp); err != nil {
+			return err
+		}
+		if !flagged {
+			visit.XPAwarded = xp
+			result.XPGained = xp
+		}
+
+		if err := tx.Omit("User", "Spot").Create(&visit).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Spot{}).Where("id = ?", spot.ID).
+			Update("visit_count", gorm.Expr("visit_count + 1")).Error; err != nil {
+			return err
+		}
+		if visit.XPAwarded == 0 {
+			return nil
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Update("xp", gorm.Expr("xp + ?", visit.XPAwarded)).Error
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Visit = visit
+
+	if !flagged && !result.Duplicate {
+		// The visit stands either way
+		if result.NewBadges, err = awardBadges(db, userID); err != nil {
+			log.Printf("Failed to award badges: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// writeVisitResult answers a visit request: 201 for a new visit, 200 for a
+// repeat on the same day, 202 for a visit held for review and 422 for one
+// that failed verification
+func writeVisitResult(w http.ResponseWriter, result VisitResult) {
+	visit := result.Visit
+	response := map[string]any{
+		"message":      "Visited spot added",
+		"xp_gained":    result.XPGained,
+		"first_visit":  visit.FirstVisit,
+		"visited_spot": visit,
+		"new_badges":   badgeResponses(result.NewBadges),
+	}
+	status := http.StatusCreated
+	switch {
+	case !visit.Verified:
+		response["message"] = "Visit could not be verified: " + string(visit.Verification)
+		status = http.StatusUnprocessableEntity
+	case result.Duplicate:
+		response["message"] = "Spot already visited today"
+		status = http.StatusOK
+	case visit.FlagStatus == models.FlagPending:
+		response["message"] = "Visited spot added; its XP is held until the check-in is reviewed"
+		response["xp_escrowed"] = visit.EscrowXP
+		status = http.StatusAccepted
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// visitError reports a recordVisit failure
+func visitError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInvalidTimezone) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Failed to record visit", http.StatusInternalServerError)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTimezone(t *testing.T) {
+	at := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		wantOffset int // seconds east of UTC on at
+		wantErr    bool
+	}{
+		{"Europe/Skopje", 3600, false},
+		{"America/New_York", -5 * 3600, false},
+		{"UTC", 0, false},
+		{"+02:00", 2 * 3600, false},
+		{"-09:30", -(9*3600 + 30*60), false},
+		{"+00:00", 0, false},
+		{"+2", 0, true},
+		{"+25:00", 0, true},
+		{"Mars/Olympus_Mons", 0, true},
+		{"Local", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, err := parseTimezone(tt.name)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidTimezone) {
+					t.Errorf("err = %v, want %v", err, errInvalidTimezone)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, offset := at.In(location).Zone(); offset != tt.wantOffset {
+				t.Errorf("offset = %d, want %d", offset, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestVisitDay(t *testing.T) {
+	zone := func(name string) *time.Location {
+		location, err := parseTimezone(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return location
+	}
+
+	tests := []struct {
+		name      string
+		at        time.Time
+		location  *time.Location
+		wantStart time.Time
+		wantHours float64
+	}{
+		{"UTC midday", time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), time.UTC,
+			time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), 24},
+		// 23:30 UTC is already the next day two hours east
+		{"late UTC evening east of UTC", time.Date(2025, 6, 1, 23, 30, 0, 0, time.UTC), zone("+02:00"),
+			time.Date(2025, 6, 1, 22, 0, 0, 0, time.UTC), 24},
+		{"early UTC morning west of UTC", time.Date(2025, 6, 2, 3, 0, 0, 0, time.UTC), zone("America/New_York"),
+			time.Date(2025, 6, 1, 4, 0, 0, 0, time.UTC), 24},
+		{"exactly midnight", time.Date(2025, 6, 1, 22, 0, 0, 0, time.UTC), zone("+02:00"),
+			time.Date(2025, 6, 1, 22, 0, 0, 0, time.UTC), 24},
+		{"just before midnight", time.Date(2025, 6, 1, 21, 59, 59, 0, time.UTC), zone("+02:00"),
+			time.Date(2025, 5, 31, 22, 0, 0, 0, time.UTC), 24},
+		// Days around a DST change aren't 24 hours long
+		{"spring forward", time.Date(2025, 3, 30, 12, 0, 0, 0, time.UTC), zone("Europe/Skopje"),
+			time.Date(2025, 3, 29, 23, 0, 0, 0, time.UTC), 23},
+		{"fall back", time.Date(2025, 10, 26, 12, 0, 0, 0, time.UTC), zone("Europe/Skopje"),
+			time.Date(2025, 10, 25, 22, 0, 0, 0, time.UTC), 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := visitDay(tt.at, tt.location)
+			if !start.Equal(tt.wantStart) {
+				t.Errorf("start = %s, want %s", start.UTC(), tt.wantStart)
+			}
+			if hours := end.Sub(start).Hours(); hours != tt.wantHours {
+				t.Errorf("day lasts %v hours, want %v", hours, tt.wantHours)
+			}
+			if tt.at.Before(start) || !tt.at.Before(end) {
+				t.Errorf("%s outside [%s, %s)", tt.at, start.UTC(), end.UTC())
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// EXIF tags we care about
+const (
+	tagOrientation = 0x0112
+	tagExifIFD     = 0x8769
+	tagGPSIFD      = 0x8825
+
+	// Exif IFD
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+
+	// GPS IFD
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+	tagGPSTimeStamp    = 0x0007
+	tagGPSDateStamp    = 0x001D
+)
+
+const exifDateLayout = "2006:01:02 15:04:05"
+
+// Metadata is what an upload's EXIF says about where and when it was taken
+type Metadata struct {
+	Latitude  *float64
+	Longitude *float64
+	Altitude  *float64 // meters above sea level
+	// CapturedAt is exact when the camera recorded a GPS time or a UTC
+	// offset. Otherwise it holds the camera's wall clock time labelled as
+	// UTC and CapturedAtLocal is set.
+	CapturedAt      *time.Time
+	CapturedAtLocal bool
+}
+
+var errNoExif = errors.New("no EXIF data")
+
+// exifTags holds the numeric and text values of the IFD0, Exif and GPS
+// directories. Rationals are stored as float64.
+type exifTags struct {
+	ifd0 map[uint16][]any
+	exif map[uint16][]any
+	gps  map[uint16][]any
+}
+
+// findJPEGExif returns the TIFF payload of the APP1 Exif segment of a JPEG
+func findJPEGExif(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errNoExif
+	}
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, errNoExif
+		}
+		marker := data[pos+1]
+		// Start of scan: metadata segments always come before it
+		if marker == 0xDA {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(data) {
+			return nil, errNoExif
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+		pos += 2 + length
+	}
+	return nil, errNoExif
+}
+
+// parseExif reads the EXIF directories of a JPEG file
+func parseExif(data []byte) (*exifTags, error) {
+	tiff, err := findJPEGExif(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(tiff) < 8 {
+		return nil, errNoExif
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errNoExif
+	}
+
+	tags := &exifTags{}
+	tags.ifd0 = readIFD(tiff, order, order.Uint32(tiff[4:8]))
+	if offset, ok := tags.uint(tags.ifd0, tagExifIFD); ok {
+		tags.exif = readIFD(tiff, order, uint32(offset))
+	}
+	if offset, ok := tags.uint(tags.ifd0, tagGPSIFD); ok {
+		tags.gps = readIFD(tiff, order, uint32(offset))
+	}
+	return tags, nil
+}
+
+// Sizes in bytes of the TIFF field types, indexed by type
+var tiffTypeSizes = [...]int{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8}
+
+// readIFD decodes one image file directory. Malformed entries are skipped
+// rather than failing the whole image, since broken EXIF is common.
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) map[uint16][]any {
+	entries := make(map[uint16][]any)
+	if int(offset)+2 > len(tiff) {
+		return entries
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := int(offset) + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+
+		tag := order.Uint16(tiff[entry:])
+		typ := int(order.Uint16(tiff[entry+2:]))
+		n := int(order.Uint32(tiff[entry+4:]))
+		if typ <= 0 || typ >= len(tiffTypeSizes) || n <= 0 || n > 1<<16 {
+			continue
+		}
+
+		size := tiffTypeSizes[typ] * n
+		valueAt := entry + 8
+		if size > 4 {
+			valueAt = int(order.Uint32(tiff[entry+8:]))
+		}
+		if valueAt < 0 || valueAt+size > len(tiff) {
+			continue
+		}
+		raw := tiff[valueAt : valueAt+size]
+
+		var values []any
+		switch typ {
+		case 1, 7: // BYTE, UNDEFINED
+			for _, b := range raw {
+				values = append(values, uint64(b))
+			}
+		case 2: // ASCII
+			values = append(values, string(bytes.TrimRight(raw, "\x00")))
+		case 3: // SHORT
+			for j := 0; j < n; j++ {
+				values = append(values, uint64(order.Uint16(raw[j*2:])))
+			}
+		case 4: // LONG
+			for j := 0; j < n; j++ {
+				values = append(values, uint64(order.Uint32(raw[j*4:])))
+			}
+		case 5: // RATIONAL
+			for j := 0; j < n; j++ {
+				num, den := order.Uint32(raw[j*8:]), order.Uint32(raw[j*8+4:])
+				if den != 0 {
+					values = append(values, float64(num)/float64(den))
+				}
+			}
+		case 9: // SLONG
+			for j := 0; j < n; j++ {
+				values = append(values, int64(int32(order.Uint32(raw[j*4:]))))
+			}
+		case 10: // SRATIONAL
+			for j := 0; j < n; j++ {
+				num, den := int32(order.Uint32(raw[j*8:])), int32(order.Uint32(raw[j*8+4:]))
+				if den != 0 {
+					values = append(values, float64(num)/float64(den))
+				}
+			}
+		default:
+			continue
+		}
+		entries[tag] = values
+	}
+	return entries
+}
+
+func (t *exifTags) uint(ifd map[uint16][]any, tag uint16) (uint64, bool) {
+	values := ifd[tag]
+	if len(values) == 0 {
+		return 0, false
+	}
+	v, ok := values[0].(uint64)
+	return v, ok
+}
+
+// orientation returns the EXIF orientation (1-8), defaulting to 1 (upright)
+func (t *exifTags) orientation() int {
+	if t == nil {
+		return 1
+	}
+	v, ok := t.uint(t.ifd0, tagOrientation)
+	if !ok || v < 1 || v > 8 {
+		return 1
+	}
+	return int(v)
+}
+
+func (t *exifTags) floats(ifd map[uint16][]any, tag uint16) []float64 {
+	var values []float64
+	for _, value := range ifd[tag] {
+		switch v := value.(type) {
+		case float64:
+			values = append(values, v)
+		case uint64:
+			values = append(values, float64(v))
+		case int64:
+			values = append(values, float64(v))
+		}
+	}
+	return values
+}
+
+func (t *exifTags) string(ifd map[uint16][]any, tag uint16) string {
+	values := ifd[tag]
+	if len(values) == 0 {
+		return ""
+	}
+	v, _ := values[0].(string)
+	return strings.TrimSpace(v)
+}
+
+// coordinate reads a GPS latitude or longitude given as degrees, minutes and
+// seconds plus a hemisphere reference
+func (t *exifTags) coordinate(tag, refTag uint16, negativeRef string, limit float64) (*float64, bool) {
+	dms := t.floats(t.gps, tag)
+	if len(dms) != 3 {
+		return nil, false
+	}
+	value := dms[0] + dms[1]/60 + dms[2]/3600
+	if t.string(t.gps, refTag) == negativeRef {
+		value = -value
+	}
+	if value < -limit || value > limit {
+		return nil, false
+	}
+	return &value, true
+}
+
+// metadata extracts the position and capture time, leaving out whatever is
+// missing or malformed
+func (t *exifTags) metadata() Metadata {
+	var meta Metadata
+	if t == nil {
+		return meta
+	}
+
+	lat, latOK := t.coordinate(tagGPSLatitude, tagGPSLatitudeRef, "S", 90)
+	lng, lngOK := t.coordinate(tagGPSLongitude, tagGPSLongitudeRef, "W", 180)
+	// Cameras without a fix often write 0,0
+	if latOK && lngOK && (*lat != 0 || *lng != 0) {
+		meta.Latitude, meta.Longitude = lat, lng
+	}
+
+	if altitude := t.floats(t.gps, tagGPSAltitude); len(altitude) == 1 {
+		value := altitude[0]
+		if ref, ok := t.uint(t.gps, tagGPSAltitudeRef); ok && ref == 1 {
+			value = -value
+		}
+		meta.Altitude = &value
+	}
+
+	// GPS time is always UTC, so it wins over the camera clock
+	date := t.string(t.gps, tagGPSDateStamp)
+	clock := t.floats(t.gps, tagGPSTimeStamp)
+	if date != "" && len(clock) == 3 {
+		if day, err := time.Parse("2006:01:02", date); err == nil {
+			captured := day.Add(time.Duration(clock[0]*float64(time.Hour) +
+				clock[1]*float64(time.Minute) +
+				clock[2]*float64(time.Second)))
+			meta.CapturedAt = &captured
+			return meta
+		}
+	}
+
+	original := t.string(t.exif, tagDateTimeOriginal)
+	if original == "" {
+		return meta
+	}
+	if offset := t.string(t.exif, tagOffsetTimeOriginal); offset != "" {
+		if captured, err := time.Parse(exifDateLayout+"-07:00", original+offset); err == nil {
+			captured = captured.UTC()
+			meta.CapturedAt = &captured
+			return meta
+		}
+	}
+	if captured, err := time.Parse(exifDateLayout, original); err == nil {
+		meta.CapturedAt = &captured
+		meta.CapturedAtLocal = true
+	}
+	return meta
+}
@@ -0,0 +1,459 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+// tiffField is one IFD entry, its value already encoded in the file's byte
+// order. Values over 4 bytes are moved out of the entry by buildTIFF.
+type tiffField struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+type tiffEncoder struct{ order binary.ByteOrder }
+
+func (e tiffEncoder) bytes(tag uint16, values ...byte) tiffField {
+	return tiffField{tag, 1, uint32(len(values)), values}
+}
+
+func (e tiffEncoder) ascii(tag uint16, s string) tiffField {
+	return tiffField{tag, 2, uint32(len(s) + 1), append([]byte(s), 0)}
+}
+
+func (e tiffEncoder) shorts(tag uint16, values ...uint16) tiffField {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		e.order.PutUint16(b[2*i:], v)
+	}
+	return tiffField{tag, 3, uint32(len(values)), b}
+}
+
+func (e tiffEncoder) longs(tag uint16, values ...uint32) tiffField {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		e.order.PutUint32(b[4*i:], v)
+	}
+	return tiffField{tag, 4, uint32(len(values)), b}
+}
+
+func (e tiffEncoder) slongs(tag uint16, values ...int32) tiffField {
+	f := e.longs(tag, signed(values)...)
+	f.typ = 9
+	return f
+}
+
+// rationals takes numerator, denominator pairs
+func (e tiffEncoder) rationals(tag uint16, pairs ...uint32) tiffField {
+	f := e.longs(tag, pairs...)
+	f.typ, f.count = 5, uint32(len(pairs)/2)
+	return f
+}
+
+func (e tiffEncoder) srationals(tag uint16, pairs ...int32) tiffField {
+	f := e.rationals(tag, signed(pairs)...)
+	f.typ = 10
+	return f
+}
+
+func signed(values []int32) []uint32 {
+	u := make([]uint32, len(values))
+	for i, v := range values {
+		u[i] = uint32(v)
+	}
+	return u
+}
+
+// buildTIFF lays out a TIFF header, IFD0 and, when given, the Exif and GPS
+// directories with IFD0 pointing at them
+func buildTIFF(order binary.ByteOrder, ifd0, exif, gps []tiffField) []byte {
+	ifdSize := func(fields []tiffField) int { return 2 + 12*len(fields) + 4 }
+	pointers := 0
+	if exif != nil {
+		pointers++
+	}
+	if gps != nil {
+		pointers++
+	}
+
+	at := 8 + ifdSize(ifd0) + 12*pointers
+	e := tiffEncoder{order}
+	ifd0 = append([]tiffField(nil), ifd0...)
+	exifAt, gpsAt := at, at
+	if exif != nil {
+		ifd0 = append(ifd0, e.longs(tagExifIFD, uint32(exifAt)))
+		gpsAt += ifdSize(exif)
+	}
+	if gps != nil {
+		ifd0 = append(ifd0, e.longs(tagGPSIFD, uint32(gpsAt)))
+	}
+
+	buf := make([]byte, gpsAt+ifdSize(gps))
+	if order == binary.LittleEndian {
+		copy(buf, "II")
+	} else {
+		copy(buf, "MM")
+	}
+	order.PutUint16(buf[2:], 42)
+	order.PutUint32(buf[4:], 8)
+
+	write := func(at int, fields []tiffField) {
+		order.PutUint16(buf[at:], uint16(len(fields)))
+		for i, f := range fields {
+			entry := at + 2 + 12*i
+			order.PutUint16(buf[entry:], f.tag)
+			order.PutUint16(buf[entry+2:], f.typ)
+			order.PutUint32(buf[entry+4:], f.count)
+			if len(f.value) <= 4 {
+				copy(buf[entry+8:], f.value)
+				continue
+			}
+			order.PutUint32(buf[entry+8:], uint32(len(buf)))
+			buf = append(buf, f.value...)
+		}
+	}
+	write(8, ifd0)
+	if exif != nil {
+		write(exifAt, exif)
+	}
+	if gps != nil {
+		write(gpsAt, gps)
+	}
+	return buf
+}
+
+// jpegWithExif wraps a TIFF payload in an APP1 segment of a bare JPEG
+func jpegWithExif(tiff []byte) []byte {
+	return jpegWithSegments(segment(0xE1, append([]byte("Exif\x00\x00"), tiff...)))
+}
+
+func segment(marker byte, payload []byte) []byte {
+	s := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(2+len(payload)))
+	return append(s, payload...)
+}
+
+func jpegWithSegments(segments ...[]byte) []byte {
+	data := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		data = append(data, s...)
+	}
+	return append(data, 0xFF, 0xD9)
+}
+
+func TestFindJPEGExif(t *testing.T) {
+	tiff := buildTIFF(binary.BigEndian, nil, nil, nil)
+	app1 := segment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+	jfif := segment(0xE0, []byte("JFIF\x00\x01\x02"))
+	xmp := segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>"))
+	scan := []byte{0xFF, 0xDA, 0x00, 0x08}
+
+	overrun := append([]byte(nil), app1...)
+	binary.BigEndian.PutUint16(overrun[2:], uint16(len(app1)+10))
+	tooShort := []byte{0xFF, 0xE0, 0x00, 0x01}
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool // whether the TIFF payload is found
+	}{
+		{"right after SOI", jpegWithSegments(app1), true},
+		{"after other segments", jpegWithSegments(jfif, xmp, app1), true},
+		{"none", jpegWithSegments(jfif), false},
+		{"after the scan starts", jpegWithSegments(jfif, scan, app1), false},
+		{"not a JPEG", append([]byte{0x89, 'P', 'N', 'G'}, app1...), false},
+		{"too short", []byte{0xFF, 0xD8}, false},
+		{"segment past the end", jpegWithSegments(overrun), false},
+		{"segment length under 2", jpegWithSegments(tooShort, app1), false},
+		{"garbage between segments", jpegWithSegments(jfif, []byte{0x00}, app1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findJPEGExif(tt.data)
+			switch {
+			case tt.want && string(got) != string(tiff):
+				t.Errorf("found %x, err %v; want %x", got, err, tiff)
+			case !tt.want && !errors.Is(err, errNoExif):
+				t.Errorf("found %x, err %v; want %v", got, err, errNoExif)
+			}
+		})
+	}
+}
+
+func TestParseExif(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			e := tiffEncoder{order}
+			tiff := buildTIFF(order,
+				[]tiffField{
+					e.shorts(tagOrientation, 8),
+					e.ascii(0x010F, "Camera Maker"),
+					e.slongs(0x9201, -7),
+				},
+				[]tiffField{
+					e.ascii(tagDateTimeOriginal, "2025:06:01 18:30:00"),
+					e.srationals(0x9204, -1, 3),
+				},
+				[]tiffField{
+					e.bytes(0x0000, 2, 3, 0, 0),
+					e.ascii(tagGPSLatitudeRef, "S"),
+					e.rationals(tagGPSLatitude, 33, 1, 52, 1, 12, 1),
+					e.ascii(tagGPSLongitudeRef, "W"),
+					e.rationals(tagGPSLongitude, 70, 1, 30, 1, 0, 1),
+					e.bytes(tagGPSAltitudeRef, 1),
+					e.rationals(tagGPSAltitude, 5, 2),
+				})
+
+			tags, err := parseExif(jpegWithExif(tiff))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tags.orientation(); got != 8 {
+				t.Errorf("orientation %d, want 8", got)
+			}
+			if got := tags.string(tags.ifd0, 0x010F); got != "Camera Maker" {
+				t.Errorf("make %q", got)
+			}
+			if got := tags.floats(tags.ifd0, 0x9201); len(got) != 1 || got[0] != -7 {
+				t.Errorf("SLONG %v, want [-7]", got)
+			}
+			if got := tags.floats(tags.exif, 0x9204); len(got) != 1 || got[0] != -1.0/3 {
+				t.Errorf("SRATIONAL %v, want [-1/3]", got)
+			}
+			if got := tags.floats(tags.gps, 0x0000); len(got) != 4 || got[0] != 2 || got[1] != 3 {
+				t.Errorf("BYTE %v, want [2 3 0 0]", got)
+			}
+
+			meta := tags.metadata()
+			if meta.Latitude == nil || math.Abs(*meta.Latitude-(-33.87)) > 1e-9 {
+				t.Errorf("latitude %v, want -33.87", meta.Latitude)
+			}
+			if meta.Longitude == nil || *meta.Longitude != -70.5 {
+				t.Errorf("longitude %v, want -70.5", meta.Longitude)
+			}
+			if meta.Altitude == nil || *meta.Altitude != -2.5 {
+				t.Errorf("altitude %v, want -2.5", meta.Altitude)
+			}
+			want := time.Date(2025, 6, 1, 18, 30, 0, 0, time.UTC)
+			if meta.CapturedAt == nil || !meta.CapturedAt.Equal(want) || !meta.CapturedAtLocal {
+				t.Errorf("captured at %v (local %v), want %s local", meta.CapturedAt, meta.CapturedAtLocal, want)
+			}
+		})
+	}
+
+	t.Run("fixture", func(t *testing.T) {
+		data, err := os.ReadFile("testdata/rotated.jpg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tags, err := parseExif(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tags.orientation(); got != 6 {
+			t.Errorf("orientation %d, want 6", got)
+		}
+	})
+
+	for name, tiff := range map[string][]byte{
+		"unknown byte order": append([]byte("XX"), buildTIFF(binary.BigEndian, nil, nil, nil)[2:]...),
+		"truncated header":   []byte("MM\x00\x2A\x00"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseExif(jpegWithExif(tiff)); !errors.Is(err, errNoExif) {
+				t.Errorf("err = %v, want %v", err, errNoExif)
+			}
+		})
+	}
+}
+
+func TestReadIFD(t *testing.T) {
+	order := binary.LittleEndian
+	e := tiffEncoder{order}
+	valid := e.shorts(tagOrientation, 3)
+
+	t.Run("offset past the end", func(t *testing.T) {
+		tiff := buildTIFF(order, []tiffField{valid}, nil, nil)
+		for _, offset := range []uint32{uint32(len(tiff)) - 1, uint32(len(tiff)), math.MaxUint32} {
+			if entries := readIFD(tiff, order, offset); len(entries) != 0 {
+				t.Errorf("offset %d read %v", offset, entries)
+			}
+		}
+	})
+
+	t.Run("truncated directory", func(t *testing.T) {
+		tiff := buildTIFF(order, []tiffField{valid, e.shorts(0x0128, 2)}, nil, nil)
+		// Claims more entries than there are, and cuts the second in half
+		order.PutUint16(tiff[8:], 40)
+		entries := readIFD(tiff[:8+2+12+6], order, 8)
+		if len(entries) != 1 || entries[tagOrientation][0] != uint64(3) {
+			t.Errorf("read %v, want only the orientation", entries)
+		}
+	})
+
+	t.Run("malformed entries are skipped", func(t *testing.T) {
+		zeroType := e.shorts(0x0001, 1)
+		zeroType.typ = 0
+		unknownType := e.shorts(0x0002, 1)
+		unknownType.typ = 13
+		noValues := e.shorts(0x0003, 1)
+		noValues.count = 0
+		tooMany := e.shorts(0x0004, 1)
+		tooMany.count = 1<<16 + 1
+		// Claims more values than the data past the entry holds
+		overrun := e.longs(0x0005, 1, 2)
+		overrun.count = 1 << 12
+		zeroDenominator := e.rationals(0x0006, 1, 0)
+
+		tiff := buildTIFF(order, []tiffField{
+			zeroType, unknownType, noValues, tooMany, overrun, zeroDenominator, valid,
+		}, nil, nil)
+		entries := readIFD(tiff, order, 8)
+		for _, tag := range []uint16{0x0001, 0x0002, 0x0003, 0x0004, 0x0005} {
+			if values, ok := entries[tag]; ok {
+				t.Errorf("tag %#04x read as %v", tag, values)
+			}
+		}
+		if values := entries[0x0006]; len(values) != 0 {
+			t.Errorf("rational over zero read as %v", values)
+		}
+		if entries[tagOrientation][0] != uint64(3) {
+			t.Errorf("valid entry lost: %v", entries)
+		}
+	})
+
+	t.Run("bad directory pointers", func(t *testing.T) {
+		tiff := buildTIFF(order, []tiffField{valid, e.longs(tagExifIFD, 1<<20), e.longs(tagGPSIFD, 0xFFFFFFF0)}, nil, nil)
+		tags, err := parseExif(jpegWithExif(tiff))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tags.exif) != 0 || len(tags.gps) != 0 || tags.orientation() != 3 {
+			t.Errorf("parsed %+v", tags)
+		}
+		if meta := tags.metadata(); meta != (Metadata{}) {
+			t.Errorf("metadata %+v", meta)
+		}
+	})
+}
+
+func TestMetadata(t *testing.T) {
+	e := tiffEncoder{binary.BigEndian}
+	skopje := []tiffField{
+		e.ascii(tagGPSLatitudeRef, "N"),
+		e.rationals(tagGPSLatitude, 41, 1, 59, 1, 5316, 100),
+		e.ascii(tagGPSLongitudeRef, "E"),
+		e.rationals(tagGPSLongitude, 21, 1, 25, 1, 3144, 100),
+	}
+	gpsTime := []tiffField{
+		e.ascii(tagGPSDateStamp, "2025:06:01"),
+		e.rationals(tagGPSTimeStamp, 16, 1, 29, 1, 305, 10),
+	}
+	original := e.ascii(tagDateTimeOriginal, "2025:06:01 18:30:00")
+	offset := e.ascii(tagOffsetTimeOriginal, "+02:00")
+	with := func(fields ...[]tiffField) []tiffField {
+		var all []tiffField
+		for _, f := range fields {
+			all = append(all, f...)
+		}
+		return all
+	}
+	date := func(hour, min, sec int, nsec int) *time.Time {
+		d := time.Date(2025, 6, 1, hour, min, sec, nsec, time.UTC)
+		return &d
+	}
+
+	tests := []struct {
+		name      string
+		exif, gps []tiffField
+		wantPlace bool
+		wantTime  *time.Time
+		wantLocal bool
+	}{
+		{"GPS time wins", []tiffField{original, offset}, with(skopje, gpsTime), true, date(16, 29, 30, 5e8), false},
+		{"offset time", []tiffField{original, offset}, skopje, true, date(16, 30, 0, 0), false},
+		{"local time", []tiffField{original}, skopje, true, date(18, 30, 0, 0), true},
+		{"bad offset falls back to local", []tiffField{original, e.ascii(tagOffsetTimeOriginal, "CEST")}, nil, false, date(18, 30, 0, 0), true},
+		{"bad GPS date falls back", []tiffField{original, offset}, []tiffField{e.ascii(tagGPSDateStamp, "June 1"), gpsTime[1]}, false, date(16, 30, 0, 0), false},
+		{"unset camera clock", []tiffField{e.ascii(tagDateTimeOriginal, "    :  :     :  :  ")}, skopje, true, nil, false},
+		{"no fix at 0,0", nil, []tiffField{
+			e.ascii(tagGPSLatitudeRef, "N"), e.rationals(tagGPSLatitude, 0, 1, 0, 1, 0, 1),
+			e.ascii(tagGPSLongitudeRef, "E"), e.rationals(tagGPSLongitude, 0, 1, 0, 1, 0, 1),
+		}, false, nil, false},
+		{"latitude out of range", nil, []tiffField{
+			e.ascii(tagGPSLatitudeRef, "N"), e.rationals(tagGPSLatitude, 91, 1, 0, 1, 0, 1),
+			skopje[2], skopje[3],
+		}, false, nil, false},
+		{"latitude without minutes", nil, []tiffField{
+			e.rationals(tagGPSLatitude, 41, 1), skopje[2], skopje[3],
+		}, false, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, err := parseExif(jpegWithExif(buildTIFF(binary.BigEndian, nil, tt.exif, tt.gps)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			meta := tags.metadata()
+
+			if placed := meta.Latitude != nil && meta.Longitude != nil; placed != tt.wantPlace {
+				t.Errorf("position %v, %v", meta.Latitude, meta.Longitude)
+			}
+			if tt.wantPlace && (math.Abs(*meta.Latitude-41.9981) > 1e-9 || math.Abs(*meta.Longitude-21.4254) > 1e-9) {
+				t.Errorf("position %v, %v; want 41.9981, 21.4254", *meta.Latitude, *meta.Longitude)
+			}
+			switch {
+			case (meta.CapturedAt == nil) != (tt.wantTime == nil),
+				tt.wantTime != nil && !meta.CapturedAt.Equal(*tt.wantTime),
+				meta.CapturedAtLocal != tt.wantLocal:
+				t.Errorf("captured at %v (local %v), want %v (local %v)", meta.CapturedAt, meta.CapturedAtLocal, tt.wantTime, tt.wantLocal)
+			}
+		})
+	}
+
+	var none *exifTags
+	if none.orientation() != 1 || none.metadata() != (Metadata{}) {
+		t.Error("missing EXIF isn't upright and empty")
+	}
+}
+
+func FuzzParseExif(f *testing.F) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		e := tiffEncoder{order}
+		f.Add(buildTIFF(order,
+			[]tiffField{e.shorts(tagOrientation, 6)},
+			[]tiffField{e.ascii(tagDateTimeOriginal, "2025:06:01 18:30:00"), e.ascii(tagOffsetTimeOriginal, "+02:00")},
+			[]tiffField{
+				e.ascii(tagGPSLatitudeRef, "N"), e.rationals(tagGPSLatitude, 41, 1, 59, 1, 5316, 100),
+				e.ascii(tagGPSLongitudeRef, "E"), e.rationals(tagGPSLongitude, 21, 1, 25, 1, 3144, 100),
+				e.ascii(tagGPSDateStamp, "2025:06:01"), e.rationals(tagGPSTimeStamp, 16, 1, 30, 1, 0, 1),
+			}))
+	}
+
+	f.Fuzz(func(t *testing.T, tiff []byte) {
+		if len(tiff) > 0xFFFF-8 {
+			return
+		}
+		tags, err := parseExif(jpegWithExif(tiff))
+		if err != nil {
+			return
+		}
+		if o := tags.orientation(); o < 1 || o > 8 {
+			t.Errorf("orientation %d", o)
+		}
+		meta := tags.metadata()
+		if meta.Latitude != nil && math.Abs(*meta.Latitude) > 90 {
+			t.Errorf("latitude %v", *meta.Latitude)
+		}
+		if meta.Longitude != nil && math.Abs(*meta.Longitude) > 180 {
+			t.Errorf("longitude %v", *meta.Longitude)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package imaging
+
+import (
+	"image"
+	"io"
+	"math/bits"
+
+	"golang.org/x/image/draw"
+)
+
+// DHash is a 64 bit difference hash: the image is shrunk to 9x8 grey pixels
+// and each bit tells whether a pixel is brighter than its right neighbour.
+// Re-encoded, resized or slightly edited copies of a photo hash alike.
+func DHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HashDistance is the number of differing bits between two hashes. Copies of
+// the same photo are usually within a few bits of each other.
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Hash decodes a stored or uploaded image and returns its DHash
+func Hash(r io.Reader) (uint64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+
+	img, _, err := decode(data)
+	if err != nil {
+		return 0, err
+	}
+	return DHash(img), nil
+}
@@ -0,0 +1,303 @@
+// Package imaging validates uploaded photos and turns them into the resized,
+// metadata-free renditions that are actually served.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// Decoding larger images could exhaust memory (decompression bombs)
+const maxPixels = 50_000_000
+
+const jpegQuality = 85
+
+var ErrUnsupportedImage = errors.New("unsupported image type")
+var ErrImageTooLarge = errors.New("image dimensions are too large")
+
+type Rendition struct {
+	Name    string
+	MaxSize int // longest edge in pixels
+}
+
+// Renditions generated for every upload, smallest first
+var Renditions = []Rendition{
+	{Name: "thumb", MaxSize: 320},
+	{Name: "medium", MaxSize: 1024},
+	{Name: "full", MaxSize: 2048},
+}
+
+const (
+	FormatJPEG = "jpg"
+	FormatWebP = "webp"
+)
+
+var Formats = []string{FormatJPEG, FormatWebP}
+
+// Processed holds the encoded files of one upload, keyed by rendition name
+// then format.
+type Processed struct {
+	Files map[string]map[string][]byte
+	// Perceptual hash of the upright image, see DHash
+	Hash uint64
+	// Raw EXIF of the original, read before it was stripped
+	exif *exifTags
+}
+
+type decoder func(io.Reader) (image.Image, error)
+
+// Only these sniffed content types are accepted, whatever the file name says
+var decoders = map[string]decoder{
+	"image/jpeg": jpeg.Decode,
+	"image/png":  png.Decode,
+	"image/gif":  gif.Decode,
+	"image/webp": webp.Decode,
+}
+
+var configDecoders = map[string]func(io.Reader) (image.Config, error){
+	"image/jpeg": jpeg.DecodeConfig,
+	"image/png":  png.DecodeConfig,
+	"image/gif":  gif.DecodeConfig,
+	"image/webp": webp.DecodeConfig,
+}
+
+// Process validates the upload and builds every rendition in every format.
+// Re-encoding from decoded pixels drops all metadata, EXIF GPS included; the
+// EXIF orientation is applied to the pixels first so photos stay upright.
+func Process(r io.Reader) (*Processed, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	img, exif, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	processed := &Processed{
+		Files: make(map[string]map[string][]byte),
+		Hash:  DHash(img),
+		exif:  exif,
+	}
+
+	for _, rendition := range Renditions {
+		resized := fit(img, rendition.MaxSize)
+
+		var jpegBuf bytes.Buffer
+		if err := jpeg.Encode(&jpegBuf, flatten(resized), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+
+		var webpBuf bytes.Buffer
+		if err := nativewebp.Encode(&webpBuf, unpalette(resized), nil); err != nil {
+			return nil, err
+		}
+
+		processed.Files[rendition.Name] = map[string][]byte{
+			FormatJPEG: jpegBuf.Bytes(),
+			FormatWebP: webpBuf.Bytes(),
+		}
+	}
+
+	return processed, nil
+}
+
+// decode sniffs and decodes an upload, refusing anything that is not one of
+// the accepted types or would be too large to hold in memory. The returned
+// image is already rotated upright.
+func decode(data []byte) (image.Image, *exifTags, error) {
+	contentType := http.DetectContentType(data)
+	decodeImage, ok := decoders[contentType]
+	if !ok {
+		return nil, nil, ErrUnsupportedImage
+	}
+
+	config, err := configDecoders[contentType](bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrUnsupportedImage
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, nil, ErrImageTooLarge
+	}
+
+	img, err := decodeImage(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrUnsupportedImage
+	}
+
+	var exif *exifTags
+	if contentType == "image/jpeg" {
+		exif, _ = parseExif(data)
+	}
+	return applyOrientation(img, exif.orientation()), exif, nil
+}
+
+// Metadata is the position and capture time recorded in the original's EXIF.
+// None of it ends up in the stored files.
+func (p *Processed) Metadata() Metadata {
+	return p.exif.metadata()
+}
+
+// fit scales img down so its longest edge is at most maxSize. Images are
+// never scaled up.
+func fit(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSize && h <= maxSize {
+		return img
+	}
+
+	if w >= h {
+		h = max(1, h*maxSize/w)
+		w = maxSize
+	} else {
+		w = max(1, w*maxSize/h)
+		h = maxSize
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// flatten composites transparent images onto white, since JPEG has no alpha
+func flatten(img image.Image) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
+}
+
+// unpalette expands paletted images, such as GIFs small enough to skip
+// resizing: nativewebp writes undecodable files from them
+func unpalette(img image.Image) image.Image {
+	if _, ok := img.(*image.Paletted); !ok {
+		return img
+	}
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+// applyOrientation rotates/flips img according to the EXIF orientation value
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	// Orientations 5-8 swap width and height
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored horizontally, rotated 270 CW
+				dx, dy = y, x
+			case 6: // rotated 90 CW
+				dx, dy = h-1-y, x
+			case 7: // mirrored horizontally, rotated 90 CW
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 270 CW
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// Filename is the stored file of one rendition of an upload named base
+func Filename(base, rendition, format string) string {
+	return fmt.Sprintf("%s_%s.%s", base, rendition, format)
+}
+
+// PrimaryFilename is the name kept in the database for an upload: the full
+// size JPEG, which older clients can display as is.
+func PrimaryFilename(base string) string {
+	return Filename(base, "full", FormatJPEG)
+}
+
+// baseName recovers the upload base from a primary filename. Files stored
+// before the pipeline existed have no renditions and report false.
+func baseName(filename string) (string, bool) {
+	suffix := "_full." + FormatJPEG
+	if !strings.HasSuffix(filename, suffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(filename, suffix), true
+}
+
+// AllFilenames lists every file stored for the primary filename
+func AllFilenames(filename string) []string {
+	base, ok := baseName(filename)
+	if !ok {
+		return []string{filename}
+	}
+
+	var names []string
+	for _, rendition := range Renditions {
+		for _, format := range Formats {
+			names = append(names, Filename(base, rendition.Name, format))
+		}
+	}
+	return names
+}
+
+type RenditionURLs struct {
+	JPEG string `json:"jpeg"`
+	WebP string `json:"webp"`
+}
+
+type ImageURLs struct {
+	Thumb  RenditionURLs `json:"thumb"`
+	Medium RenditionURLs `json:"medium"`
+	Full   RenditionURLs `json:"full"`
+}
+
+// URLs returns the URL of each rendition of the primary filename under
+// prefix. Legacy uploads point every rendition at the original file.
+func URLs(prefix, filename string) ImageURLs {
+	base, ok := baseName(filename)
+	url := func(rendition, format string) string {
+		if !ok {
+			return prefix + filename
+		}
+		return prefix + Filename(base, rendition, format)
+	}
+
+	return ImageURLs{
+		Thumb:  RenditionURLs{JPEG: url("thumb", FormatJPEG), WebP: url("thumb", FormatWebP)},
+		Medium: RenditionURLs{JPEG: url("medium", FormatJPEG), WebP: url("medium", FormatWebP)},
+		Full:   RenditionURLs{JPEG: url("full", FormatJPEG), WebP: url("full", FormatWebP)},
+	}
+}
@@ -0,0 +1,233 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+// The fixtures are 48x24, red on the left and blue on the right, except
+// transparent.png (16x16, fully transparent), huge.png (a 1x1 PNG whose
+// header claims 10000x10000) and page.jpg (HTML). rotated.jpg carries
+// big-endian EXIF: orientation 6, GPS 41°59'53.16"N 21°25'31.44"E at 250 m,
+// taken 2025:06:01 18:30:00 +02:00.
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func process(t *testing.T, data []byte) *Processed {
+	t.Helper()
+	processed, err := Process(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return processed
+}
+
+func decodeJPEG(t *testing.T, processed *Processed, rendition string) image.Image {
+	t.Helper()
+	img, err := jpeg.Decode(bytes.NewReader(processed.Files[rendition][FormatJPEG]))
+	if err != nil {
+		t.Fatalf("%s JPEG: %v", rendition, err)
+	}
+	return img
+}
+
+// near reports whether c is within a JPEG's error of want
+func near(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	close := func(got uint32, want uint8) bool {
+		return math.Abs(float64(got>>8)-float64(want)) < 48
+	}
+	return close(r, want.R) && close(g, want.G) && close(b, want.B)
+}
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	white = color.RGBA{255, 255, 255, 255}
+)
+
+// pngHeader is huge.png claiming to be w x h
+func pngHeader(t *testing.T, w, h uint32) []byte {
+	data := readFixture(t, "huge.png")
+	binary.BigEndian.PutUint32(data[16:], w)
+	binary.BigEndian.PutUint32(data[20:], h)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestProcessSniffsContent(t *testing.T) {
+	tests := []struct {
+		fixture string
+		wantErr error
+	}{
+		{"upright.jpg", nil},
+		{"transparent.png", nil},
+		{"halves.gif", nil},
+		{"halves.webp", nil},
+		// The name doesn't matter, only what the bytes are
+		{"page.jpg", ErrUnsupportedImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			_, err := Process(bytes.NewReader(readFixture(t, tt.fixture)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A JPEG signature on a broken body
+	broken := readFixture(t, "upright.jpg")[:20]
+	if _, err := Process(bytes.NewReader(broken)); !errors.Is(err, ErrUnsupportedImage) {
+		t.Errorf("truncated JPEG: err = %v, want %v", err, ErrUnsupportedImage)
+	}
+}
+
+func TestProcessAppliesOrientation(t *testing.T) {
+	upright := decodeJPEG(t, process(t, readFixture(t, "upright.jpg")), "thumb")
+	if size := upright.Bounds().Size(); size != image.Pt(48, 24) {
+		t.Fatalf("upright photo is %v", size)
+	}
+
+	rotated := decodeJPEG(t, process(t, readFixture(t, "rotated.jpg")), "thumb")
+	if size := rotated.Bounds().Size(); size != image.Pt(24, 48) {
+		t.Fatalf("rotated photo is %v, want 24x48", size)
+	}
+	// Turned 90° clockwise, the left half ends up on top
+	if top, bottom := rotated.At(12, 6), rotated.At(12, 42); !near(top, red) || !near(bottom, blue) {
+		t.Errorf("top %v, bottom %v; want red over blue", top, bottom)
+	}
+}
+
+func TestProcessMetadata(t *testing.T) {
+	meta := process(t, readFixture(t, "rotated.jpg")).Metadata()
+
+	if meta.Latitude == nil || meta.Longitude == nil {
+		t.Fatal("no position")
+	}
+	if math.Abs(*meta.Latitude-41.9981) > 1e-6 || math.Abs(*meta.Longitude-21.4254) > 1e-6 {
+		t.Errorf("position %v, %v", *meta.Latitude, *meta.Longitude)
+	}
+	if meta.Altitude == nil || *meta.Altitude != 250 {
+		t.Errorf("altitude %v", meta.Altitude)
+	}
+	want := time.Date(2025, 6, 1, 16, 30, 0, 0, time.UTC)
+	if meta.CapturedAt == nil || !meta.CapturedAt.Equal(want) || meta.CapturedAtLocal {
+		t.Errorf("captured at %v (local %v), want %s", meta.CapturedAt, meta.CapturedAtLocal, want)
+	}
+
+	if meta := process(t, readFixture(t, "upright.jpg")).Metadata(); meta != (Metadata{}) {
+		t.Errorf("metadata without EXIF: %+v", meta)
+	}
+}
+
+func TestProcessPixelLimit(t *testing.T) {
+	if _, err := Process(bytes.NewReader(readFixture(t, "huge.png"))); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("err = %v, want %v", err, ErrImageTooLarge)
+	}
+
+	// Exactly at the limit the header passes, and the missing pixels fail
+	// the decode instead
+	if _, err := Process(bytes.NewReader(pngHeader(t, 10000, 5000))); !errors.Is(err, ErrUnsupportedImage) {
+		t.Errorf("at the limit: err = %v, want %v", err, ErrUnsupportedImage)
+	}
+	if _, err := Process(bytes.NewReader(pngHeader(t, 10000, 5001))); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("over the limit: err = %v, want %v", err, ErrImageTooLarge)
+	}
+}
+
+func TestProcessRenditions(t *testing.T) {
+	encode := func(w, h int) []byte {
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for i := range img.Pix {
+			img.Pix[i] = 0x80
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want map[string]image.Point
+	}{
+		{"landscape", encode(3000, 1200), map[string]image.Point{
+			"thumb": {320, 128}, "medium": {1024, 409}, "full": {2048, 819},
+		}},
+		{"portrait", encode(600, 1500), map[string]image.Point{
+			"thumb": {128, 320}, "medium": {409, 1024}, "full": {600, 1500},
+		}},
+		// Never scaled up
+		{"small", readFixture(t, "upright.jpg"), map[string]image.Point{
+			"thumb": {48, 24}, "medium": {48, 24}, "full": {48, 24},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processed := process(t, tt.data)
+			if len(processed.Files) != len(Renditions) {
+				t.Errorf("%d renditions, want %d", len(processed.Files), len(Renditions))
+			}
+			for _, rendition := range Renditions {
+				want := tt.want[rendition.Name]
+				if size := decodeJPEG(t, processed, rendition.Name).Bounds().Size(); size != want {
+					t.Errorf("%s JPEG is %v, want %v", rendition.Name, size, want)
+				}
+				config, err := webp.DecodeConfig(bytes.NewReader(processed.Files[rendition.Name][FormatWebP]))
+				if err != nil {
+					t.Fatalf("%s WebP: %v", rendition.Name, err)
+				}
+				if size := image.Pt(config.Width, config.Height); size != want {
+					t.Errorf("%s WebP is %v, want %v", rendition.Name, size, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessWebP(t *testing.T) {
+	processed := process(t, readFixture(t, "halves.gif"))
+	img, err := webp.Decode(bytes.NewReader(processed.Files["full"][FormatWebP]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lossless, so the pixels come through exactly
+	if left, right := img.At(5, 12), img.At(40, 12); left != color.Color(color.NRGBA(red)) || right != color.Color(color.NRGBA(blue)) {
+		t.Errorf("left %v, right %v; want red and blue", left, right)
+	}
+
+	// WebP keeps transparency, the JPEG is flattened onto white
+	processed = process(t, readFixture(t, "transparent.png"))
+	img, err = webp.Decode(bytes.NewReader(processed.Files["thumb"][FormatWebP]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := img.At(8, 8).RGBA(); a != 0 {
+		t.Errorf("WebP alpha %d, want transparent", a)
+	}
+	if c := decodeJPEG(t, processed, "thumb").At(8, 8); !near(c, white) {
+		t.Errorf("JPEG pixel %v, want white", c)
+	}
+}
@@ -5,10 +5,11 @@ import (
 	"chillspot-backend/internal/middleware"
 	"chillspot-backend/internal/models"
 	"chillspot-backend/internal/routes"
+	"chillspot-backend/internal/storage"
+	"chillspot-backend/internal/weather"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 
 	"github.com/joho/godotenv"
 )
@@ -22,6 +23,7 @@ func main() {
 
 	err := database.AutoMigrate(
 		&models.User{},
+		&models.Category{},
 		&models.Spot{},
 		&models.Review{},
 		&models.Badge{},
@@ -29,50 +31,47 @@ func main() {
 		&models.Like{},
 		&models.FriendRequest{},
 		&models.BadgeDefinition{},
+		&models.SpotRevision{},
+		&models.SpotPhoto{},
+		&models.Tag{},
+		&models.SpotTag{},
+		&models.AttributeSuggestion{},
+		&models.SpotDiscovery{},
+		&models.VisitFlag{},
+		&models.VisitPhoto{},
+		&models.VisitCompanion{},
 	)
 	if err != nil {
 		log.Fatal("Migration failed:", err)
 	}
 
+	if err := db.RunMigrations(database); err != nil {
+		log.Fatal("Migration failed:", err)
+	}
+
 	err = godotenv.Load()
 	if err != nil {
 		log.Println("Warning: No .env file found - using default environment variables")
 	}
 
-	r := routes.SetupRoutes(database)
-
-	// Get absolute path to uploads directory - FIXED PATH
-	wd, _ := os.Getwd()
-
-	// Go up one directory level to get out of the internal folder
-	projectRoot := filepath.Dir(wd)
-
-	// Correct path to uploads directory
-	uploadsPath := filepath.Join(projectRoot, "internal", "uploads")
-	imagesPath := filepath.Join(projectRoot, "internal", "images")
-	log.Printf("Images directory: %s", imagesPath)
-
-	// Create images directory if it doesn't exist
-	if _, err := os.Stat(imagesPath); os.IsNotExist(err) {
-		if err := os.MkdirAll(imagesPath, 0755); err != nil {
-			log.Fatalf("Failed to create images directory: %v", err)
-		}
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatal("Failed to set up storage:", err)
 	}
-	log.Printf("Current working directory: %s", wd)
-	log.Printf("Project root: %s", projectRoot)
-	log.Printf("Uploads directory: %s", uploadsPath)
 
-	// Create uploads directory if it doesn't exist
-	if _, err := os.Stat(uploadsPath); os.IsNotExist(err) {
-		if err := os.MkdirAll(uploadsPath, 0755); err != nil {
-			log.Fatalf("Failed to create uploads directory: %v", err)
-		}
+	forecasts, err := weather.FromEnv()
+	if err != nil {
+		log.Fatal("Failed to set up weather provider:", err)
 	}
 
-	// Serve static files
-	staticHandler := http.StripPrefix("/uploads/", http.FileServer(http.Dir(uploadsPath)))
-	r.PathPrefix("/uploads/").Handler(middleware.CorsMiddleware(staticHandler))
-	imagesHandler := http.StripPrefix("/images/", http.FileServer(http.Dir(imagesPath)))
+	r := routes.SetupRoutes(database, store, forecasts)
+
+	// Serve uploaded files, or with STORAGE_REDIRECT=true send clients straight
+	// to presigned URLs when the backend supports them
+	redirect := os.Getenv("STORAGE_REDIRECT") == "true"
+	uploadsHandler := http.StripPrefix("/uploads/", storage.Handler(store, storage.UploadsPrefix, redirect))
+	r.PathPrefix("/uploads/").Handler(middleware.CorsMiddleware(uploadsHandler))
+	imagesHandler := http.StripPrefix("/images/", storage.Handler(store, storage.ImagesPrefix, redirect))
 	r.PathPrefix("/images/").Handler(middleware.CorsMiddleware(imagesHandler))
 	log.Println("Server is running on :8080")
 	log.Fatal(http.ListenAndServe(":8080", r))
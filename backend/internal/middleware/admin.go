@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"chillspot-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AdminMiddleware only lets admins through. It must run after AuthMiddleware.
+func AdminMiddleware(db *gorm.DB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value("user_id").(string)
+			if !ok || userID == "" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			var user models.User
+			if err := db.Select("id", "is_admin").Where("id = ?", userID).First(&user).Error; err != nil || !user.IsAdmin {
+				http.Error(w, "Admin access required", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
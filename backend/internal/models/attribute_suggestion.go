@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SuggestionStatus string
+
+const (
+	SuggestionPending  SuggestionStatus = "pending"
+	SuggestionAccepted SuggestionStatus = "accepted"
+	SuggestionRejected SuggestionStatus = "rejected"
+)
+
+// AttributeSuggestion is a visitor's correction of a spot's attributes. Only
+// the known (non-nil) attributes are proposed; the spot's owner accepts or
+// rejects the suggestion as a whole.
+type AttributeSuggestion struct {
+	ID         uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpotID     uuid.UUID        `gorm:"type:uuid;not null;index" json:"spot_id"`
+	UserID     uuid.UUID        `gorm:"type:uuid;not null" json:"user_id"`
+	User       User             `gorm:"foreignKey:UserID" json:"user"`
+	Comment    string           `gorm:"type:varchar(500)" json:"comment"`
+	Status     SuggestionStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	ReviewedAt *time.Time       `json:"reviewed_at"`
+	CreatedAt  time.Time        `json:"created_at"`
+
+	Attributes SpotAttributes `gorm:"embedded;embeddedPrefix:proposed_" json:"attributes"`
+}
+
+func (s *AttributeSuggestion) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
@@ -13,6 +13,8 @@ const (
 	BadgeSpots   BadgeType = "spots"
 	BadgeFriends BadgeType = "friends"
 	BadgeLikes   BadgeType = "likes"
+	// BadgeDiscoveries counts the geocache spots a user revealed
+	BadgeDiscoveries BadgeType = "discoveries"
 )
 
 type BadgeDefinition struct {
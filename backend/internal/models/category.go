@@ -0,0 +1,46 @@
+package models
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Category is one entry of the admin-curated spot taxonomy. Icon names a
+// Material icon the client renders.
+type Category struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Slug      string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"slug"`
+	Name      string    `gorm:"type:varchar(100);not null" json:"name"`
+	Icon      string    `gorm:"type:varchar(100);not null;default:''" json:"icon"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (c *Category) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+var categorySlugPattern = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// ValidCategorySlug reports whether slug is lowercase words joined by underscores
+func ValidCategorySlug(slug string) bool {
+	return len(slug) <= 50 && categorySlugPattern.MatchString(slug)
+}
+
+// DefaultCategories seed the taxonomy; admins can edit or extend it afterwards
+var DefaultCategories = []Category{
+	{Slug: "viewpoint", Name: "Viewpoint", Icon: "landscape", Position: 0},
+	{Slug: "lake", Name: "Lake", Icon: "water", Position: 1},
+	{Slug: "waterfall", Name: "Waterfall", Icon: "waves", Position: 2},
+	{Slug: "campsite", Name: "Campsite", Icon: "cabin", Position: 3},
+	{Slug: "beach", Name: "Beach", Icon: "beach_access", Position: 4},
+	{Slug: "forest", Name: "Forest", Icon: "forest", Position: 5},
+	{Slug: "urban_rooftop", Name: "Urban rooftop", Icon: "location_city", Position: 6},
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"chillspot-backend/internal/geo"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Hint radius bounds for geocache spots, in meters
+const (
+	DefaultHintRadius = 500
+	MinHintRadius     = 100
+	MaxHintRadius     = 5000
+	MaxClueLength     = 300
+)
+
+// SpotDiscovery records that a user reached a geocache spot and revealed it
+type SpotDiscovery struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_spot_discovery_user" json:"user_id"`
+	SpotID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_spot_discovery_user" json:"spot_id"`
+	Spot         Spot      `gorm:"foreignKey:SpotID;constraint:OnDelete:CASCADE" json:"spot"`
+	XPGained     int       `gorm:"not null;default:0" json:"xp_gained"`
+	DiscoveredAt time.Time `gorm:"not null" json:"discovered_at"`
+}
+
+func (d *SpotDiscovery) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return
+}
+
+// placeHint picks a random hint circle center the spot lies within, unless
+// the current one still contains it. The center stays well inside the
+// circle so the spot is never right at its edge either.
+func (s *Spot) placeHint() {
+	if s.HintRadius == 0 {
+		s.HintRadius = DefaultHintRadius
+	}
+	radius := float64(s.HintRadius)
+	if (s.HintLatitude != 0 || s.HintLongitude != 0) &&
+		geo.Distance(s.Latitude, s.Longitude, s.HintLatitude, s.HintLongitude) <= radius {
+		return
+	}
+
+	// sqrt keeps the points uniform over the disk rather than bunched at its center
+	distance := 0.8 * radius * math.Sqrt(rand.Float64())
+	bearing := 2 * math.Pi * rand.Float64()
+	s.HintLatitude, s.HintLongitude = geo.Offset(s.Latitude, s.Longitude,
+		distance*math.Cos(bearing), distance*math.Sin(bearing))
+}
+
+// Conceal hides everything that would give an undiscovered geocache spot
+// away, leaving the hint circle and the clue. A concealed spot must never be
+// saved.
+func (s *Spot) Conceal() {
+	s.Latitude, s.Longitude = s.HintLatitude, s.HintLongitude
+	s.Altitude = 0
+	s.Title = ""
+	s.Description = ""
+	s.DayImage, s.NightImage = nil, nil
+	s.Images = SpotImages{}
+	s.NightImageAfterSunset = nil
+	s.LocationMismatch = false
+	s.Concealed = true
+}
@@ -1,40 +1,153 @@
 package models
 
 import (
+	"errors"
 	"time"
 
+	"chillspot-backend/internal/geo"
+	"chillspot-backend/internal/imaging"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-type WeatherCondition string
+type Spot struct {
+	ID                    uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID                uuid.UUID         `gorm:"type:uuid;not null"`
+	User                  User              `gorm:"foreignKey:UserID"`
+	Latitude              float64           `gorm:"type:double precision;not null"`
+	Longitude             float64           `gorm:"type:double precision;not null"`
+	Altitude              float64           `gorm:"type:double precision;not null;default:0"`
+	Title                 string            `gorm:"type:varchar(100);not null"`
+	Description           string            `gorm:"type:text;not null"`
+	DayImage              *string           `gorm:"type:text"`
+	NightImage            *string           `gorm:"type:text"`
+	RecommendedWeather    WeatherCondition  `gorm:"type:varchar(100)"` // first of RecommendedWeathers, for older clients
+	RecommendedWeathers   WeatherConditions `gorm:"type:text[]" json:"recommended_weathers"`
+	CategoryID            *uuid.UUID        `gorm:"type:uuid;index" json:"category_id"`
+	Category              *Category         `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL" json:"category,omitempty"`
+	VisitCount            uint              `gorm:"default:0"`
+	FavoritesCount        uint              `gorm:"default:0" json:"favorites_count"`
+	Geohash               string            `gorm:"type:varchar(12)" json:"-"`
+	Visibility            Visibility        `gorm:"type:varchar(20);not null;default:'public';index" json:"visibility"`
+	FuzzLocation          bool              `gorm:"not null;default:false" json:"fuzz_location"` // only show an approximate area until the viewer is close
+	LocationFuzzed        bool              `gorm:"-" json:"location_fuzzed"`                    // set when Latitude and Longitude were fuzzed for this viewer
+	Geocache              bool              `gorm:"not null;default:false" json:"geocache"`      // hidden until a user reaches it
+	HintRadius            uint              `gorm:"not null;default:0" json:"hint_radius"`       // meters around HintLatitude/HintLongitude
+	HintLatitude          float64           `gorm:"type:double precision;not null;default:0" json:"hint_latitude"`
+	HintLongitude         float64           `gorm:"type:double precision;not null;default:0" json:"hint_longitude"`
+	Clue                  *string           `gorm:"type:varchar(300)" json:"clue"`
+	Concealed             bool              `gorm:"-" json:"concealed"`                              // set when the spot was concealed for this viewer
+	LocationMismatch      bool              `gorm:"not null;default:false" json:"location_mismatch"` // EXIF position far from the coordinates
+	NightImageAfterSunset *bool             `json:"night_image_after_sunset"`                        // per the night image's EXIF; nil if unknown
+	Images                SpotImages        `gorm:"-" json:"images"`
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
 
-const (
-	Sunny  WeatherCondition = "sunny"
-	Rainy  WeatherCondition = "rainy"
-	Cloudy WeatherCondition = "cloudy"
-	Snowy  WeatherCondition = "snowy"
-)
+	SpotAttributes
+}
 
-type Spot struct {
-	ID                 uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	UserID             uuid.UUID        `gorm:"type:uuid;not null"`
-	User               User             `gorm:"foreignKey:UserID"`
-	Latitude           float64          `gorm:"type:double precision;not null"`
-	Longitude          float64          `gorm:"type:double precision;not null"`
-	Altitude           float64          `gorm:"type:double precision;not null;default:0"`
-	Title              string           `gorm:"type:varchar(100);not null"`
-	Description        string           `gorm:"type:text;not null"`
-	DayImage           *string          `gorm:"type:text"`
-	NightImage         *string          `gorm:"type:text"`
-	RecommendedWeather WeatherCondition `gorm:"type:varchar(100)"`
-	VisitCount         uint             `gorm:"default:0"`
-	FavoritesCount     uint             `gorm:"default:0" json:"favorites_count"`
-	CreatedAt          time.Time
-	UpdatedAt          time.Time
+// SpotImages exposes the URL of every rendition of the day and night images
+type SpotImages struct {
+	Day   *imaging.ImageURLs `json:"day"`
+	Night *imaging.ImageURLs `json:"night"`
+}
+
+// FillImageURLs computes Images from DayImage and NightImage. It runs after
+// every Find; code that Scans spots must call it itself.
+func (s *Spot) FillImageURLs() {
+	s.Images = SpotImages{}
+	if s.DayImage != nil && *s.DayImage != "" {
+		urls := imaging.URLs("/images/", *s.DayImage)
+		s.Images.Day = &urls
+	}
+	if s.NightImage != nil && *s.NightImage != "" {
+		urls := imaging.URLs("/images/", *s.NightImage)
+		s.Images.Night = &urls
+	}
+}
+
+// SuitsWeather reports whether the spot recommends visiting in the condition
+func (s *Spot) SuitsWeather(condition WeatherCondition) bool {
+	for _, recommended := range s.RecommendedWeathers {
+		if recommended == AnyWeather || recommended == condition {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *Spot) BeforeCreate(tx *gorm.DB) (err error) {
 	s.ID = uuid.New()
 	return nil
 }
+
+func (s *Spot) AfterFind(tx *gorm.DB) (err error) {
+	s.FillImageURLs()
+	return nil
+}
+
+func (s *Spot) BeforeSave(tx *gorm.DB) (err error) {
+	if s.LocationFuzzed {
+		return errors.New("a spot with a fuzzed location can't be saved")
+	}
+	if s.Concealed {
+		return errors.New("a concealed spot can't be saved")
+	}
+	if s.Visibility == "" {
+		s.Visibility = Public
+	}
+	s.Geohash = geo.Encode(s.Latitude, s.Longitude, geo.MaxPrecision)
+	if s.Geocache {
+		s.placeHint()
+	}
+	if len(s.RecommendedWeathers) == 0 && s.RecommendedWeather != "" {
+		s.RecommendedWeathers = WeatherConditions{s.RecommendedWeather}
+	}
+	s.RecommendedWeathers = s.RecommendedWeathers.Normalize()
+	s.RecommendedWeather = s.RecommendedWeathers.Primary()
+	return nil
+}
+
+// Columns bumped on every visit and like. They aren't part of a revision.
+var spotCounterColumns = map[string]bool{
+	"visit_count":     true,
+	"VisitCount":      true,
+	"favorites_count": true,
+	"FavoritesCount":  true,
+}
+
+// updatesCountersOnly reports whether the statement is an Update or Updates
+// with a map that sets nothing but counters, which can skip the revision
+// lookup and its lock on the spot.
+func updatesCountersOnly(tx *gorm.DB) bool {
+	updates, ok := tx.Statement.Dest.(map[string]any)
+	if !ok || len(updates) == 0 {
+		return false
+	}
+	for column := range updates {
+		if !spotCounterColumns[column] {
+			return false
+		}
+	}
+	return true
+}
+
+// BeforeUpdate snapshots the stored row first, so the previous state is kept
+// even for spots created before revisions existed or changed outside GORM.
+func (s *Spot) BeforeUpdate(tx *gorm.DB) (err error) {
+	// Bulk counter updates (Model(&Spot{}).Where(...)) carry no ID and never
+	// touch the tracked fields.
+	if s.ID == uuid.Nil || updatesCountersOnly(tx) {
+		return nil
+	}
+	return recordSpotRevision(tx, s.ID, nil)
+}
+
+func (s *Spot) AfterSave(tx *gorm.DB) (err error) {
+	s.FillImageURLs()
+	if s.ID == uuid.Nil || updatesCountersOnly(tx) {
+		return nil
+	}
+	return recordSpotRevision(tx, s.ID, revisionAuthor(tx))
+}
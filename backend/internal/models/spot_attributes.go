@@ -0,0 +1,247 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Difficulty string
+
+const (
+	Easy     Difficulty = "easy"
+	Moderate Difficulty = "moderate"
+	Hard     Difficulty = "hard"
+	Expert   Difficulty = "expert"
+)
+
+// Difficulties are ordered from easiest to hardest
+var Difficulties = []Difficulty{Easy, Moderate, Hard, Expert}
+
+type Parking string
+
+const (
+	NoParking      Parking = "none"
+	LimitedParking Parking = "limited"
+	AmpleParking   Parking = "ample"
+)
+
+var ParkingOptions = []Parking{NoParking, LimitedParking, AmpleParking}
+
+const (
+	maxHikeMinutes    = 24 * 60
+	maxFeeNoteLength  = 200
+	allMonths         = Months(1<<12 - 1)
+	monthAbbreviation = 3
+)
+
+func ValidDifficulty(difficulty Difficulty) bool {
+	for _, d := range Difficulties {
+		if d == difficulty {
+			return true
+		}
+	}
+	return false
+}
+
+// Rank is the position of the difficulty in Difficulties, or -1
+func (d Difficulty) Rank() int {
+	for i, difficulty := range Difficulties {
+		if difficulty == d {
+			return i
+		}
+	}
+	return -1
+}
+
+func ValidParking(parking Parking) bool {
+	for _, p := range ParkingOptions {
+		if p == parking {
+			return true
+		}
+	}
+	return false
+}
+
+// Months is a set of months of the year, bit 0 being January. It is stored as
+// an integer and encoded in JSON as a list of month numbers (1-12).
+type Months uint16
+
+// Has reports whether month is in the set
+func (m Months) Has(month time.Month) bool {
+	return month >= time.January && month <= time.December && m&(1<<(month-1)) != 0
+}
+
+func (m Months) List() []int {
+	months := []int{}
+	for month := time.January; month <= time.December; month++ {
+		if m.Has(month) {
+			months = append(months, int(month))
+		}
+	}
+	return months
+}
+
+func (m Months) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.List())
+}
+
+func (m *Months) UnmarshalJSON(data []byte) error {
+	var months []int
+	if err := json.Unmarshal(data, &months); err != nil {
+		return err
+	}
+	*m = 0
+	for _, month := range months {
+		if month < 1 || month > 12 {
+			return fmt.Errorf("invalid month %d", month)
+		}
+		*m |= 1 << (month - 1)
+	}
+	return nil
+}
+
+// ParseMonth reads a month number (1-12) or an English name or its first
+// three letters
+func ParseMonth(value string) (time.Month, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Month(n), n >= 1 && n <= 12
+	}
+	if len(value) < monthAbbreviation {
+		return 0, false
+	}
+	for month := time.January; month <= time.December; month++ {
+		if strings.HasPrefix(strings.ToLower(month.String()), value) {
+			return month, true
+		}
+	}
+	return 0, false
+}
+
+// ParseMonths reads a comma separated list of months
+func ParseMonths(value string) (Months, error) {
+	var months Months
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		month, ok := ParseMonth(part)
+		if !ok {
+			return 0, fmt.Errorf("invalid month %q", strings.TrimSpace(part))
+		}
+		months |= 1 << (month - 1)
+	}
+	return months, nil
+}
+
+// SpotAttributes are the practical facts about getting to and staying at a
+// spot. Nil fields (and empty BestMonths) are unknown.
+type SpotAttributes struct {
+	AccessDifficulty     *Difficulty `gorm:"type:varchar(20)" json:"access_difficulty"`
+	HikeMinutes          *int        `json:"hike_minutes"` // walk from the parking
+	Parking              *Parking    `gorm:"type:varchar(20)" json:"parking"`
+	DogFriendly          *bool       `json:"dog_friendly"`
+	WheelchairAccessible *bool       `json:"wheelchair_accessible"`
+	WaterSource          *bool       `json:"water_source"`
+	CampingAllowed       *bool       `json:"camping_allowed"`
+	FeeRequired          *bool       `json:"fee_required"`
+	FeeNote              *string     `gorm:"type:varchar(200)" json:"fee_note"` // e.g. "5 EUR per car"
+	BestMonths           Months      `gorm:"not null;default:0" json:"best_months"`
+}
+
+// Validate checks every known attribute
+func (a *SpotAttributes) Validate() error {
+	switch {
+	case a.AccessDifficulty != nil && !ValidDifficulty(*a.AccessDifficulty):
+		return errors.New("Invalid access_difficulty")
+	case a.HikeMinutes != nil && (*a.HikeMinutes < 0 || *a.HikeMinutes > maxHikeMinutes):
+		return errors.New("hike_minutes must be between 0 and " + strconv.Itoa(maxHikeMinutes))
+	case a.Parking != nil && !ValidParking(*a.Parking):
+		return errors.New("Invalid parking")
+	case a.FeeNote != nil && len(*a.FeeNote) > maxFeeNoteLength:
+		return errors.New("fee_note must be at most " + strconv.Itoa(maxFeeNoteLength) + " characters")
+	case a.BestMonths&^allMonths != 0:
+		return errors.New("Invalid best_months")
+	}
+	return nil
+}
+
+// Merge copies the known attributes of other onto a
+func (a *SpotAttributes) Merge(other SpotAttributes) {
+	if other.AccessDifficulty != nil {
+		a.AccessDifficulty = other.AccessDifficulty
+	}
+	if other.HikeMinutes != nil {
+		a.HikeMinutes = other.HikeMinutes
+	}
+	if other.Parking != nil {
+		a.Parking = other.Parking
+	}
+	if other.DogFriendly != nil {
+		a.DogFriendly = other.DogFriendly
+	}
+	if other.WheelchairAccessible != nil {
+		a.WheelchairAccessible = other.WheelchairAccessible
+	}
+	if other.WaterSource != nil {
+		a.WaterSource = other.WaterSource
+	}
+	if other.CampingAllowed != nil {
+		a.CampingAllowed = other.CampingAllowed
+	}
+	if other.FeeRequired != nil {
+		a.FeeRequired = other.FeeRequired
+	}
+	if other.FeeNote != nil {
+		a.FeeNote = other.FeeNote
+	}
+	if other.BestMonths != 0 {
+		a.BestMonths = other.BestMonths
+	}
+}
+
+// IsEmpty reports whether no attribute is known
+func (a *SpotAttributes) IsEmpty() bool {
+	return *a == SpotAttributes{}
+}
+
+func boolValue(b *bool) any {
+	if b == nil {
+		return nil
+	}
+	return *b
+}
+
+func intValue(i *int) any {
+	if i == nil {
+		return nil
+	}
+	return *i
+}
+
+// fields lists the attributes for revision diffs; unknown values are nil
+func (a *SpotAttributes) fields() []FieldChange {
+	var difficulty, parking any
+	if a.AccessDifficulty != nil {
+		difficulty = *a.AccessDifficulty
+	}
+	if a.Parking != nil {
+		parking = *a.Parking
+	}
+	return []FieldChange{
+		{Field: "access_difficulty", New: difficulty},
+		{Field: "hike_minutes", New: intValue(a.HikeMinutes)},
+		{Field: "parking", New: parking},
+		{Field: "dog_friendly", New: boolValue(a.DogFriendly)},
+		{Field: "wheelchair_accessible", New: boolValue(a.WheelchairAccessible)},
+		{Field: "water_source", New: boolValue(a.WaterSource)},
+		{Field: "camping_allowed", New: boolValue(a.CampingAllowed)},
+		{Field: "fee_required", New: boolValue(a.FeeRequired)},
+		{Field: "fee_note", New: stringValue(a.FeeNote)},
+		{Field: "best_months", New: a.BestMonths.List()},
+	}
+}
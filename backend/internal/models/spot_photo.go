@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"chillspot-backend/internal/imaging"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Season string
+
+const (
+	Spring Season = "spring"
+	Summer Season = "summer"
+	Autumn Season = "autumn"
+	Winter Season = "winter"
+)
+
+var Seasons = []Season{Spring, Summer, Autumn, Winter}
+
+type TimeOfDay string
+
+const (
+	Sunrise TimeOfDay = "sunrise"
+	Day     TimeOfDay = "day"
+	Sunset  TimeOfDay = "sunset"
+	Night   TimeOfDay = "night"
+)
+
+var TimesOfDay = []TimeOfDay{Sunrise, Day, Sunset, Night}
+
+// SpotPhoto is one picture in a spot's gallery. Photos are shown by Position
+// ascending; the cover photo, chosen by the spot owner, is also mirrored into
+// the spot's DayImage or NightImage so older clients keep showing it.
+type SpotPhoto struct {
+	ID         uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpotID     uuid.UUID         `gorm:"type:uuid;not null;index:idx_spot_photo_position" json:"spot_id"`
+	UserID     uuid.UUID         `gorm:"type:uuid;not null" json:"user_id"`
+	Filename   string            `gorm:"type:text;not null" json:"-"`
+	Hash       *int64            `json:"-"` // imaging.DHash bits, nil until computed
+	Caption    string            `gorm:"type:varchar(500)" json:"caption"`
+	Season     Season            `gorm:"type:varchar(20)" json:"season"`
+	TimeOfDay  TimeOfDay         `gorm:"type:varchar(20)" json:"time_of_day"`
+	CapturedAt *time.Time        `json:"captured_at"`
+	Position   int               `gorm:"not null;default:0;index:idx_spot_photo_position" json:"position"`
+	IsCover    bool              `gorm:"not null;default:false" json:"is_cover"`
+	Images     imaging.ImageURLs `gorm:"-" json:"images"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+func (p *SpotPhoto) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
+
+func (p *SpotPhoto) AfterFind(tx *gorm.DB) (err error) {
+	p.Images = imaging.URLs("/images/", p.Filename)
+	return nil
+}
+
+func (p *SpotPhoto) AfterSave(tx *gorm.DB) (err error) {
+	p.Images = imaging.URLs("/images/", p.Filename)
+	return nil
+}
+
+// SetHash stores an imaging.DHash value; Postgres has no unsigned bigint
+func (p *SpotPhoto) SetHash(hash uint64) {
+	value := int64(hash)
+	p.Hash = &value
+}
+
+// IsNight reports whether the photo belongs in the spot's NightImage slot
+func (p *SpotPhoto) IsNight() bool {
+	return p.TimeOfDay == Night
+}
+
+func ValidSeason(season Season) bool {
+	for _, s := range Seasons {
+		if s == season {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidTimeOfDay(timeOfDay TimeOfDay) bool {
+	for _, t := range TimesOfDay {
+		if t == timeOfDay {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SpotRevision is a snapshot of a spot's editable fields after a write.
+// Revisions are numbered per spot starting at 1.
+type SpotRevision struct {
+	ID                  uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SpotID              uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex:idx_spot_revision_number" json:"spot_id"`
+	Number              int               `gorm:"not null;uniqueIndex:idx_spot_revision_number" json:"number"`
+	AuthorID            *uuid.UUID        `gorm:"type:uuid" json:"author_id"` // nil when the change wasn't made by a user
+	Title               string            `gorm:"type:varchar(100);not null" json:"title"`
+	Description         string            `gorm:"type:text;not null" json:"description"`
+	Latitude            float64           `gorm:"type:double precision;not null" json:"latitude"`
+	Longitude           float64           `gorm:"type:double precision;not null" json:"longitude"`
+	Altitude            float64           `gorm:"type:double precision;not null;default:0" json:"altitude"`
+	RecommendedWeather  WeatherCondition  `gorm:"type:varchar(100)" json:"recommended_weather"`
+	RecommendedWeathers WeatherConditions `gorm:"type:text[]" json:"recommended_weathers"`
+	CategoryID          *uuid.UUID        `gorm:"type:uuid" json:"category_id"`
+	DayImage            *string           `gorm:"type:text" json:"day_image"`
+	NightImage          *string           `gorm:"type:text" json:"night_image"`
+	CreatedAt           time.Time         `json:"created_at"`
+
+	SpotAttributes
+}
+
+func (sr *SpotRevision) BeforeCreate(tx *gorm.DB) (err error) {
+	if sr.ID == uuid.Nil {
+		sr.ID = uuid.New()
+	}
+	return
+}
+
+// FieldChange is one entry of a revision diff
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func uuidValue(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// fields lists the tracked values in a stable order
+func (sr *SpotRevision) fields() []FieldChange {
+	fields := []FieldChange{
+		{Field: "title", New: sr.Title},
+		{Field: "description", New: sr.Description},
+		{Field: "latitude", New: sr.Latitude},
+		{Field: "longitude", New: sr.Longitude},
+		{Field: "altitude", New: sr.Altitude},
+		{Field: "recommended_weathers", New: sr.RecommendedWeathers.Normalize()},
+		{Field: "category_id", New: uuidValue(sr.CategoryID)},
+		{Field: "day_image", New: stringValue(sr.DayImage)},
+		{Field: "night_image", New: stringValue(sr.NightImage)},
+	}
+	return append(fields, sr.SpotAttributes.fields()...)
+}
+
+// Diff returns the fields that differ between prev and sr. A nil prev
+// compares against an empty spot, so every set field shows up.
+func (sr *SpotRevision) Diff(prev *SpotRevision) []FieldChange {
+	if prev == nil {
+		prev = &SpotRevision{}
+	}
+
+	before := prev.fields()
+	changes := []FieldChange{}
+	for i, after := range sr.fields() {
+		if !reflect.DeepEqual(before[i].New, after.New) {
+			changes = append(changes, FieldChange{Field: after.Field, Old: before[i].New, New: after.New})
+		}
+	}
+	return changes
+}
+
+// ApplyTo copies the snapshot back onto the spot
+func (sr *SpotRevision) ApplyTo(s *Spot) {
+	s.Title = sr.Title
+	s.Description = sr.Description
+	s.Latitude = sr.Latitude
+	s.Longitude = sr.Longitude
+	s.Altitude = sr.Altitude
+	s.RecommendedWeather = sr.RecommendedWeather
+	s.RecommendedWeathers = sr.RecommendedWeathers
+	s.CategoryID = sr.CategoryID
+	s.SpotAttributes = sr.SpotAttributes
+	s.DayImage = sr.DayImage
+	s.NightImage = sr.NightImage
+}
+
+func snapshotSpot(s *Spot) SpotRevision {
+	return SpotRevision{
+		SpotID:              s.ID,
+		Title:               s.Title,
+		Description:         s.Description,
+		Latitude:            s.Latitude,
+		Longitude:           s.Longitude,
+		Altitude:            s.Altitude,
+		RecommendedWeather:  s.RecommendedWeather,
+		RecommendedWeathers: s.RecommendedWeathers,
+		CategoryID:          s.CategoryID,
+		SpotAttributes:      s.SpotAttributes,
+		DayImage:            s.DayImage,
+		NightImage:          s.NightImage,
+	}
+}
+
+// revisionAuthor reads the acting user from the statement context, which
+// handlers pass along with db.WithContext(r.Context()).
+func revisionAuthor(tx *gorm.DB) *uuid.UUID {
+	userID, ok := tx.Statement.Context.Value("user_id").(string)
+	if !ok {
+		return nil
+	}
+	authorID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil
+	}
+	return &authorID
+}
+
+// recordSpotRevision stores the spot's current row as a new revision unless
+// it matches the latest one. The row is re-read so that partial updates
+// (Updates with a map) are captured as they were written, and locked so that
+// concurrent writes to the spot take the next numbers in turn.
+func recordSpotRevision(tx *gorm.DB, spotID uuid.UUID, author *uuid.UUID) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+
+	var current Spot
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", spotID).First(&current).Error; err != nil {
+		return err
+	}
+	revision := snapshotSpot(&current)
+	revision.AuthorID = author
+
+	var latest SpotRevision
+	err := db.Where("spot_id = ?", spotID).Order("number DESC").First(&latest).Error
+	switch {
+	case err == nil:
+		if len(revision.Diff(&latest)) == 0 {
+			return nil
+		}
+		revision.Number = latest.Number + 1
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		revision.Number = 1
+	default:
+		return err
+	}
+
+	return db.Create(&revision).Error
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxTagLength   = 30
+	MaxTagsPerSpot = 20
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+// Tag is a free-form label users attach to spots. Names are normalized, so
+// "#Sun Set" and "sun-set" are the same tag.
+type Tag struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name      string    `gorm:"type:varchar(30);uniqueIndex;not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (t *Tag) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
+
+// SpotTag links a spot to a tag and remembers who added it
+type SpotTag struct {
+	SpotID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"spot_id"`
+	TagID     uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"tag_id"`
+	Tag       Tag       `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE" json:"tag"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NormalizeTag lowercases a tag, drops a leading '#' and joins its words
+// with hyphens. Only letters and digits may remain within the words.
+func NormalizeTag(name string) (string, error) {
+	words := strings.FieldsFunc(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#")), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == '_'
+	})
+	normalized := strings.Join(words, "-")
+
+	if normalized == "" || len([]rune(normalized)) > MaxTagLength {
+		return "", ErrInvalidTag
+	}
+	for _, r := range normalized {
+		if r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return "", ErrInvalidTag
+		}
+	}
+	return normalized, nil
+}
+
+// ParseTags normalizes comma separated tags, dropping duplicates
+func ParseTags(values ...string) ([]string, error) {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			tag, err := NormalizeTag(part)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, nil
+}
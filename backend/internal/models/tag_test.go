@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    []string
+		wantErr error
+	}{
+		{"nothing", nil, []string{}, nil},
+		{"blank entries", []string{" , ,", ""}, []string{}, nil},
+		{"one tag", []string{"lake"}, []string{"lake"}, nil},
+		{"case and hash", []string{"#Sunset"}, []string{"sunset"}, nil},
+		{"words joined with hyphens", []string{"  Sun  Set ", "sun_set", "sun--set"}, []string{"sun-set"}, nil},
+		{"stray separators", []string{"-_pine  forest_-"}, []string{"pine-forest"}, nil},
+		// Duplicates go, whichever form or value they came in, first one first
+		{"comma separated and repeated", []string{"lake, Forest", "forest,#lake,view"}, []string{"lake", "forest", "view"}, nil},
+		{"letters of any script", []string{"Езеро", "café", "2024"}, []string{"езеро", "café", "2024"}, nil},
+		{"longest tag", []string{strings.Repeat("a", MaxTagLength)}, []string{strings.Repeat("a", MaxTagLength)}, nil},
+		// The limit is in characters, not bytes
+		{"longest tag in Cyrillic", []string{strings.Repeat("я", MaxTagLength)}, []string{strings.Repeat("я", MaxTagLength)}, nil},
+		{"too long", []string{strings.Repeat("a", MaxTagLength+1)}, nil, ErrInvalidTag},
+		{"too long once joined", []string{strings.Repeat("a", 15) + " " + strings.Repeat("b", 15)}, nil, ErrInvalidTag},
+		{"punctuation", []string{"sun!set"}, nil, ErrInvalidTag},
+		{"emoji", []string{"sunset 🌅"}, nil, ErrInvalidTag},
+		{"only a hash", []string{"#"}, nil, ErrInvalidTag},
+		{"one bad tag fails them all", []string{"lake", "forest,sun.set"}, nil, ErrInvalidTag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTags(tt.values...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			// An empty list must not be nil, so it encodes as []
+			if !slices.Equal(got, tt.want) || (tt.want != nil && got == nil) {
+				t.Errorf("ParseTags(%q) = %q, want %q", tt.values, got, tt.want)
+			}
+		})
+	}
+}
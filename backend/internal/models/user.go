@@ -12,6 +12,8 @@ type User struct {
 	Email      string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"email"`
 	ProfilePic *string   `gorm:"type:text" json:"profile_pic"` // Optional
 	XP         int       `gorm:"default:0" json:"xp"`
+	IsAdmin    bool      `gorm:"not null;default:false" json:"is_admin"`                  // granted directly in the database
+	Timezone   string    `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"` // IANA name or UTC offset such as +02:00
 	Favorites  []Spot    `gorm:"many2many:user_favorites;"`
 	Friends    []*User   `gorm:"many2many:user_friends;"`
 
@@ -103,11 +105,12 @@ func (u *User) DeclineFriendRequest(db *gorm.DB, requestID uuid.UUID) error {
 	return db.Model(&friendRequest).Update("status", "declined").Error
 }
 
+// GetPendingFriendRequests returns the requests sent to u. The caller orders
+// and pages them through db.
 func (u *User) GetPendingFriendRequests(db *gorm.DB) ([]FriendRequest, error) {
 	var requests []FriendRequest
 	err := db.Where("receiver_id = ? AND status = 'pending'", u.ID).
 		Preload("Sender").
-		Order("created_at DESC").
 		Find(&requests).Error
 	return requests, err
 }
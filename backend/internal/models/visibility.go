@@ -0,0 +1,46 @@
+package models
+
+import (
+	"math"
+)
+
+type Visibility string
+
+const (
+	// Public spots are listed for everyone
+	Public Visibility = "public"
+	// FriendsOnly spots are shown to the owner's friends only
+	FriendsOnly Visibility = "friends"
+	// Private spots are shown to their owner only
+	Private Visibility = "private"
+	// Unlisted spots open for anyone with the ID but are never listed
+	Unlisted Visibility = "unlisted"
+)
+
+var Visibilities = []Visibility{Public, FriendsOnly, Private, Unlisted}
+
+func ValidVisibility(visibility Visibility) bool {
+	for _, v := range Visibilities {
+		if v == visibility {
+			return true
+		}
+	}
+	return false
+}
+
+// FuzzGrid is the size in degrees of the grid cells fuzzed locations snap
+// to, about 1 km north-south
+const FuzzGrid = 0.01
+
+// FuzzCoordinate snaps a coordinate to the center of its FuzzGrid cell
+func FuzzCoordinate(value, limit float64) float64 {
+	return math.Max(-limit, math.Min(limit, math.Floor(value/FuzzGrid)*FuzzGrid+FuzzGrid/2))
+}
+
+// Fuzz replaces the exact position with the center of its grid cell. A
+// fuzzed spot must never be saved.
+func (s *Spot) Fuzz() {
+	s.Latitude = FuzzCoordinate(s.Latitude, 90)
+	s.Longitude = FuzzCoordinate(s.Longitude, 180)
+	s.LocationFuzzed = true
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FlagReason names the anti-cheat check a visit failed
+type FlagReason string
+
+const (
+	// FlagImpossibleSpeed visits are too far from the user's previous check-in
+	// for the time between them
+	FlagImpossibleSpeed FlagReason = "impossible_speed"
+	// FlagPerfectCoordinates visits report the spot's exact position or a
+	// flawless fix, as GPS spoofing apps do
+	FlagPerfectCoordinates FlagReason = "perfect_coordinates"
+	// FlagRapidCheckIns visits follow several check-ins within seconds
+	FlagRapidCheckIns FlagReason = "rapid_check_ins"
+	// FlagConstantAccuracy visits repeat the accuracy of every recent check-in
+	FlagConstantAccuracy FlagReason = "constant_accuracy"
+)
+
+// FlagStatus is where a visit stands in the anti-cheat review queue
+type FlagStatus string
+
+const (
+	FlagNone FlagStatus = "none"
+	// FlagPending visits wait for an admin, their XP in escrow
+	FlagPending FlagStatus = "pending"
+	// FlagConfirmed visits were faked: they lose their XP and verification
+	FlagConfirmed FlagStatus = "confirmed"
+	// FlagCleared visits were genuine: their escrowed XP was paid out
+	FlagCleared FlagStatus = "cleared"
+)
+
+// VisitFlag is one reason a visit was sent to review
+type VisitFlag struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	VisitID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"visit_id"`
+	Reason    FlagReason `gorm:"type:varchar(30);not null" json:"reason"`
+	Detail    string     `gorm:"type:text" json:"detail"` // e.g. the implied speed
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (f *VisitFlag) BeforeCreate(tx *gorm.DB) (err error) {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return
+}
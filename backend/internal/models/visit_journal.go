@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"chillspot-backend/internal/imaging"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CrowdLevel is how busy a spot was during a visit
+type CrowdLevel string
+
+const (
+	CrowdEmpty    CrowdLevel = "empty"
+	CrowdQuiet    CrowdLevel = "quiet"
+	CrowdModerate CrowdLevel = "moderate"
+	CrowdBusy     CrowdLevel = "busy"
+	CrowdPacked   CrowdLevel = "packed"
+)
+
+var CrowdLevels = []CrowdLevel{CrowdEmpty, CrowdQuiet, CrowdModerate, CrowdBusy, CrowdPacked}
+
+func ValidCrowdLevel(level CrowdLevel) bool {
+	for _, l := range CrowdLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// JournalVisibilities are the visibilities a journal entry may have; there
+// is no unlisted entry
+var JournalVisibilities = []Visibility{Public, FriendsOnly, Private}
+
+func ValidJournalVisibility(visibility Visibility) bool {
+	for _, v := range JournalVisibilities {
+		if v == visibility {
+			return true
+		}
+	}
+	return false
+}
+
+// Limits on a journal entry
+const (
+	MaxJournalNotesLength = 5000 // characters
+	MaxVisitPhotos        = 20
+	MaxVisitCompanions    = 20
+)
+
+// VisitPhoto is a picture taken during a visit, part of its journal entry
+type VisitPhoto struct {
+	ID         uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	VisitID    uuid.UUID         `gorm:"type:uuid;not null;index" json:"visit_id"`
+	UserID     uuid.UUID         `gorm:"type:uuid;not null" json:"user_id"`
+	Filename   string            `gorm:"type:text;not null" json:"-"`
+	Caption    string            `gorm:"type:varchar(500)" json:"caption"`
+	CapturedAt *time.Time        `json:"captured_at"`
+	Images     imaging.ImageURLs `gorm:"-" json:"images"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+func (p *VisitPhoto) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
+
+func (p *VisitPhoto) AfterFind(tx *gorm.DB) (err error) {
+	p.Images = imaging.URLs("/images/", p.Filename)
+	return nil
+}
+
+func (p *VisitPhoto) AfterSave(tx *gorm.DB) (err error) {
+	p.Images = imaging.URLs("/images/", p.Filename)
+	return nil
+}
+
+// VisitCompanion tags a friend who was there on a visit
+type VisitCompanion struct {
+	VisitID  uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	UserID   uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"user_id"`
+	Username string    `gorm:"->;-:migration" json:"username"` // read with the users table joined
+	User     User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
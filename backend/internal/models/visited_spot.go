@@ -7,14 +7,55 @@ import (
 	"gorm.io/gorm"
 )
 
+// VisitVerification is the outcome of checking a visit's reported position
+type VisitVerification string
+
+const (
+	// VisitUnverified visits were recorded before positions were required
+	VisitUnverified VisitVerification = "unverified"
+	VisitVerified   VisitVerification = "verified"
+	// VisitTooFar visits were reported outside the spot's radius
+	VisitTooFar VisitVerification = "too_far"
+	// VisitInaccurate visits came with a fix too imprecise to tell
+	VisitInaccurate VisitVerification = "inaccurate"
+	// VisitStale visits came with a fix taken too long ago or in the future
+	VisitStale VisitVerification = "stale"
+	// VisitRejected visits were found faked in an anti-cheat review
+	VisitRejected VisitVerification = "rejected"
+)
+
+// VisitedSpot is a check-in at a spot, at most one verified visit per user,
+// spot and day. Only verified visits earn XP and count toward badges;
+// flagged ones wait for an admin first. Verified and legacy unverified
+// visits double as the user's journal: notes, photos, companions and what
+// the spot was like, shared as Visibility says.
 type VisitedSpot struct {
-	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null"`
-	SpotID    uuid.UUID `gorm:"type:uuid;not null"`
-	VisitedAt time.Time `gorm:"not null"`
-	Notes     string    `gorm:"type:text"`
-	User      User      `gorm:"foreignKey:UserID"`
-	Spot      Spot      `gorm:"foreignKey:SpotID"`
+	ID           uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID       uuid.UUID         `gorm:"type:uuid;not null"`
+	SpotID       uuid.UUID         `gorm:"type:uuid;not null"`
+	VisitedAt    time.Time         `gorm:"not null"`
+	Notes        string            `gorm:"type:text"`
+	Latitude     *float64          `gorm:"type:double precision" json:"latitude"`
+	Longitude    *float64          `gorm:"type:double precision" json:"longitude"`
+	Accuracy     *float64          `gorm:"type:double precision" json:"accuracy"` // meters, as reported by the device
+	ReportedAt   *time.Time        `json:"reported_at"`                           // when the device took the fix
+	Distance     *float64          `gorm:"type:double precision" json:"-"`        // meters from the spot; not exposed, it would locate fuzzed spots
+	Verification VisitVerification `gorm:"type:varchar(20);not null;default:'unverified'" json:"verification"`
+	Verified     bool              `gorm:"not null;default:false;index" json:"verified"`
+	FirstVisit   bool              `gorm:"not null;default:false" json:"first_visit"` // the user's first verified visit to the spot
+	XPAwarded    int               `gorm:"not null;default:0" json:"xp_awarded"`
+	FlagStatus   FlagStatus        `gorm:"type:varchar(20);not null;default:'none';index" json:"flag_status"`
+	EscrowXP     int               `gorm:"not null;default:0" json:"escrow_xp"` // withheld while flagged
+	ReviewedAt   *time.Time        `json:"reviewed_at"`
+	Weather      WeatherCondition  `gorm:"type:varchar(20)" json:"weather"` // as observed, never "any"
+	Crowd        CrowdLevel        `gorm:"type:varchar(20)" json:"crowd"`
+	Visibility   Visibility        `gorm:"type:varchar(20);not null;default:'private'" json:"visibility"` // of the journal entry
+	EditedAt     *time.Time        `json:"edited_at"`
+	Flags        []VisitFlag       `gorm:"foreignKey:VisitID;constraint:OnDelete:CASCADE" json:"flags,omitempty"`
+	Photos       []VisitPhoto      `gorm:"foreignKey:VisitID;constraint:OnDelete:CASCADE" json:"photos,omitempty"`
+	Companions   []VisitCompanion  `gorm:"foreignKey:VisitID;constraint:OnDelete:CASCADE" json:"companions,omitempty"`
+	User         User              `gorm:"foreignKey:UserID"`
+	Spot         Spot              `gorm:"foreignKey:SpotID"`
 }
 
 func (v *VisitedSpot) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type WeatherCondition string
+
+const (
+	Sunny      WeatherCondition = "sunny"
+	Rainy      WeatherCondition = "rainy"
+	Cloudy     WeatherCondition = "cloudy"
+	Snowy      WeatherCondition = "snowy"
+	Fog        WeatherCondition = "fog"
+	Windy      WeatherCondition = "windy"
+	ClearNight WeatherCondition = "clear_night"
+	Stormy     WeatherCondition = "stormy"
+	// AnyWeather marks a spot that is worth visiting whatever the weather
+	AnyWeather WeatherCondition = "any"
+)
+
+type WeatherConditionInfo struct {
+	Value       WeatherCondition `json:"value"`
+	Label       string           `json:"label"`
+	Description string           `json:"description"`
+}
+
+// WeatherConditionInfos lists every valid condition in display order. New
+// conditions only need an entry here (plus aliases, if any).
+var WeatherConditionInfos = []WeatherConditionInfo{
+	{Sunny, "Sunny", "Clear or mostly clear skies during the day"},
+	{Cloudy, "Cloudy", "Overcast or partly cloudy"},
+	{Rainy, "Rainy", "Drizzle, rain or showers"},
+	{Snowy, "Snowy", "Snowfall or fresh snow"},
+	{Fog, "Fog", "Fog or low clouds"},
+	{Windy, "Windy", "Strong wind"},
+	{ClearNight, "Clear night", "Cloudless night sky, good for stars"},
+	{Stormy, "Stormy", "Thunderstorms"},
+	{AnyWeather, "Any weather", "Worth a visit whatever the weather"},
+}
+
+// Free-text values seen in stored spots and older clients
+var weatherAliases = map[string]WeatherCondition{
+	"sun":                      Sunny,
+	"sunny_only":               Sunny,
+	"clear":                    Sunny,
+	"cloud":                    Cloudy,
+	"clouds":                   Cloudy,
+	"overcast":                 Cloudy,
+	"rain":                     Rainy,
+	"accessible_in_rain":       Rainy,
+	"snow":                     Snowy,
+	"foggy":                    Fog,
+	"mist":                     Fog,
+	"misty":                    Fog,
+	"wind":                     Windy,
+	"night":                    ClearNight,
+	"starry":                   ClearNight,
+	"storm":                    Stormy,
+	"thunderstorm":             Stormy,
+	"all":                      AnyWeather,
+	"accessible_on_dirt_roads": AnyWeather,
+}
+
+var ErrInvalidWeather = errors.New("invalid weather condition")
+
+// ParseWeatherCondition normalises case, spacing and known aliases and
+// reports whether the result is a valid condition
+func ParseWeatherCondition(value string) (WeatherCondition, bool) {
+	key := strings.ToLower(strings.TrimSpace(value))
+	key = strings.Join(strings.FieldsFunc(key, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	}), "_")
+
+	if alias, ok := weatherAliases[key]; ok {
+		return alias, true
+	}
+	for _, info := range WeatherConditionInfos {
+		if string(info.Value) == key {
+			return info.Value, true
+		}
+	}
+	return "", false
+}
+
+// ParseWeatherConditions reads a comma separated list of conditions
+func ParseWeatherConditions(value string) (WeatherConditions, error) {
+	var conditions WeatherConditions
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		condition, ok := ParseWeatherCondition(part)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidWeather, strings.TrimSpace(part))
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions.Normalize(), nil
+}
+
+// WeatherConditions is stored as a Postgres text[]
+type WeatherConditions []WeatherCondition
+
+// Normalize drops duplicates and collapses the list to just "any" when it
+// contains it. It never returns nil, so the column is never NULL.
+func (c WeatherConditions) Normalize() WeatherConditions {
+	normalized := WeatherConditions{}
+	seen := make(map[WeatherCondition]bool)
+	for _, condition := range c {
+		if condition == AnyWeather {
+			return WeatherConditions{AnyWeather}
+		}
+		if condition != "" && !seen[condition] {
+			seen[condition] = true
+			normalized = append(normalized, condition)
+		}
+	}
+	return normalized
+}
+
+// Primary is the first condition, or "" for none
+func (c WeatherConditions) Primary() WeatherCondition {
+	if len(c) == 0 {
+		return ""
+	}
+	return c[0]
+}
+
+func (c WeatherConditions) Strings() []string {
+	values := make([]string, len(c))
+	for i, condition := range c {
+		values[i] = string(condition)
+	}
+	return values
+}
+
+func (c WeatherConditions) Value() (driver.Value, error) {
+	parts := c.Strings()
+	for i, part := range parts {
+		parts[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(part) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}", nil
+}
+
+func (c *WeatherConditions) Scan(src any) error {
+	var literal string
+	switch v := src.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case string:
+		literal = v
+	case []byte:
+		literal = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into WeatherConditions", src)
+	}
+
+	literal = strings.TrimSuffix(strings.TrimPrefix(literal, "{"), "}")
+	conditions := WeatherConditions{}
+	if literal != "" {
+		for _, part := range strings.Split(literal, ",") {
+			conditions = append(conditions, WeatherCondition(strings.Trim(part, `"`)))
+		}
+	}
+	*c = conditions
+	return nil
+}
@@ -0,0 +1,195 @@
+// Package mvt encodes point layers as Mapbox Vector Tiles (spec v2.1).
+//
+// Only the subset of the spec needed for spot markers is implemented, so the
+// protobuf wire format is written by hand instead of pulling in generated code.
+package mvt
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// Extent is the number of integer units along each tile edge
+const Extent = 4096
+
+const (
+	geomTypePoint = 1
+	cmdMoveTo     = 1
+)
+
+// Protobuf wire types
+const (
+	wireVarint = 0
+	wire64Bit  = 1
+	wireBytes  = 2
+)
+
+type Feature struct {
+	ID         uint64
+	X, Y       int // tile coordinates in [0, Extent)
+	Properties map[string]any
+}
+
+type Layer struct {
+	Name     string
+	Features []Feature
+}
+
+// TileBounds returns the lng/lat bounding box of the z/x/y web mercator tile
+func TileBounds(z, x, y int) (minLng, minLat, maxLng, maxLat float64) {
+	n := math.Exp2(float64(z))
+	minLng = float64(x)/n*360 - 180
+	maxLng = float64(x+1)/n*360 - 180
+	maxLat = tileLat(float64(y), n)
+	minLat = tileLat(float64(y+1), n)
+	return
+}
+
+func tileLat(y, n float64) float64 {
+	return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi
+}
+
+// Project converts a lng/lat point to integer coordinates inside the z/x/y tile
+func Project(z, x, y int, lng, lat float64) (int, int) {
+	n := math.Exp2(float64(z))
+	latRad := lat * math.Pi / 180
+
+	worldX := (lng + 180) / 360 * n
+	worldY := (1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * n
+
+	return int((worldX - float64(x)) * Extent), int((worldY - float64(y)) * Extent)
+}
+
+// Encode serialises the layers into a vector tile
+func Encode(layers ...Layer) []byte {
+	var tile []byte
+	for _, layer := range layers {
+		tile = appendBytesField(tile, 3, encodeLayer(layer))
+	}
+	return tile
+}
+
+func encodeLayer(layer Layer) []byte {
+	keys := []string{}
+	keyIndex := map[string]uint32{}
+	values := [][]byte{}
+	valueIndex := map[string]uint32{}
+
+	var buf []byte
+	buf = appendVarintField(buf, 15, 2) // version
+	buf = appendBytesField(buf, 1, []byte(layer.Name))
+
+	for _, feature := range layer.Features {
+		// Sorted keys keep the encoded tile deterministic
+		names := make([]string, 0, len(feature.Properties))
+		for key := range feature.Properties {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+
+		var tags []uint64
+		for _, key := range names {
+			encoded := encodeValue(feature.Properties[key])
+			if encoded == nil {
+				continue
+			}
+
+			ki, ok := keyIndex[key]
+			if !ok {
+				ki = uint32(len(keys))
+				keyIndex[key] = ki
+				keys = append(keys, key)
+			}
+
+			vi, ok := valueIndex[string(encoded)]
+			if !ok {
+				vi = uint32(len(values))
+				valueIndex[string(encoded)] = vi
+				values = append(values, encoded)
+			}
+
+			tags = append(tags, uint64(ki), uint64(vi))
+		}
+
+		geometry := []uint64{
+			cmdMoveTo&0x7 | 1<<3,
+			zigzag(int64(feature.X)),
+			zigzag(int64(feature.Y)),
+		}
+
+		var f []byte
+		f = appendVarintField(f, 1, feature.ID)
+		f = appendPackedField(f, 2, tags)
+		f = appendVarintField(f, 3, geomTypePoint)
+		f = appendPackedField(f, 4, geometry)
+
+		buf = appendBytesField(buf, 2, f)
+	}
+
+	for _, key := range keys {
+		buf = appendBytesField(buf, 3, []byte(key))
+	}
+	for _, value := range values {
+		buf = appendBytesField(buf, 4, value)
+	}
+	buf = appendVarintField(buf, 5, Extent)
+
+	return buf
+}
+
+// encodeValue builds a Value message, or nil for unsupported types
+func encodeValue(value any) []byte {
+	switch v := value.(type) {
+	case string:
+		return appendBytesField(nil, 1, []byte(v))
+	case float64:
+		buf := appendTag(nil, 3, wire64Bit)
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+	case int:
+		return appendVarintField(nil, 6, zigzag(int64(v)))
+	case int64:
+		return appendVarintField(nil, 6, zigzag(v))
+	case uint:
+		return appendVarintField(nil, 5, uint64(v))
+	case uint64:
+		return appendVarintField(nil, 5, v)
+	case bool:
+		var b uint64
+		if v {
+			b = 1
+		}
+		return appendVarintField(nil, 7, b)
+	}
+	return nil
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func appendTag(buf []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(buf, uint64(field<<3|wireType))
+}
+
+func appendVarintField(buf []byte, field int, v uint64) []byte {
+	buf = appendTag(buf, field, wireVarint)
+	return binary.AppendUvarint(buf, v)
+}
+
+func appendBytesField(buf []byte, field int, data []byte) []byte {
+	buf = appendTag(buf, field, wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+func appendPackedField(buf []byte, field int, values []uint64) []byte {
+	if len(values) == 0 {
+		return buf
+	}
+	var packed []byte
+	for _, v := range values {
+		packed = binary.AppendUvarint(packed, v)
+	}
+	return appendBytesField(buf, field, packed)
+}
@@ -0,0 +1,166 @@
+package mvt
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// Latitude where the web mercator square ends
+const maxMercatorLat = 85.0511287798
+
+func TestTileBounds(t *testing.T) {
+	tests := []struct {
+		z, x, y                        int
+		minLng, minLat, maxLng, maxLat float64
+	}{
+		{0, 0, 0, -180, -maxMercatorLat, 180, maxMercatorLat},
+		{1, 0, 0, -180, 0, 0, maxMercatorLat},
+		{1, 1, 1, 0, -maxMercatorLat, 180, 0},
+		{2, 2, 1, 0, 0, 90, 66.5132604431},
+	}
+	for _, tt := range tests {
+		minLng, minLat, maxLng, maxLat := TileBounds(tt.z, tt.x, tt.y)
+		got := []float64{minLng, minLat, maxLng, maxLat}
+		want := []float64{tt.minLng, tt.minLat, tt.maxLng, tt.maxLat}
+		for i := range got {
+			if math.Abs(got[i]-want[i]) > 1e-9 {
+				t.Errorf("TileBounds(%d, %d, %d) = %v, want %v", tt.z, tt.x, tt.y, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		name         string
+		z, x, y      int
+		lng, lat     float64
+		wantX, wantY int
+	}{
+		{"world centre", 0, 0, 0, 0, 0, Extent / 2, Extent / 2},
+		{"tile corner", 1, 1, 1, 0, 0, 0, 0},
+		{"quarter of the tile", 1, 0, 0, -90, 0, Extent / 2, Extent},
+		// Points outside the tile land outside [0, Extent)
+		{"west of the tile", 1, 1, 0, -90, 0, -Extent / 2, Extent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := Project(tt.z, tt.x, tt.y, tt.lng, tt.lat)
+			if x != tt.wantX || y != tt.wantY {
+				t.Errorf("Project = (%d, %d), want (%d, %d)", x, y, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestProjectStaysInsideTile(t *testing.T) {
+	for _, p := range []struct{ lng, lat float64 }{{21.4254, 41.9981}, {-73.9857, 40.7484}, {151.2153, -33.8568}} {
+		for z := 0; z <= 16; z++ {
+			n := math.Exp2(float64(z))
+			x := int((p.lng + 180) / 360 * n)
+			latRad := p.lat * math.Pi / 180
+			y := int((1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * n)
+
+			minLng, minLat, maxLng, maxLat := TileBounds(z, x, y)
+			if p.lng < minLng || p.lng >= maxLng || p.lat < minLat || p.lat >= maxLat {
+				t.Fatalf("(%v, %v) outside tile %d/%d/%d", p.lng, p.lat, z, x, y)
+			}
+			if px, py := Project(z, x, y, p.lng, p.lat); px < 0 || px >= Extent || py < 0 || py >= Extent {
+				t.Errorf("Project(%d, %d, %d, %v, %v) = (%d, %d), outside the tile", z, x, y, p.lng, p.lat, px, py)
+			}
+		}
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  []byte
+	}{
+		{"string", "ab", []byte{0x0a, 0x02, 'a', 'b'}},
+		{"double", 1.5, []byte{0x19, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f}},
+		{"negative int", -1, []byte{0x30, 0x01}},
+		{"int64", int64(300), []byte{0x30, 0xd8, 0x04}},
+		{"uint", uint(5), []byte{0x28, 0x05}},
+		{"uint64", uint64(1 << 7), []byte{0x28, 0x80, 0x01}},
+		{"true", true, []byte{0x38, 0x01}},
+		{"false", false, []byte{0x38, 0x00}},
+		{"unsupported", []string{"a"}, nil},
+		{"nil", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeValue(tt.value); !bytes.Equal(got, tt.want) {
+				t.Errorf("encodeValue(%v) = % x, want % x", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	// A layer "s" with feature 1 at (1, 2) tagged a=b
+	feature := []byte{
+		0x08, 0x01, // id
+		0x12, 0x02, 0x00, 0x00, // tags: key 0, value 0
+		0x18, 0x01, // type: point
+		0x22, 0x03, 0x09, 0x02, 0x04, // geometry: MoveTo(1, 2)
+	}
+	layer := []byte{0x78, 0x02, 0x0a, 0x01, 's', 0x12, byte(len(feature))}
+	layer = append(layer, feature...)
+	layer = append(layer,
+		0x1a, 0x01, 'a', // keys
+		0x22, 0x03, 0x0a, 0x01, 'b', // values
+		0x28, 0x80, 0x20, // extent
+	)
+	want := append([]byte{0x1a, byte(len(layer))}, layer...)
+
+	tests := []struct {
+		name   string
+		layers []Layer
+		want   []byte
+	}{
+		{"no layers", nil, nil},
+		{"one point", []Layer{{Name: "s", Features: []Feature{
+			{ID: 1, X: 1, Y: 2, Properties: map[string]any{"a": "b"}},
+		}}}, want},
+		{"unsupported properties dropped", []Layer{{Name: "s", Features: []Feature{
+			{ID: 1, X: 1, Y: 2, Properties: map[string]any{"a": "b", "c": struct{}{}}},
+		}}}, want},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Encode(tt.layers...); !bytes.Equal(got, tt.want) {
+				t.Errorf("Encode() =\n% x\nwant\n% x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeSharesKeysAndValues(t *testing.T) {
+	properties := func(title string) map[string]any {
+		return map[string]any{"title": title, "visits": 3, "rating": 4.5, "hidden": false}
+	}
+	layer := Layer{Name: "spots", Features: []Feature{
+		{ID: 1, X: 10, Y: 20, Properties: properties("Lake")},
+		{ID: 2, X: 30, Y: 40, Properties: properties("Hill")},
+	}}
+
+	first := Encode(layer)
+	for i := 0; i < 20; i++ {
+		if !bytes.Equal(Encode(layer), first) {
+			t.Fatal("encoding the same layer twice gave different tiles")
+		}
+	}
+
+	// Both features use the same keys and share three values, each written once
+	if keys := bytes.Count(first, []byte("title")); keys != 1 {
+		t.Errorf("key title written %d times, want 1", keys)
+	}
+	shared := encodeValue(3)
+	if n := bytes.Count(first, append([]byte{0x22, byte(len(shared))}, shared...)); n != 1 {
+		t.Errorf("value 3 written %d times, want 1", n)
+	}
+}
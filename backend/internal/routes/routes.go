@@ -3,40 +3,78 @@ package routes
 import (
 	"chillspot-backend/internal/handlers"
 	"chillspot-backend/internal/middleware"
+	"chillspot-backend/internal/storage"
+	"chillspot-backend/internal/weather"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(db *gorm.DB) *mux.Router {
+func SetupRoutes(db *gorm.DB, store storage.Storage, forecasts weather.Provider) *mux.Router {
 	r := mux.NewRouter()
 	r.Use(middleware.CorsMiddleware)
 	r.HandleFunc("/register", handlers.Register(db)).Methods("POST")
 	r.HandleFunc("/login", handlers.Login(db)).Methods("POST")
+	r.HandleFunc("/meta/weather-conditions", handlers.GetWeatherConditionsHandler()).Methods("GET")
+	r.HandleFunc("/categories", handlers.GetCategoriesHandler(db)).Methods("GET")
 
 	// Protected routes
 	protected := r.PathPrefix("").Subrouter()
 	protected.Use(middleware.AuthMiddleware)
 
 	protected.HandleFunc("/profile", handlers.GetProfile(db)).Methods("GET")
-	protected.HandleFunc("/profile", handlers.UpdateProfile(db)).Methods("PUT")
+	protected.HandleFunc("/profile", handlers.UpdateProfile(db, store)).Methods("PUT")
 
 	// Spot management
-	protected.HandleFunc("/spots", handlers.AddSpotHandler(db)).Methods("POST")
+	protected.HandleFunc("/spots", handlers.AddSpotHandler(db, store)).Methods("POST")
 	protected.HandleFunc("/spots/user", handlers.GetSpotsByUserHandler(db)).Methods("GET")
+	protected.HandleFunc("/spots/nearby", handlers.GetNearbySpotsHandler(db)).Methods("GET")
+	protected.HandleFunc("/spots/map", handlers.GetMapSpotsHandler(db)).Methods("GET")
+	protected.HandleFunc("/spots/search", handlers.SearchSpotsHandler(db)).Methods("GET")
+	protected.HandleFunc("/spots/recommended", handlers.GetRecommendedSpotsHandler(db, forecasts)).Methods("GET")
+
+	// Tags
+	protected.HandleFunc("/tags", handlers.GetTagsHandler(db)).Methods("GET")
+
+	// Vector tiles
+	protected.HandleFunc("/tiles/spots/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.mvt", handlers.GetSpotTileHandler(db)).Methods("GET")
 
 	// Visited spots endpoints
 	protected.HandleFunc("/visited-spots", handlers.AddVisitedSpotHandler(db)).Methods("POST")
 	protected.HandleFunc("/visited-spots", handlers.GetVisitedSpotsHandler(db)).Methods("GET")
+	protected.HandleFunc("/visited-spots/{id}", handlers.GetJournalEntryHandler(db)).Methods("GET")
+	protected.HandleFunc("/visited-spots/{id}", handlers.UpdateJournalEntryHandler(db)).Methods("PUT", "PATCH")
+	protected.HandleFunc("/visited-spots/{id}", handlers.DeleteJournalEntryHandler(db, store)).Methods("DELETE")
+	protected.HandleFunc("/visited-spots/{id}/photos", handlers.UploadVisitPhotoHandler(db, store)).Methods("POST")
+	protected.HandleFunc("/visited-spots/{id}/photos/{photoId}", handlers.DeleteVisitPhotoHandler(db, store)).Methods("DELETE")
+	protected.HandleFunc("/journal", handlers.GetJournalHandler(db)).Methods("GET")
 
 	// Proximity check endpoint
 	protected.HandleFunc("/spots/check-proximity", handlers.CheckProximityHandler(db)).Methods("POST")
+	protected.HandleFunc("/discoveries", handlers.GetDiscoveriesHandler(db)).Methods("GET")
 
 	//Review endpoints
 	protected.HandleFunc("/reviews", handlers.CreateReviewHandler(db)).Methods("POST")
 	protected.HandleFunc("/reviews/user", handlers.GetUserReviewsHandler(db)).Methods("GET")
 
-	protected.HandleFunc("/spots/{id}", handlers.GetSpotHandler(db)).Methods("GET")
+	protected.HandleFunc("/spots/{id}", handlers.GetSpotHandler(db, forecasts)).Methods("GET")
+	protected.HandleFunc("/spots/{id}", handlers.UpdateSpotHandler(db, store)).Methods("PUT", "PATCH")
+	protected.HandleFunc("/spots/{id}", handlers.DeleteSpotHandler(db, store)).Methods("DELETE")
+	protected.HandleFunc("/spots/{id}/sun", handlers.GetSpotSunHandler(db)).Methods("GET")
+	protected.HandleFunc("/spots/{id}/revisions", handlers.GetSpotRevisionsHandler(db)).Methods("GET")
+	protected.HandleFunc("/spots/{id}/revisions/{rev:[0-9]+}/diff", handlers.GetSpotRevisionDiffHandler(db)).Methods("GET")
+	protected.HandleFunc("/spots/{id}/revisions/{rev:[0-9]+}/revert", handlers.RevertSpotRevisionHandler(db, store)).Methods("POST")
+	protected.HandleFunc("/spots/{id}/photos", handlers.UploadSpotPhotoHandler(db, store)).Methods("POST")
+	protected.HandleFunc("/spots/{id}/photos", handlers.GetSpotPhotosHandler(db)).Methods("GET")
+	protected.HandleFunc("/spots/{id}/photos/order", handlers.ReorderSpotPhotosHandler(db)).Methods("PUT")
+	protected.HandleFunc("/spots/{id}/photos/{photoId}", handlers.DeleteSpotPhotoHandler(db, store)).Methods("DELETE")
+	protected.HandleFunc("/spots/{id}/photos/{photoId}/feature", handlers.FeatureSpotPhotoHandler(db)).Methods("POST")
+	protected.HandleFunc("/spots/{id}/tags", handlers.AddSpotTagsHandler(db)).Methods("POST")
+	protected.HandleFunc("/spots/{id}/tags/{tag}", handlers.RemoveSpotTagHandler(db)).Methods("DELETE")
+	protected.HandleFunc("/spots/{id}/attribute-suggestions", handlers.CreateAttributeSuggestionHandler(db)).Methods("POST")
+	protected.HandleFunc("/spots/{id}/attribute-suggestions", handlers.GetAttributeSuggestionsHandler(db)).Methods("GET")
+	protected.HandleFunc("/spots/{id}/attribute-suggestions/{suggestionId}/accept", handlers.ReviewAttributeSuggestionHandler(db, true)).Methods("POST")
+	protected.HandleFunc("/spots/{id}/attribute-suggestions/{suggestionId}/reject", handlers.ReviewAttributeSuggestionHandler(db, false)).Methods("POST")
 	protected.HandleFunc("/spots/{id}/like", handlers.LikeSpotHandler(db)).Methods("POST")
 	protected.HandleFunc("/spots/{id}/visit", handlers.TrackVisitHandler(db)).Methods("POST")
 
@@ -57,5 +95,16 @@ func SetupRoutes(db *gorm.DB) *mux.Router {
 
 	protected.HandleFunc("/badges/check", handlers.CheckBadgesHandler(db)).Methods("POST")
 	protected.HandleFunc("/badges", handlers.GetUserBadgesHandler(db)).Methods("GET")
+
+	// Admin routes
+	admin := protected.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.AdminMiddleware(db))
+
+	admin.HandleFunc("/categories", handlers.CreateCategoryHandler(db)).Methods("POST")
+	admin.HandleFunc("/categories/{id}", handlers.UpdateCategoryHandler(db)).Methods("PUT", "PATCH")
+	admin.HandleFunc("/categories/{id}", handlers.DeleteCategoryHandler(db)).Methods("DELETE")
+	admin.HandleFunc("/visit-flags", handlers.GetFlaggedVisitsHandler(db)).Methods("GET")
+	admin.HandleFunc("/visit-flags/{id}/confirm", handlers.ReviewFlaggedVisitHandler(db, true)).Methods("POST")
+	admin.HandleFunc("/visit-flags/{id}/clear", handlers.ReviewFlaggedVisitHandler(db, false)).Methods("POST")
 	return r
 }
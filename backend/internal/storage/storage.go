@@ -0,0 +1,131 @@
+// Package storage abstracts where uploaded files live. Keys are slash
+// separated paths such as "images/123_full.jpg".
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Key namespaces, served under /images/ and /uploads/ respectively
+const (
+	ImagesPrefix  = "images/"  // spot photos and badge artwork
+	UploadsPrefix = "uploads/" // profile pictures
+)
+
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by backends that can hand out temporary direct URLs
+type Presigner interface {
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// How long presigned URLs handed out by Handler stay valid
+const presignExpiry = 15 * time.Minute
+
+// FromEnv builds the storage backend selected by STORAGE_BACKEND:
+//
+//	local (default)  files under STORAGE_LOCAL_DIR (default "internal")
+//	s3               S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY,
+//	                 S3_SECRET_KEY, S3_PATH_STYLE (default true, as MinIO needs)
+func FromEnv() (Storage, error) {
+	switch os.Getenv("STORAGE_BACKEND") {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "internal"
+		}
+		log.Printf("Storing uploads on disk in %s", dir)
+		return NewLocal(dir)
+	case "s3":
+		config := S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PathStyle: os.Getenv("S3_PATH_STYLE") != "false",
+		}
+		log.Printf("Storing uploads in bucket %s at %s", config.Bucket, config.Endpoint)
+		return NewS3(config)
+	default:
+		return nil, errors.New("unknown STORAGE_BACKEND " + os.Getenv("STORAGE_BACKEND"))
+	}
+}
+
+// cleanKey rejects keys that could escape their namespace
+func cleanKey(key string) (string, bool) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if cleaned == "" || cleaned != key {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// Handler serves the objects under prefix for requests already stripped of
+// their URL prefix. With redirect set and a backend that supports it, clients
+// are sent to a presigned URL instead of the bytes being proxied.
+func Handler(store Storage, prefix string, redirect bool) http.Handler {
+	presigner, canPresign := store.(Presigner)
+	redirect = redirect && canPresign
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		key, ok := cleanKey(prefix + r.URL.Path)
+		if !ok || !strings.HasPrefix(key, prefix) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if redirect {
+			url, err := presigner.PresignGet(r.Context(), key, presignExpiry)
+			if err != nil {
+				http.Error(w, "Failed to sign URL", http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, url, http.StatusFound)
+			return
+		}
+
+		object, err := store.Get(r.Context(), key)
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, "Failed to read file", http.StatusInternalServerError)
+			return
+		}
+		defer object.Close()
+
+		if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		// Uploaded files are never overwritten in place, only replaced by new keys
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+
+		if seeker, ok := object.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, path.Base(key), time.Time{}, seeker)
+			return
+		}
+		io.Copy(w, object)
+	})
+}
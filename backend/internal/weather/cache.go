@@ -0,0 +1,74 @@
+package weather
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chillspot-backend/internal/geo"
+)
+
+const (
+	defaultCacheTTL = 30 * time.Minute
+	// Geohash length of the cache grid (cells of about 5 x 5 km)
+	defaultCellPrecision = 5
+)
+
+type cacheEntry struct {
+	forecast  *Forecast
+	fetchedAt time.Time
+}
+
+// Cache shares one forecast between every location in a geohash cell, taken
+// at the cell's centre, for ttl
+type Cache struct {
+	provider  Provider
+	ttl       time.Duration
+	precision int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func NewCache(provider Provider, ttl time.Duration, precision int) *Cache {
+	return &Cache{
+		provider:  provider,
+		ttl:       ttl,
+		precision: precision,
+		entries:   make(map[string]cacheEntry),
+	}
+}
+
+func (c *Cache) Forecast(ctx context.Context, lat, lng float64) (*Forecast, error) {
+	cell := geo.Encode(lat, lng, c.precision)
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[cell]
+	c.mu.Unlock()
+	if ok && now.Sub(entry.fetchedAt) < c.ttl {
+		return entry.forecast, nil
+	}
+
+	centerLat, centerLng := geo.Center(cell)
+	forecast, err := c.provider.Forecast(ctx, centerLat, centerLng)
+	if err != nil {
+		// A stale forecast beats none when the provider is down
+		if ok {
+			return entry.forecast, nil
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[cell] = cacheEntry{forecast: forecast, fetchedAt: now}
+	// Drop expired cells so the map doesn't grow forever
+	for key, old := range c.entries {
+		if now.Sub(old.fetchedAt) >= c.ttl {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+
+	return forecast, nil
+}
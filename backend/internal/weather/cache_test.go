@@ -0,0 +1,110 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"chillspot-backend/internal/geo"
+)
+
+// flakyProvider hands out a new forecast per request until it goes down
+type flakyProvider struct {
+	calls int
+	down  bool
+	asked Location
+}
+
+var errDown = errors.New("provider down")
+
+func (p *flakyProvider) Forecast(ctx context.Context, lat, lng float64) (*Forecast, error) {
+	p.calls++
+	p.asked = Location{lat, lng}
+	if p.down {
+		return nil, errDown
+	}
+	return &Forecast{Current: Snapshot{TemperatureC: float64(p.calls)}}, nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	const ttl = time.Millisecond
+	expire := func() { time.Sleep(2 * ttl) }
+
+	t.Run("shares a cell within the TTL", func(t *testing.T) {
+		provider := &flakyProvider{}
+		cache := NewCache(provider, time.Hour, defaultCellPrecision)
+
+		first, err := cache.Forecast(ctx, 41.9981, 21.4254)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The forecast is taken at the cell's centre
+		cell := geo.Encode(41.9981, 21.4254, defaultCellPrecision)
+		if lat, lng := geo.Center(cell); provider.asked != (Location{lat, lng}) {
+			t.Errorf("asked at %v, want the centre of %s", provider.asked, cell)
+		}
+
+		second, err := cache.Forecast(ctx, 41.9985, 21.4250)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second != first || provider.calls != 1 {
+			t.Errorf("refetched within the TTL: %d calls", provider.calls)
+		}
+
+		if _, err := cache.Forecast(ctx, 51.5074, -0.1278); err != nil {
+			t.Fatal(err)
+		}
+		if provider.calls != 2 {
+			t.Errorf("another cell served from the cache: %d calls", provider.calls)
+		}
+	})
+
+	t.Run("refetches once expired", func(t *testing.T) {
+		provider := &flakyProvider{}
+		cache := NewCache(provider, ttl, defaultCellPrecision)
+
+		first, _ := cache.Forecast(ctx, 41.9981, 21.4254)
+		expire()
+		second, err := cache.Forecast(ctx, 41.9981, 21.4254)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second == first || provider.calls != 2 {
+			t.Errorf("expired forecast reused: %d calls", provider.calls)
+		}
+	})
+
+	t.Run("falls back to a stale forecast", func(t *testing.T) {
+		provider := &flakyProvider{}
+		cache := NewCache(provider, ttl, defaultCellPrecision)
+
+		first, _ := cache.Forecast(ctx, 41.9981, 21.4254)
+		expire()
+		provider.down = true
+		stale, err := cache.Forecast(ctx, 41.9981, 21.4254)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stale != first {
+			t.Error("stale forecast not returned while the provider is down")
+		}
+
+		// Without one the error comes through
+		if _, err := cache.Forecast(ctx, 51.5074, -0.1278); !errors.Is(err, errDown) {
+			t.Errorf("err = %v, want %v", err, errDown)
+		}
+	})
+
+	t.Run("drops expired cells", func(t *testing.T) {
+		cache := NewCache(&flakyProvider{}, ttl, defaultCellPrecision)
+		cache.Forecast(ctx, 41.9981, 21.4254)
+		expire()
+		cache.Forecast(ctx, 51.5074, -0.1278)
+		if _, ok := cache.entries[geo.Encode(41.9981, 21.4254, defaultCellPrecision)]; ok || len(cache.entries) != 1 {
+			t.Errorf("cells kept: %v", cache.entries)
+		}
+	})
+}
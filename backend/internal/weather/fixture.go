@@ -0,0 +1,47 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Fixture replays a recorded Open-Meteo response for every location, so the
+// app and tests run without network access. The recording is shifted in time
+// so that it always starts at the current hour.
+type Fixture struct {
+	response openMeteoResponse
+	now      func() time.Time
+}
+
+func NewFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &Fixture{now: time.Now}
+	if err := json.Unmarshal(data, &fixture.response); err != nil {
+		return nil, err
+	}
+	// Fail on load rather than on the first request
+	if _, err := fixture.response.forecast(); err != nil {
+		return nil, err
+	}
+	return fixture, nil
+}
+
+func (f *Fixture) Forecast(ctx context.Context, lat, lng float64) (*Forecast, error) {
+	forecast, err := f.response.forecast()
+	if err != nil {
+		return nil, err
+	}
+
+	shift := f.now().UTC().Truncate(time.Hour).Sub(forecast.Current.Time.Truncate(time.Hour))
+	forecast.Current.Time = forecast.Current.Time.Add(shift)
+	for i := range forecast.Hourly {
+		forecast.Hourly[i].Time = forecast.Hourly[i].Time.Add(shift)
+	}
+	return forecast, nil
+}
@@ -0,0 +1,58 @@
+package weather
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chillspot-backend/internal/models"
+)
+
+func TestFixture(t *testing.T) {
+	fixture, err := NewFixture("testdata/forecast.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 10, 14, 20, 0, 0, time.UTC)
+	fixture.now = func() time.Time { return now }
+
+	forecast, err := fixture.Forecast(context.Background(), 41.9981, 21.4254)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The recording starts at the current hour, on an hourly grid
+	hour := now.Truncate(time.Hour)
+	if !forecast.Current.Time.Equal(hour) {
+		t.Errorf("current weather at %s, want %s", forecast.Current.Time, hour)
+	}
+	if len(forecast.Hourly) != 24 {
+		t.Fatalf("%d hours, want 24", len(forecast.Hourly))
+	}
+	for i, snapshot := range forecast.Hourly {
+		if want := hour.Add(time.Duration(i) * time.Hour); !snapshot.Time.Equal(want) {
+			t.Errorf("hour %d at %s, want %s", i, snapshot.Time, want)
+		}
+	}
+
+	// Codes and daylight as recorded: a clear night turning to rain by six
+	if forecast.Current.Condition != models.ClearNight {
+		t.Errorf("current condition %s, want %s", forecast.Current.Condition, models.ClearNight)
+	}
+	if got := forecast.Hourly[6].Condition; got != models.Rainy {
+		t.Errorf("condition at six %s, want %s", got, models.Rainy)
+	}
+	if next := forecast.Next(now, 3); len(next) != 3 || !next[0].Time.Equal(hour) {
+		t.Errorf("next 3 hours: %v", next)
+	}
+
+	// Every request replays the recording afresh
+	again, _ := fixture.Forecast(context.Background(), 0, 0)
+	if again == forecast || !again.Hourly[0].Time.Equal(hour) {
+		t.Error("fixture forecasts are shared or shifted twice")
+	}
+
+	if _, err := NewFixture("testdata/missing.json"); err == nil {
+		t.Error("missing fixture loaded")
+	}
+}
@@ -0,0 +1,148 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"chillspot-backend/internal/models"
+)
+
+const (
+	defaultOpenMeteoURL = "https://api.open-meteo.com/v1/forecast"
+	forecastHours       = 24
+)
+
+// OpenMeteo fetches forecasts from the Open-Meteo API, which needs no key
+type OpenMeteo struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewOpenMeteo(baseURL string) *OpenMeteo {
+	if baseURL == "" {
+		baseURL = defaultOpenMeteoURL
+	}
+	return &OpenMeteo{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// openMeteoResponse is the subset of the API response we request. Times are
+// in UTC, formatted without a zone.
+type openMeteoResponse struct {
+	Current struct {
+		Time        string  `json:"time"`
+		WeatherCode int     `json:"weather_code"`
+		Temperature float64 `json:"temperature_2m"`
+		WindSpeed   float64 `json:"wind_speed_10m"`
+		IsDay       int     `json:"is_day"`
+	} `json:"current"`
+	Hourly struct {
+		Time        []string  `json:"time"`
+		WeatherCode []int     `json:"weather_code"`
+		Temperature []float64 `json:"temperature_2m"`
+		WindSpeed   []float64 `json:"wind_speed_10m"`
+		IsDay       []int     `json:"is_day"`
+	} `json:"hourly"`
+}
+
+const openMeteoTimeLayout = "2006-01-02T15:04"
+
+func (o *OpenMeteo) Forecast(ctx context.Context, lat, lng float64) (*Forecast, error) {
+	query := url.Values{}
+	query.Set("latitude", strconv.FormatFloat(lat, 'f', 4, 64))
+	query.Set("longitude", strconv.FormatFloat(lng, 'f', 4, 64))
+	query.Set("current", "weather_code,temperature_2m,wind_speed_10m,is_day")
+	query.Set("hourly", "weather_code,temperature_2m,wind_speed_10m,is_day")
+	query.Set("forecast_hours", strconv.Itoa(forecastHours))
+	query.Set("timezone", "UTC")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("weather API returned %s", resp.Status)
+	}
+
+	var body openMeteoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.forecast()
+}
+
+func (body *openMeteoResponse) forecast() (*Forecast, error) {
+	current, err := time.Parse(openMeteoTimeLayout, body.Current.Time)
+	if err != nil {
+		return nil, fmt.Errorf("invalid current time %q", body.Current.Time)
+	}
+
+	forecast := &Forecast{
+		Current: Snapshot{
+			Time:         current,
+			Condition:    conditionFromWMO(body.Current.WeatherCode, body.Current.WindSpeed, body.Current.IsDay == 1),
+			TemperatureC: body.Current.Temperature,
+			WindSpeedKmh: body.Current.WindSpeed,
+			IsDay:        body.Current.IsDay == 1,
+		},
+	}
+
+	hourly := body.Hourly
+	for i, timeStr := range hourly.Time {
+		if i >= len(hourly.WeatherCode) || i >= len(hourly.Temperature) || i >= len(hourly.WindSpeed) || i >= len(hourly.IsDay) {
+			break
+		}
+		t, err := time.Parse(openMeteoTimeLayout, timeStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hourly time %q", timeStr)
+		}
+		forecast.Hourly = append(forecast.Hourly, Snapshot{
+			Time:         t,
+			Condition:    conditionFromWMO(hourly.WeatherCode[i], hourly.WindSpeed[i], hourly.IsDay[i] == 1),
+			TemperatureC: hourly.Temperature[i],
+			WindSpeedKmh: hourly.WindSpeed[i],
+			IsDay:        hourly.IsDay[i] == 1,
+		})
+	}
+	return forecast, nil
+}
+
+// Wind speed from which an otherwise dry hour counts as windy (Beaufort 6)
+const windyThreshold = 39.0 // km/h
+
+// conditionFromWMO maps a WMO weather interpretation code, the wind speed and
+// daylight onto the spot weather conditions
+func conditionFromWMO(code int, windSpeed float64, isDay bool) models.WeatherCondition {
+	switch {
+	case code >= 95: // thunderstorms
+		return models.Stormy
+	case code >= 71 && code <= 77, code == 85, code == 86: // snow
+		return models.Snowy
+	case code >= 51: // drizzle, rain, showers
+		return models.Rainy
+	case windSpeed >= windyThreshold:
+		return models.Windy
+	case code == 45, code == 48: // fog, depositing rime fog
+		return models.Fog
+	case code <= 1 && !isDay: // clear, mainly clear
+		return models.ClearNight
+	case code <= 1:
+		return models.Sunny
+	default: // partly cloudy, overcast
+		return models.Cloudy
+	}
+}
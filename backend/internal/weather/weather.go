@@ -0,0 +1,133 @@
+// Package weather fetches current and forecast conditions and maps them onto
+// the conditions spots recommend.
+package weather
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"chillspot-backend/internal/geo"
+	"chillspot-backend/internal/models"
+)
+
+// Forecasts fetches at most this many forecasts at once
+const maxParallelForecasts = 8
+
+// Snapshot is the weather at one point in time
+type Snapshot struct {
+	Time         time.Time               `json:"time"`
+	Condition    models.WeatherCondition `json:"condition"`
+	TemperatureC float64                 `json:"temperature_c"`
+	WindSpeedKmh float64                 `json:"wind_speed_kmh"`
+	IsDay        bool                    `json:"is_day"`
+}
+
+// Forecast holds the current weather and the coming hours, oldest first
+type Forecast struct {
+	Current Snapshot   `json:"current"`
+	Hourly  []Snapshot `json:"hourly"`
+}
+
+type Provider interface {
+	Forecast(ctx context.Context, lat, lng float64) (*Forecast, error)
+}
+
+// Next returns the hourly snapshots from now up to the given number of hours
+func (f *Forecast) Next(now time.Time, hours int) []Snapshot {
+	from := now.Truncate(time.Hour)
+	until := from.Add(time.Duration(hours) * time.Hour)
+
+	var next []Snapshot
+	for _, snapshot := range f.Hourly {
+		if !snapshot.Time.Before(from) && snapshot.Time.Before(until) {
+			next = append(next, snapshot)
+		}
+	}
+	return next
+}
+
+// Location is a point to forecast
+type Location struct {
+	Lat, Lng float64
+}
+
+// Forecasts fetches the forecast of every location, several at once, in the
+// order given. Locations in the same cache cell share one request. Those
+// whose forecast failed, or wasn't fetched before ctx was done, get nil.
+func Forecasts(ctx context.Context, provider Provider, locations []Location) []*Forecast {
+	precision := defaultCellPrecision
+	if cache, ok := provider.(*Cache); ok {
+		precision = cache.precision
+	}
+
+	var cells []string
+	byCell := make(map[string][]int)
+	for i, location := range locations {
+		cell := geo.Encode(location.Lat, location.Lng, precision)
+		if _, ok := byCell[cell]; !ok {
+			cells = append(cells, cell)
+		}
+		byCell[cell] = append(byCell[cell], i)
+	}
+
+	forecasts := make([]*Forecast, len(locations))
+	slots := make(chan struct{}, maxParallelForecasts)
+	var wg sync.WaitGroup
+	for _, cell := range cells {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				return
+			}
+
+			indexes := byCell[cell]
+			location := locations[indexes[0]]
+			forecast, err := provider.Forecast(ctx, location.Lat, location.Lng)
+			if err != nil {
+				log.Printf("Failed to fetch the forecast for cell %s: %v", cell, err)
+				return
+			}
+			for _, i := range indexes {
+				forecasts[i] = forecast
+			}
+		}()
+	}
+	wg.Wait()
+	return forecasts
+}
+
+// FromEnv builds the provider selected by WEATHER_PROVIDER:
+//
+//	openmeteo (default)  the Open-Meteo API at WEATHER_API_URL
+//	fixture              the recorded forecast in WEATHER_FIXTURE
+//
+// Either way forecasts are cached per grid cell.
+func FromEnv() (Provider, error) {
+	var provider Provider
+	switch os.Getenv("WEATHER_PROVIDER") {
+	case "", "openmeteo":
+		provider = NewOpenMeteo(os.Getenv("WEATHER_API_URL"))
+	case "fixture":
+		path := os.Getenv("WEATHER_FIXTURE")
+		if path == "" {
+			path = "internal/weather/testdata/forecast.json"
+		}
+		log.Printf("Using weather fixture %s", path)
+		fixture, err := NewFixture(path)
+		if err != nil {
+			return nil, err
+		}
+		provider = fixture
+	default:
+		return nil, errors.New("unknown WEATHER_PROVIDER " + os.Getenv("WEATHER_PROVIDER"))
+	}
+	return NewCache(provider, defaultCacheTTL, defaultCellPrecision), nil
+}
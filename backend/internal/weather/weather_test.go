@@ -0,0 +1,158 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"chillspot-backend/internal/geo"
+	"chillspot-backend/internal/models"
+)
+
+// fakeProvider forecasts sunshine at the latitude in degrees Celsius, fails
+// south of the equator, and records where it was asked
+type fakeProvider struct {
+	mu    sync.Mutex
+	asked []Location
+}
+
+var errSouth = errors.New("no forecast south of the equator")
+
+func (p *fakeProvider) Forecast(ctx context.Context, lat, lng float64) (*Forecast, error) {
+	p.mu.Lock()
+	p.asked = append(p.asked, Location{lat, lng})
+	p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if lat < 0 {
+		return nil, errSouth
+	}
+	return &Forecast{Current: Snapshot{Condition: models.Sunny, TemperatureC: lat}}, nil
+}
+
+func TestConditionFromWMO(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  int
+		wind  float64
+		isDay bool
+		want  models.WeatherCondition
+	}{
+		{"clear day", 0, 5, true, models.Sunny},
+		{"mainly clear day", 1, 5, true, models.Sunny},
+		{"clear night", 0, 5, false, models.ClearNight},
+		{"mainly clear night", 1, 5, false, models.ClearNight},
+		{"partly cloudy", 2, 5, true, models.Cloudy},
+		{"overcast night", 3, 5, false, models.Cloudy},
+		{"fog", 45, 5, true, models.Fog},
+		{"rime fog", 48, 5, false, models.Fog},
+		{"drizzle", 51, 5, true, models.Rainy},
+		{"rain", 63, 5, true, models.Rainy},
+		{"rain showers", 80, 5, true, models.Rainy},
+		{"snow", 71, 5, true, models.Snowy},
+		{"snow grains", 77, 5, true, models.Snowy},
+		{"snow showers", 86, 5, true, models.Snowy},
+		{"thunderstorm", 95, 5, true, models.Stormy},
+		{"thunderstorm with hail", 99, 5, true, models.Stormy},
+		// Wind only tells on an otherwise dry hour
+		{"windy and clear", 0, windyThreshold, true, models.Windy},
+		{"windy and overcast", 3, 60, false, models.Windy},
+		{"windy fog", 45, 50, true, models.Windy},
+		{"just below windy", 0, windyThreshold - 0.1, true, models.Sunny},
+		{"windy rain", 61, 60, true, models.Rainy},
+		{"windy snow", 73, 60, true, models.Snowy},
+		{"windy thunderstorm", 95, 90, true, models.Stormy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conditionFromWMO(tt.code, tt.wind, tt.isDay); got != tt.want {
+				t.Errorf("conditionFromWMO(%d, %v, %v) = %s, want %s", tt.code, tt.wind, tt.isDay, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForecastNext(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	forecast := &Forecast{}
+	for i := range 6 {
+		forecast.Hourly = append(forecast.Hourly, Snapshot{Time: start.Add(time.Duration(i) * time.Hour)})
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		hours     int
+		wantFirst int // index of the first snapshot returned
+		wantCount int
+	}{
+		{"on the hour", start.Add(2 * time.Hour), 3, 2, 3},
+		// The current hour counts, however far into it
+		{"within the hour", start.Add(2*time.Hour + 59*time.Minute), 3, 2, 3},
+		{"past the end", start.Add(4 * time.Hour), 3, 4, 2},
+		{"before the start", start.Add(-2 * time.Hour), 3, 0, 1},
+		{"after the end", start.Add(6 * time.Hour), 3, 0, 0},
+		{"a single hour", start, 1, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := forecast.Next(tt.now, tt.hours)
+			if len(next) != tt.wantCount {
+				t.Fatalf("got %d snapshots, want %d", len(next), tt.wantCount)
+			}
+			for i, snapshot := range next {
+				if want := forecast.Hourly[tt.wantFirst+i].Time; !snapshot.Time.Equal(want) {
+					t.Errorf("snapshot %d at %s, want %s", i, snapshot.Time, want)
+				}
+			}
+		})
+	}
+}
+
+func TestForecasts(t *testing.T) {
+	skopje := Location{41.9981, 21.4254}
+	// About 400 m away, in the same cache cell
+	nearbyLat, nearbyLng := geo.Offset(skopje.Lat, skopje.Lng, 300, 300)
+	nearby := Location{nearbyLat, nearbyLng}
+	if geo.Encode(nearby.Lat, nearby.Lng, defaultCellPrecision) != geo.Encode(skopje.Lat, skopje.Lng, defaultCellPrecision) {
+		t.Fatal("nearby isn't in Skopje's cell")
+	}
+	sydney := Location{-33.8688, 151.2093}
+	london := Location{51.5074, -0.1278}
+
+	provider := &fakeProvider{}
+	forecasts := Forecasts(context.Background(), provider, []Location{skopje, sydney, nearby, london})
+
+	if len(provider.asked) != 3 {
+		t.Errorf("asked for %v, want one forecast per cell", provider.asked)
+	}
+	if forecasts[0] == nil || forecasts[0] != forecasts[2] {
+		t.Error("locations in one cell don't share the forecast")
+	}
+	if forecasts[1] != nil {
+		t.Error("a failed forecast isn't nil")
+	}
+	if forecasts[3] == nil || forecasts[3].Current.TemperatureC != london.Lat {
+		t.Errorf("London got %+v", forecasts[3])
+	}
+
+	// A cache dedupes on its own grid, here cells of about 150 km
+	behind := &fakeProvider{}
+	centerLat, centerLng := geo.Center(geo.Encode(skopje.Lat, skopje.Lng, 3))
+	Forecasts(context.Background(), NewCache(behind, 0, 3), []Location{skopje, {centerLat, centerLng}})
+	if len(behind.asked) != 1 {
+		t.Errorf("asked for %v, want one forecast per cell of the cache", behind.asked)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i, forecast := range Forecasts(ctx, &fakeProvider{}, []Location{skopje, london}) {
+		if forecast != nil {
+			t.Errorf("forecast %d fetched after the context was done", i)
+		}
+	}
+}
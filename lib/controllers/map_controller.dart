@@ -8,6 +8,7 @@
   import 'package:geolocator/geolocator.dart';
   import 'package:flutter_polyline_points/flutter_polyline_points.dart';
 import 'package:shared_preferences/shared_preferences.dart';
+import 'package:domasna/services/paging.dart';
 
   class MapSearchController {
     final MapController mapController = MapController();
@@ -340,61 +341,59 @@ import 'package:shared_preferences/shared_preferences.dart';
       final token = prefs.getString('token');
       if (token == null) return;
 
-      final response = await http.get(
-        Uri.parse('http://10.0.2.2:8080/visited-spots'),
-        headers: {'Authorization': 'Bearer $token'},
+      final baseUrl = dotenv.env['API_BASE_URL'] ?? 'http://localhost:8080';
+      final data = await fetchAllPages(
+        '$baseUrl/visited-spots',
+        {'Authorization': 'Bearer $token'},
       );
 
-      if (response.statusCode == 200) {
-        final data = json.decode(response.body);
-        visitedMarkers = data.map((visited) {
-          final spot = visited['Spot'];
-          return Marker(
-            point: LatLng(spot['Latitude'], spot['Longitude']),
-            width: 100,
-            height: 80,
-            child: Column(
-              mainAxisSize: MainAxisSize.min,
-              children: [
-                GestureDetector(
-                  onTap: () {
-                    if (currentLocation != null) {
-                      calculateRoute(
-                        currentLocation!,
-                        LatLng(spot['Latitude'], spot['Longitude'])
-                      );
-                    }
-                  },
-                  child: Icon(Icons.check_circle, color: Colors.green, size: 40),
-                ),
-                ConstrainedBox(
-                  constraints: BoxConstraints(maxHeight: 40, maxWidth: 100),
-                  child: Container(
-                    padding: EdgeInsets.all(4),
-                    decoration: BoxDecoration(
-                      color: Colors.white,
-                      borderRadius: BorderRadius.circular(5),
-                      boxShadow: [BoxShadow(color: Colors.black26, blurRadius: 2)],
-                    ),
-                    child: FittedBox(
-                      fit: BoxFit.scaleDown,
-                      child: Text(
-                        spot['Title'] ?? 'Visited Spot',
-                        style: TextStyle(
-                          fontSize: 12,
-                          fontWeight: FontWeight.bold,
-                          color: Colors.green[700],
-                        ),
+      visitedMarkers = data.map((visited) {
+        final spot = visited['Spot'];
+        return Marker(
+          point: LatLng(spot['Latitude'], spot['Longitude']),
+          width: 100,
+          height: 80,
+          child: Column(
+            mainAxisSize: MainAxisSize.min,
+            children: [
+              GestureDetector(
+                onTap: () {
+                  if (currentLocation != null) {
+                    calculateRoute(
+                      currentLocation!,
+                      LatLng(spot['Latitude'], spot['Longitude'])
+                    );
+                  }
+                },
+                child: Icon(Icons.check_circle, color: Colors.green, size: 40),
+              ),
+              ConstrainedBox(
+                constraints: BoxConstraints(maxHeight: 40, maxWidth: 100),
+                child: Container(
+                  padding: EdgeInsets.all(4),
+                  decoration: BoxDecoration(
+                    color: Colors.white,
+                    borderRadius: BorderRadius.circular(5),
+                    boxShadow: [BoxShadow(color: Colors.black26, blurRadius: 2)],
+                  ),
+                  child: FittedBox(
+                    fit: BoxFit.scaleDown,
+                    child: Text(
+                      spot['Title'] ?? 'Visited Spot',
+                      style: TextStyle(
+                        fontSize: 12,
+                        fontWeight: FontWeight.bold,
+                        color: Colors.green[700],
                       ),
                     ),
                   ),
                 ),
-              ],
-            ),
-          );
-        }).toList();
-        _visitedSpotsController.add(visitedMarkers);
-      }
+              ),
+            ],
+          ),
+        );
+      }).toList();
+      _visitedSpotsController.add(visitedMarkers);
     } catch (e) {
       print('Failed to load visited spots: $e');
     }
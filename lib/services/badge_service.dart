@@ -3,6 +3,7 @@ import 'package:flutter_dotenv/flutter_dotenv.dart';
 import 'package:http/http.dart' as http;
 import 'package:shared_preferences/shared_preferences.dart';
 import 'package:domasna/models/badge_model.dart';
+import 'package:domasna/services/paging.dart';
 
 class BadgeService {
   static String get _baseUrl => dotenv.env['API_BASE_URL'] ?? 'http://localhost:8080';
@@ -57,32 +58,16 @@ class BadgeService {
     }
 
     try {
-      final response = await http.get(
-        Uri.parse('$_baseUrl/badges'),
-        headers: {
+      final rawData = await fetchAllPages(
+        '$_baseUrl/badges',
+        {
           'Content-Type': 'application/json',
           'Authorization': 'Bearer $token',
         },
+        error: 'Failed to load user badges',
       );
 
-      print('Get badges response status: ${response.statusCode}');
-      print('Get badges response body: ${response.body}');
-
-      if (response.statusCode == 200) {
-        final dynamic rawData = json.decode(response.body);
-        
-        if (rawData == null) {
-          return [];
-        }
-        
-        if (rawData is! List) {
-          throw Exception('Invalid response format: expected List but got ${rawData.runtimeType}');
-        }
-        
-        return rawData.map((json) => AchievementBadge.fromJson(json)).toList();
-      } else {
-        throw Exception('Failed to load user badges: ${response.statusCode} - ${response.body}');
-      }
+      return rawData.map((json) => AchievementBadge.fromJson(json)).toList();
     } catch (e) {
       print('Error in getUserBadges: $e');
       rethrow;
@@ -2,6 +2,7 @@ import 'dart:convert';
 import 'package:flutter_dotenv/flutter_dotenv.dart';
 import 'package:http/http.dart' as http;
 import 'package:shared_preferences/shared_preferences.dart';
+import 'package:domasna/services/paging.dart';
 
 class FriendService {
   static String get _baseUrl => dotenv.env['API_BASE_URL'] ?? 'http://localhost:8080';
@@ -79,20 +80,8 @@ class FriendService {
  static Future<List<Map<String, dynamic>>> getFriendRequests() async {
   try {
     final headers = await _getHeaders();
-    final response = await http.get(
-      Uri.parse('$_baseUrl/friends/requests'),
-      headers: headers,
-    );
-    
-    if (response.statusCode == 200) {
-      final data = json.decode(response.body);
-      if (data is List) {
-        return data.cast<Map<String, dynamic>>();
-      }
-      return [];
-    } else {
-      throw Exception('${response.statusCode} - ${response.body}');
-    }
+    final data = await fetchAllPages('$_baseUrl/friends/requests', headers);
+    return data.cast<Map<String, dynamic>>();
   } catch (e) {
     throw Exception('Get requests error: $e');
   }
@@ -129,20 +118,8 @@ class FriendService {
   static Future<List<Map<String, dynamic>>> getFriends() async {
   try {
     final headers = await _getHeaders();
-    final response = await http.get(
-      Uri.parse('$_baseUrl/friends'),
-      headers: headers,
-    );
-    
-    if (response.statusCode == 200) {
-      final data = json.decode(response.body);
-      if (data is List) {
-        return data.cast<Map<String, dynamic>>();
-      }
-      return [];
-    } else {
-      throw Exception('${response.statusCode} - ${response.body}');
-    }
+    final data = await fetchAllPages('$_baseUrl/friends', headers);
+    return data.cast<Map<String, dynamic>>();
   } catch (e) {
     throw Exception('Get friends error: $e');
   }
@@ -151,20 +128,8 @@ class FriendService {
  static Future<List<Map<String, dynamic>>> getFriendsSpots() async {
     try {
       final headers = await _getHeaders();
-      final response = await http.get(
-        Uri.parse('$_baseUrl/friends/spots'),
-        headers: headers,
-      );
-      
-      if (response.statusCode == 200) {
-        final data = json.decode(response.body);
-        if (data is List) {
-          return data.cast<Map<String, dynamic>>();
-        }
-        return [];
-      } else {
-        throw Exception('${response.statusCode} - ${response.body}');
-      }
+      final data = await fetchAllPages('$_baseUrl/friends/spots', headers);
+      return data.cast<Map<String, dynamic>>();
     } catch (e) {
       throw Exception('Get friends spots error: $e');
     }
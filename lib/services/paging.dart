@@ -0,0 +1,32 @@
+import 'dart:convert';
+import 'package:http/http.dart' as http;
+
+// List endpoints return a page at a time as {items, next_cursor};
+// fetchAllPages follows next_cursor until the last page.
+Future<List<dynamic>> fetchAllPages(
+  String url,
+  Map<String, String> headers, {
+  String error = 'Failed to load',
+}) async {
+  final items = <dynamic>[];
+  String? cursor;
+  do {
+    final uri = Uri.parse(url);
+    final response = await http.get(
+      uri.replace(queryParameters: {
+        ...uri.queryParameters,
+        'limit': '100',
+        if (cursor != null) 'cursor': cursor,
+      }),
+      headers: headers,
+    );
+    if (response.statusCode != 200) {
+      throw Exception('$error: ${response.statusCode} - ${response.body}');
+    }
+
+    final data = json.decode(response.body);
+    items.addAll(data['items'] ?? []);
+    cursor = data['next_cursor'];
+  } while (cursor != null);
+  return items;
+}
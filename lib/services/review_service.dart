@@ -3,6 +3,7 @@ import 'dart:convert';
 import 'package:flutter_dotenv/flutter_dotenv.dart';
 import 'package:http/http.dart' as http;
 import 'package:shared_preferences/shared_preferences.dart';
+import 'package:domasna/services/paging.dart';
 
 class ReviewService {
   static String get _baseUrl => dotenv.env['API_BASE_URL'] ?? 'http://localhost:8080';
@@ -43,18 +44,11 @@ class ReviewService {
     throw Exception('User not authenticated');
   }
 
-  final response = await http.get(
-    Uri.parse('$_baseUrl/reviews/user'),
-    headers: {
-      "Authorization": "Bearer $token",
-    },
+  return fetchAllPages(
+    '$_baseUrl/reviews/user',
+    {"Authorization": "Bearer $token"},
+    error: 'Failed to load reviews',
   );
-
-  if (response.statusCode == 200) {
-    return json.decode(response.body);
-  } else {
-    throw Exception('Failed to load reviews: ${response.body}');
-  }
 }
 
 static Future<List<dynamic>> getReviewsForSpot(String spotId) async {
@@ -65,18 +59,11 @@ static Future<List<dynamic>> getReviewsForSpot(String spotId) async {
     throw Exception('User not authenticated');
   }
 
-  final response = await http.get(
-    Uri.parse('$_baseUrl/reviews/spot/$spotId'),
-    headers: {
-      "Authorization": "Bearer $token",
-    },
+  return fetchAllPages(
+    '$_baseUrl/reviews/spot/$spotId',
+    {"Authorization": "Bearer $token"},
+    error: 'Failed to load reviews',
   );
-
-  if (response.statusCode == 200) {
-    return json.decode(response.body);
-  } else {
-    throw Exception('Failed to load reviews: ${response.body}');
-  }
 }
 
 }
\ No newline at end of file
@@ -3,6 +3,8 @@ import 'dart:io';
 import 'package:flutter_dotenv/flutter_dotenv.dart';
 import 'package:http/http.dart' as http;
 import 'package:shared_preferences/shared_preferences.dart';
+import 'package:domasna/services/paging.dart';
+import 'package:domasna/services/visited_spot_service.dart';
 
 class SpotService {
   static String get _baseUrl => dotenv.env['API_BASE_URL'] ?? 'http://localhost:8080';
@@ -16,6 +18,7 @@ class SpotService {
    required double altitude,
   required File? dayImage,
   required File? nightImage,
+  bool force = false,
 }) async {
   final prefs = await SharedPreferences.getInstance();
   final token = prefs.getString('token');
@@ -35,6 +38,10 @@ class SpotService {
   request.fields['title'] = title;
   request.fields['description'] = desc;
   request.fields['weather'] = weather;
+  // Skips the server's duplicate spot check
+  if (force) {
+    request.fields['force'] = 'true';
+  }
   
   // Add day image if exists
   if (dayImage != null) {
@@ -76,18 +83,11 @@ class SpotService {
       throw Exception('User not authenticated');
     }
 
-    final response = await http.get(
-      Uri.parse('$_baseUrl/spots/user'),
-      headers: {
-        "Authorization": "Bearer $token",
-      },
+    return fetchAllPages(
+      '$_baseUrl/spots/user',
+      {"Authorization": "Bearer $token"},
+      error: 'Failed to load spots',
     );
-
-    if (response.statusCode == 200) {
-      return json.decode(response.body);
-    } else {
-      throw Exception('Failed to load spots: ${response.body}');
-    }
   }
 
 static Future<Map<String, dynamic>> getSpotById(String spotId) async {
@@ -166,11 +166,14 @@ static Future<void> trackVisit(String spotId) async {
   final response = await http.post(
     Uri.parse('$_baseUrl/spots/$spotId/visit'),
     headers: {
+      "Content-Type": "application/json",
       "Authorization": "Bearer $token",
     },
+    body: jsonEncode(await VisitedSpotService.currentFix()),
   );
 
-  if (response.statusCode != 200) {
+  // 200: already visited today, 201: new visit, 202: held for review
+  if (response.statusCode != 200 && response.statusCode != 201 && response.statusCode != 202) {
     throw Exception('Failed to track visit: ${response.body}');
   }
 }
@@ -1,11 +1,37 @@
     import 'dart:convert';
 import 'package:flutter_dotenv/flutter_dotenv.dart';
+import 'package:geolocator/geolocator.dart';
 import 'package:http/http.dart' as http;
 import 'package:shared_preferences/shared_preferences.dart';
+import 'package:domasna/services/paging.dart';
 
 class VisitedSpotService {
   static String get _baseUrl => dotenv.env['API_BASE_URL'] ?? 'http://localhost:8080';
 
+  // The server only counts visits sent with a fresh position fix at the spot
+  static Future<Map<String, dynamic>> currentFix() async {
+    final position = await Geolocator.getCurrentPosition(
+      desiredAccuracy: LocationAccuracy.high,
+    );
+    return {
+      "latitude": position.latitude,
+      "longitude": position.longitude,
+      "accuracy": position.accuracy,
+      "timestamp": (position.timestamp ?? DateTime.now()).toUtc().toIso8601String(),
+      "timezone": utcOffset(),
+    };
+  }
+
+  // Visits are one per spot and day; the server needs to know where the day starts
+  static String utcOffset() {
+    final offset = DateTime.now().timeZoneOffset;
+    final sign = offset.isNegative ? '-' : '+';
+    final minutes = offset.inMinutes.abs();
+    final hh = (minutes ~/ 60).toString().padLeft(2, '0');
+    final mm = (minutes % 60).toString().padLeft(2, '0');
+    return '$sign$hh:$mm';
+  }
+
   static Future<Map<String, dynamic>> addVisitedSpot({
     required String spotId,
     String notes = '',
@@ -26,14 +52,18 @@ class VisitedSpotService {
       body: jsonEncode({
         "spot_id": spotId,
         "notes": notes,
+        ...await currentFix(),
       }),
     );
 
-   if (response.statusCode == 201) {
+   // 200: already visited today, 202: added, but the XP is held until the
+   // check-in is reviewed
+   if (response.statusCode == 200 || response.statusCode == 201 || response.statusCode == 202) {
     final data = json.decode(response.body);
     return {
       'success': true,
-      'xp_gained': data['xp_gained'] ?? 10, 
+      'xp_gained': data['xp_gained'] ?? 0,
+      'first_visit': data['first_visit'] ?? false,
       'visited_spot': data['visited_spot'],
     };
   } else {
@@ -49,18 +79,11 @@ class VisitedSpotService {
       throw Exception('User not authenticated');
     }
 
-    final response = await http.get(
-      Uri.parse('$_baseUrl/visited-spots'),
-      headers: {
-        "Authorization": "Bearer $token",
-      },
+    return fetchAllPages(
+      '$_baseUrl/visited-spots',
+      {"Authorization": "Bearer $token"},
+      error: 'Failed to load visited spots',
     );
-
-    if (response.statusCode == 200) {
-      return json.decode(response.body);
-    } else {
-      throw Exception('Failed to load visited spots: ${response.body}');
-    }
   }
 
   static Future<List<NearbySpot>> checkProximity({
@@ -35,7 +35,7 @@ class _ArrivalPopupState extends State<ArrivalPopup> {
       Navigator.of(context).pop();
       
       // Show XP gain in snackbar
-      final xpGained = result['xp_gained'] ?? 10;
+      final xpGained = result['xp_gained'] ?? 0;
       ScaffoldMessenger.of(context).showSnackBar(
         SnackBar(
           content: Row(